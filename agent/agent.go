@@ -20,6 +20,20 @@ import (
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// Reload is called when a configuration reload is requested through the
+	// API.  Reloading is not available if it is nil.
+	Reload func()
+
+	// Started is called once the plugins are initialized and started, if
+	// not nil.
+	Started func()
+
+	// mu protects the plugin lists of Config and running while the agent
+	// runs; applyMu serializes calls to ApplyConfig.
+	mu      sync.Mutex
+	applyMu sync.Mutex
+	running *runningUnits
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -44,6 +58,12 @@ func NewAgent(config *config.Config) (*Agent, error) {
 type inputUnit struct {
 	dst    chan<- telegraf.Metric
 	inputs []*models.RunningInput
+
+	// Used for adding and removing inputs while running.
+	mu    sync.Mutex
+	ctx   context.Context
+	wg    sync.WaitGroup
+	loops map[*models.RunningInput]*pluginLoop
 }
 
 //  ______     ┌───────────┐     ______
@@ -53,6 +73,11 @@ type processorUnit struct {
 	src       <-chan telegraf.Metric
 	dst       chan<- telegraf.Metric
 	processor *models.RunningProcessor
+	acc       telegraf.Accumulator
+
+	// Used for replacing the processor while running.
+	mu      sync.Mutex
+	stopped bool
 }
 
 // aggregatorUnit is a group of Aggregators and their source and sink channels.
@@ -75,6 +100,12 @@ type aggregatorUnit struct {
 	aggC        chan<- telegraf.Metric
 	outputC     chan<- telegraf.Metric
 	aggregators []*models.RunningAggregator
+
+	// Used for replacing aggregators while running.
+	mu    sync.Mutex
+	ctx   context.Context
+	wg    sync.WaitGroup
+	loops map[*models.RunningAggregator]*pluginLoop
 }
 
 // outputUnit is a group of Outputs and their source channel.  Metrics on the
@@ -92,6 +123,24 @@ type aggregatorUnit struct {
 type outputUnit struct {
 	src     <-chan telegraf.Metric
 	outputs []*models.RunningOutput
+
+	// Used for adding and removing outputs while running.
+	mu    sync.RWMutex
+	ctx   context.Context
+	wg    sync.WaitGroup
+	loops map[*models.RunningOutput]*pluginLoop
+}
+
+// pluginLoop is the goroutine running a single plugin within a unit.
+type pluginLoop struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stop cancels the loop and waits for it to return.
+func (l *pluginLoop) stop() {
+	l.cancel()
+	<-l.done
 }
 
 // Run starts and runs the Agent until the context is done.
@@ -107,6 +156,14 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	if a.Config.Agent.APIAddress != "" {
+		api, err := a.startAPI(a.Config.Agent.APIAddress)
+		if err != nil {
+			return fmt.Errorf("could not start API: %v", err)
+		}
+		defer api.stop()
+	}
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
@@ -142,6 +199,31 @@ func (a *Agent) Run(ctx context.Context) error {
 		return err
 	}
 
+	// Without fingerprints every reload restarts the agent.
+	fingerprints, err := a.fingerprints(a.Config)
+	if err != nil {
+		log.Printf("W! [agent] Reloading only changed plugins is not available: %v", err)
+	}
+	a.mu.Lock()
+	a.running = &runningUnits{
+		inputs:        iu,
+		processors:    pu,
+		aggregators:   au,
+		aggProcessors: apu,
+		outputs:       ou,
+		fingerprints:  fingerprints,
+	}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running = nil
+		a.mu.Unlock()
+	}()
+
+	if a.Started != nil {
+		a.Started()
+	}
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -185,6 +267,10 @@ func (a *Agent) Run(ctx context.Context) error {
 
 // initPlugins runs the Init function on plugins.
 func (a *Agent) initPlugins() error {
+	if err := a.resolveSecrets(); err != nil {
+		return err
+	}
+
 	for _, input := range a.Config.Inputs {
 		err := input.Init()
 		if err != nil {
@@ -223,6 +309,79 @@ func (a *Agent) initPlugins() error {
 	return nil
 }
 
+// Validate resolves the secrets and initializes all plugins without starting
+// them, returning every error encountered.
+func (a *Agent) Validate() []error {
+	if err := a.Config.InitSecretStores(); err != nil {
+		return []error{err}
+	}
+
+	var errs []error
+	check := func(kind, name string, plugin interface{}, init func() error) {
+		if err := a.Config.ResolveSecrets(plugin); err != nil {
+			errs = append(errs, fmt.Errorf("could not resolve secrets of %s %s: %v", kind, name, err))
+			return
+		}
+		if err := init(); err != nil {
+			errs = append(errs, fmt.Errorf("could not initialize %s %s: %v", kind, name, err))
+		}
+	}
+
+	for _, input := range a.Config.Inputs {
+		check("input", input.LogName(), input.Input, input.Init)
+	}
+	for _, processor := range a.Config.Processors {
+		check("processor", processor.LogName(), processor.Processor, processor.Init)
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		check("aggregator", aggregator.LogName(), aggregator.Aggregator, aggregator.Init)
+	}
+	for _, output := range a.Config.Outputs {
+		check("output", output.LogName(), output.Output, output.Init)
+	}
+	return errs
+}
+
+// resolveSecrets initializes the secret stores and resolves the secret
+// references in the plugin configs.
+func (a *Agent) resolveSecrets() error {
+	if err := a.Config.InitSecretStores(); err != nil {
+		return err
+	}
+
+	for _, input := range a.Config.Inputs {
+		if err := a.Config.ResolveSecrets(input.Input); err != nil {
+			return fmt.Errorf("could not resolve secrets of input %s: %v",
+				input.LogName(), err)
+		}
+	}
+	for _, processor := range a.Config.Processors {
+		if err := a.Config.ResolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processor %s: %v",
+				processor.LogName(), err)
+		}
+	}
+	for _, processor := range a.Config.AggProcessors {
+		if err := a.Config.ResolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processor %s: %v",
+				processor.LogName(), err)
+		}
+	}
+	for _, aggregator := range a.Config.Aggregators {
+		if err := a.Config.ResolveSecrets(aggregator.Aggregator); err != nil {
+			return fmt.Errorf("could not resolve secrets of aggregator %s: %v",
+				aggregator.LogName(), err)
+		}
+	}
+	for _, output := range a.Config.Outputs {
+		if err := a.Config.ResolveSecrets(output.Output); err != nil {
+			return fmt.Errorf("could not resolve secrets of output %s: %v",
+				output.LogName(), err)
+		}
+	}
+	return nil
+}
+
 func (a *Agent) startInputs(
 	dst chan<- telegraf.Metric,
 	inputs []*models.RunningInput,
@@ -230,31 +389,15 @@ func (a *Agent) startInputs(
 	log.Printf("D! [agent] Starting service inputs")
 
 	unit := &inputUnit{
-		dst: dst,
+		dst:   dst,
+		loops: make(map[*models.RunningInput]*pluginLoop),
 	}
 
 	for _, input := range inputs {
-		if si, ok := input.Input.(telegraf.ServiceInput); ok {
-			// Service input plugins are not normally subject to timestamp
-			// rounding except for when precision is set on the input plugin.
-			//
-			// This only applies to the accumulator passed to Start(), the
-			// Gather() accumulator does apply rounding according to the
-			// precision and interval agent/plugin settings.
-			var interval time.Duration
-			var precision time.Duration
-			if input.Config.Precision != 0 {
-				precision = input.Config.Precision
-			}
-
-			acc := NewAccumulator(input, dst)
-			acc.SetPrecision(getPrecision(precision, interval))
-
-			err := si.Start(acc)
-			if err != nil {
-				stopServiceInputs(unit.inputs)
-				return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
-			}
+		err := startServiceInput(input, dst)
+		if err != nil {
+			stopServiceInputs(unit.inputs)
+			return nil, err
 		}
 		unit.inputs = append(unit.inputs, input)
 	}
@@ -262,6 +405,34 @@ func (a *Agent) startInputs(
 	return unit, nil
 }
 
+// startServiceInput calls Start if the input is a service input.
+func startServiceInput(input *models.RunningInput, dst chan<- telegraf.Metric) error {
+	si, ok := input.Input.(telegraf.ServiceInput)
+	if !ok {
+		return nil
+	}
+
+	// Service input plugins are not normally subject to timestamp
+	// rounding except for when precision is set on the input plugin.
+	//
+	// This only applies to the accumulator passed to Start(), the
+	// Gather() accumulator does apply rounding according to the
+	// precision and interval agent/plugin settings.
+	var interval time.Duration
+	var precision time.Duration
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
+
+	acc := NewAccumulator(input, dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	if err := si.Start(acc); err != nil {
+		return fmt.Errorf("starting input %s: %w", input.LogName(), err)
+	}
+	return nil
+}
+
 // runInputs starts and triggers the periodic gather for Inputs.
 //
 // When the context is done the timers are stopped and this function returns
@@ -271,51 +442,75 @@ func (a *Agent) runInputs(
 	startTime time.Time,
 	unit *inputUnit,
 ) {
-	var wg sync.WaitGroup
+	unit.mu.Lock()
+	unit.ctx = ctx
 	for _, input := range unit.inputs {
-		// Overwrite agent interval if this plugin has its own.
-		interval := time.Duration(a.Config.Agent.Interval)
-		if input.Config.Interval != 0 {
-			interval = input.Config.Interval
-		}
+		a.startGatherLoop(unit, input, startTime)
+	}
+	unit.mu.Unlock()
 
-		// Overwrite agent precision if this plugin has its own.
-		precision := time.Duration(a.Config.Agent.Precision)
-		if input.Config.Precision != 0 {
-			precision = input.Config.Precision
-		}
+	<-ctx.Done()
 
-		// Overwrite agent collection_jitter if this plugin has its own.
-		jitter := time.Duration(a.Config.Agent.CollectionJitter)
-		if input.Config.CollectionJitter != 0 {
-			jitter = input.Config.CollectionJitter
-		}
+	// Inputs are only added while the context is not done, so no loops can
+	// be started after this point.
+	unit.mu.Lock()
+	inputs := unit.inputs
+	unit.mu.Unlock()
+	unit.wg.Wait()
 
-		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
-			ticker = NewAlignedTicker(startTime, interval, jitter)
-		} else {
-			ticker = NewUnalignedTicker(interval, jitter)
-		}
-		defer ticker.Stop()
+	log.Printf("D! [agent] Stopping service inputs")
+	stopServiceInputs(inputs)
 
-		acc := NewAccumulator(input, unit.dst)
-		acc.SetPrecision(getPrecision(precision, interval))
+	close(unit.dst)
+	log.Printf("D! [agent] Input channel closed")
+}
 
-		wg.Add(1)
-		go func(input *models.RunningInput) {
-			defer wg.Done()
-			a.gatherLoop(ctx, acc, input, ticker, interval)
-		}(input)
+// startGatherLoop starts the periodic gather of the input.  The unit must be
+// locked.
+func (a *Agent) startGatherLoop(
+	unit *inputUnit,
+	input *models.RunningInput,
+	startTime time.Time,
+) {
+	// Overwrite agent interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.Interval)
+	if input.Config.Interval != 0 {
+		interval = input.Config.Interval
 	}
 
-	wg.Wait()
+	// Overwrite agent precision if this plugin has its own.
+	precision := time.Duration(a.Config.Agent.Precision)
+	if input.Config.Precision != 0 {
+		precision = input.Config.Precision
+	}
 
-	log.Printf("D! [agent] Stopping service inputs")
-	stopServiceInputs(unit.inputs)
+	// Overwrite agent collection_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.CollectionJitter)
+	if input.Config.CollectionJitter != 0 {
+		jitter = input.Config.CollectionJitter
+	}
 
-	close(unit.dst)
-	log.Printf("D! [agent] Input channel closed")
+	var ticker Ticker
+	if a.Config.Agent.RoundInterval {
+		ticker = NewAlignedTicker(startTime, interval, jitter)
+	} else {
+		ticker = NewUnalignedTicker(interval, jitter)
+	}
+
+	acc := NewAccumulator(input, unit.dst)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	loop := &pluginLoop{cancel: cancel, done: make(chan struct{})}
+	unit.loops[input] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+		defer ticker.Stop()
+		a.gatherLoop(ctx, acc, input, ticker, interval)
+	}()
 }
 
 // testStartInputs is a variation of startInputs for use in --test and --once
@@ -497,10 +692,7 @@ func (a *Agent) startProcessors(
 ) (chan<- telegraf.Metric, []*processorUnit, error) {
 	var units []*processorUnit
 
-	// Sort from last to first
-	sort.SliceStable(processors, func(i, j int) bool {
-		return processors[i].Config.Order > processors[j].Config.Order
-	})
+	sortProcessors(processors)
 
 	var src chan telegraf.Metric
 	for _, processor := range processors {
@@ -520,6 +712,7 @@ func (a *Agent) startProcessors(
 			src:       src,
 			dst:       dst,
 			processor: processor,
+			acc:       acc,
 		})
 
 		dst = src
@@ -528,6 +721,13 @@ func (a *Agent) startProcessors(
 	return src, units, nil
 }
 
+// sortProcessors sorts the processors from last to first.
+func sortProcessors(processors models.RunningProcessors) {
+	sort.SliceStable(processors, func(i, j int) bool {
+		return processors[i].Config.Order > processors[j].Config.Order
+	})
+}
+
 // runProcessors begins processing metrics and runs until the source channel is
 // closed and all metrics have been written.
 func (a *Agent) runProcessors(
@@ -539,14 +739,18 @@ func (a *Agent) runProcessors(
 		go func(unit *processorUnit) {
 			defer wg.Done()
 
-			acc := NewAccumulator(unit.processor, unit.dst)
 			for m := range unit.src {
-				if err := unit.processor.Add(m, acc); err != nil {
-					acc.AddError(err)
+				unit.mu.Lock()
+				if err := unit.processor.Add(m, unit.acc); err != nil {
+					unit.acc.AddError(err)
 					m.Drop()
 				}
+				unit.mu.Unlock()
 			}
+			unit.mu.Lock()
 			unit.processor.Stop()
+			unit.stopped = true
+			unit.mu.Unlock()
 			close(unit.dst)
 			log.Printf("D! [agent] Processor channel closed")
 		}(unit)
@@ -566,6 +770,7 @@ func (a *Agent) startAggregators(
 		aggC:        aggC,
 		outputC:     outputC,
 		aggregators: aggregators,
+		loops:       make(map[*models.RunningAggregator]*pluginLoop),
 	}
 	return src, unit
 }
@@ -578,24 +783,25 @@ func (a *Agent) runAggregators(
 ) {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Before calling Add, initialize the aggregation window.  This ensures
-	// that any metric created after start time will be aggregated.
-	for _, agg := range a.Config.Aggregators {
-		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
-		agg.UpdateWindow(since, until)
+	unit.mu.Lock()
+	unit.ctx = ctx
+	for _, agg := range unit.aggregators {
+		a.startPushLoop(unit, agg, startTime)
 	}
+	unit.mu.Unlock()
 
-	var wg sync.WaitGroup
-	wg.Add(1)
+	unit.wg.Add(1)
 	go func() {
-		defer wg.Done()
+		defer unit.wg.Done()
 		for metric := range unit.src {
 			var dropOriginal bool
-			for _, agg := range a.Config.Aggregators {
+			unit.mu.Lock()
+			for _, agg := range unit.aggregators {
 				if ok := agg.Add(metric); ok {
 					dropOriginal = true
 				}
 			}
+			unit.mu.Unlock()
 
 			if !dropOriginal {
 				unit.outputC <- metric // keep original.
@@ -603,24 +809,14 @@ func (a *Agent) runAggregators(
 				metric.Drop()
 			}
 		}
+
+		// Cancel while locked so no push loops are started afterwards.
+		unit.mu.Lock()
 		cancel()
+		unit.mu.Unlock()
 	}()
 
-	for _, agg := range a.Config.Aggregators {
-		wg.Add(1)
-		go func(agg *models.RunningAggregator) {
-			defer wg.Done()
-
-			interval := time.Duration(a.Config.Agent.Interval)
-			precision := time.Duration(a.Config.Agent.Precision)
-
-			acc := NewAccumulator(agg, unit.aggC)
-			acc.SetPrecision(getPrecision(precision, interval))
-			a.push(ctx, agg, acc)
-		}(agg)
-	}
-
-	wg.Wait()
+	unit.wg.Wait()
 
 	// In the case that there are no processors, both aggC and outputC are the
 	// same channel.  If there are processors, we close the aggC and the
@@ -629,6 +825,36 @@ func (a *Agent) runAggregators(
 	log.Printf("D! [agent] Aggregator channel closed")
 }
 
+// startPushLoop initializes the aggregation window of the aggregator and
+// starts pushing it every period.  The unit must be locked.
+func (a *Agent) startPushLoop(
+	unit *aggregatorUnit,
+	agg *models.RunningAggregator,
+	startTime time.Time,
+) {
+	// Before calling Add, initialize the aggregation window.  This ensures
+	// that any metric created after start time will be aggregated.
+	since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
+	agg.UpdateWindow(since, until)
+
+	interval := time.Duration(a.Config.Agent.Interval)
+	precision := time.Duration(a.Config.Agent.Precision)
+
+	acc := NewAccumulator(agg, unit.aggC)
+	acc.SetPrecision(getPrecision(precision, interval))
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	loop := &pluginLoop{cancel: cancel, done: make(chan struct{})}
+	unit.loops[agg] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+		a.push(ctx, agg, acc)
+	}()
+}
+
 func updateWindow(start time.Time, roundInterval bool, period time.Duration) (time.Time, time.Time) {
 	var until time.Time
 	if roundInterval {
@@ -676,7 +902,10 @@ func (a *Agent) startOutputs(
 	outputs []*models.RunningOutput,
 ) (chan<- telegraf.Metric, *outputUnit, error) {
 	src := make(chan telegraf.Metric, 100)
-	unit := &outputUnit{src: src}
+	unit := &outputUnit{
+		src:   src,
+		loops: make(map[*models.RunningOutput]*pluginLoop),
+	}
 	for _, output := range outputs {
 		err := a.connectOutput(ctx, output)
 		if err != nil {
@@ -692,8 +921,12 @@ func (a *Agent) startOutputs(
 	return src, unit, nil
 }
 
-// connectOutputs connects to all outputs.
+// connectOutput opens the buffer of the output and connects it.
 func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput) error {
+	if err := output.OpenBuffer(); err != nil {
+		return fmt.Errorf("Error starting output %q: %w", output.LogName(), err)
+	}
+
 	log.Printf("D! [agent] Attempting connection to [%s]", output.LogName())
 	err := output.Output.Connect()
 	if err != nil {
@@ -702,11 +935,13 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 
 		err := internal.SleepContext(ctx, 15*time.Second)
 		if err != nil {
+			output.CloseBuffer()
 			return err
 		}
 
 		err = output.Output.Connect()
 		if err != nil {
+			output.CloseBuffer()
 			return fmt.Errorf("Error connecting to output %q: %w", output.LogName(), err)
 		}
 	}
@@ -720,54 +955,72 @@ func (a *Agent) connectOutput(ctx context.Context, output *models.RunningOutput)
 func (a *Agent) runOutputs(
 	unit *outputUnit,
 ) {
-	var wg sync.WaitGroup
-
-	// Start flush loop
-	interval := time.Duration(a.Config.Agent.FlushInterval)
-	jitter := time.Duration(a.Config.Agent.FlushJitter)
-
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Start flush loop
+	unit.mu.Lock()
+	unit.ctx = ctx
 	for _, output := range unit.outputs {
-		interval := interval
-		// Overwrite agent flush_interval if this plugin has its own.
-		if output.Config.FlushInterval != 0 {
-			interval = output.Config.FlushInterval
-		}
-
-		jitter := jitter
-		// Overwrite agent flush_jitter if this plugin has its own.
-		if output.Config.FlushJitter != 0 {
-			jitter = output.Config.FlushJitter
-		}
-
-		wg.Add(1)
-		go func(output *models.RunningOutput) {
-			defer wg.Done()
-
-			ticker := NewRollingTicker(interval, jitter)
-			defer ticker.Stop()
-
-			a.flushLoop(ctx, output, ticker)
-		}(output)
+		a.startFlushLoop(unit, output)
 	}
+	unit.mu.Unlock()
 
 	for metric := range unit.src {
+		unit.mu.RLock()
+		if len(unit.outputs) == 0 {
+			metric.Drop()
+		}
 		for i, output := range unit.outputs {
-			if i == len(a.Config.Outputs)-1 {
+			if i == len(unit.outputs)-1 {
 				output.AddMetric(metric)
 			} else {
 				output.AddMetric(metric.Copy())
 			}
 		}
+		unit.mu.RUnlock()
 	}
 
 	log.Println("I! [agent] Hang on, flushing any cached metrics before shutdown")
+	// Cancel while locked so no flush loops are started afterwards.
+	unit.mu.Lock()
 	cancel()
-	wg.Wait()
+	outputs := unit.outputs
+	unit.mu.Unlock()
+	unit.wg.Wait()
 
 	log.Println("I! [agent] Stopping running outputs")
-	stopRunningOutputs(unit.outputs)
+	stopRunningOutputs(outputs)
+}
+
+// startFlushLoop starts the periodic flush of the output.  The unit must be
+// locked.
+func (a *Agent) startFlushLoop(unit *outputUnit, output *models.RunningOutput) {
+	// Overwrite agent flush_interval if this plugin has its own.
+	interval := time.Duration(a.Config.Agent.FlushInterval)
+	if output.Config.FlushInterval != 0 {
+		interval = output.Config.FlushInterval
+	}
+
+	// Overwrite agent flush_jitter if this plugin has its own.
+	jitter := time.Duration(a.Config.Agent.FlushJitter)
+	if output.Config.FlushJitter != 0 {
+		jitter = output.Config.FlushJitter
+	}
+
+	ctx, cancel := context.WithCancel(unit.ctx)
+	loop := &pluginLoop{cancel: cancel, done: make(chan struct{})}
+	unit.loops[output] = loop
+
+	unit.wg.Add(1)
+	go func() {
+		defer unit.wg.Done()
+		defer close(loop.done)
+
+		ticker := NewRollingTicker(interval, jitter)
+		defer ticker.Stop()
+
+		a.flushLoop(ctx, output, ticker)
+	}()
 }
 
 // flushLoop runs an output's flush function periodically until the context is
@@ -806,6 +1059,9 @@ func (a *Agent) flushLoop(
 		case <-flushRequested:
 			ticker.Reset()
 			logError(a.flushOnce(output, ticker, output.Write))
+		case <-output.FlushRequested:
+			ticker.Reset()
+			logError(a.flushOnce(output, ticker, output.Write))
 		case <-output.BatchReady:
 			ticker.Reset()
 			logError(a.flushOnce(output, ticker, output.WriteBatch))
@@ -977,6 +1233,14 @@ func (a *Agent) once(ctx context.Context, wait time.Duration) error {
 		return err
 	}
 
+	if a.Config.Agent.APIAddress != "" {
+		api, err := a.startAPI(a.Config.Agent.APIAddress)
+		if err != nil {
+			return fmt.Errorf("could not start API: %v", err)
+		}
+		defer api.stop()
+	}
+
 	startTime := time.Now()
 
 	log.Printf("D! [agent] Connecting outputs")
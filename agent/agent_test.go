@@ -1,10 +1,15 @@
 package agent
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	"github.com/stretchr/testify/assert"
@@ -166,3 +171,49 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+type validateInput struct {
+	Fail bool
+}
+
+func (i *validateInput) SampleConfig() string              { return "" }
+func (i *validateInput) Description() string               { return "" }
+func (i *validateInput) Gather(telegraf.Accumulator) error { return nil }
+func (i *validateInput) Init() error {
+	if i.Fail {
+		return errors.New("invalid config")
+	}
+	return nil
+}
+
+func TestAgent_Validate(t *testing.T) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs,
+		models.NewRunningInput(&validateInput{}, &models.InputConfig{Name: "valid"}),
+		models.NewRunningInput(&validateInput{Fail: true}, &models.InputConfig{Name: "invalid", Alias: "first"}),
+		models.NewRunningInput(&validateInput{Fail: true}, &models.InputConfig{Name: "invalid", Alias: "second"}),
+	)
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	errs := a.Validate()
+	require.Len(t, errs, 2)
+	require.EqualError(t, errs[0], "could not initialize input inputs.invalid::first: invalid config")
+	require.EqualError(t, errs[1], "could not initialize input inputs.invalid::second: invalid config")
+}
+
+func TestAgent_ValidateDoesNotOpenDiskBuffer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "buffer")
+	c := config.NewConfig()
+	c.Outputs = append(c.Outputs, models.NewRunningOutput(&reloadTestOutput{}, &models.OutputConfig{
+		Name:            "reloadtest",
+		BufferStrategy:  models.BufferStrategyDisk,
+		BufferDirectory: dir,
+	}, 0, 0))
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	require.Empty(t, a.Validate())
+	_, err = os.Stat(dir)
+	require.True(t, os.IsNotExist(err), "the buffer directory must not be created")
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+// apiServer is the local HTTP management API of the agent.
+//
+// Endpoints:
+//
+//	GET  /api/plugins               list the loaded plugins and their status
+//	POST /api/reload                reload the configuration
+//	POST /api/outputs/<id>/flush    flush the output with the given id
+//
+// Plugins are identified by the id assigned when they are created, so an id
+// keeps referring to the same plugin across reloads until that plugin is
+// replaced or removed.
+type apiServer struct {
+	agent    *Agent
+	listener net.Listener
+	server   *http.Server
+}
+
+// pluginStatus is the status of a single plugin as reported by the API.
+type pluginStatus struct {
+	ID    uint64           `json:"id"`
+	Name  string           `json:"name"`
+	Alias string           `json:"alias,omitempty"`
+	Stats map[string]int64 `json:"stats"`
+
+	LastGatherTime     *time.Time `json:"last_gather_time,omitempty"`
+	LastGatherDuration string     `json:"last_gather_duration,omitempty"`
+	LastError          string     `json:"last_error,omitempty"`
+	LastErrorTime      *time.Time `json:"last_error_time,omitempty"`
+}
+
+type pluginsResponse struct {
+	Inputs      []pluginStatus `json:"inputs"`
+	Processors  []pluginStatus `json:"processors"`
+	Aggregators []pluginStatus `json:"aggregators"`
+	Outputs     []pluginStatus `json:"outputs"`
+}
+
+// startAPI starts serving the management API on the given address.
+func (a *Agent) startAPI(address string) (*apiServer, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &apiServer{
+		agent:    a,
+		listener: listener,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/plugins", s.handlePlugins)
+	mux.HandleFunc("/api/reload", s.handleReload)
+	mux.HandleFunc("/api/outputs/", s.handleOutput)
+	s.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("E! [agent] Error serving API: %v", err)
+		}
+	}()
+
+	log.Printf("I! [agent] Serving API on %s", listener.Addr())
+	return s, nil
+}
+
+// stop shuts down the API server, waiting briefly for active requests.
+func (s *apiServer) stop() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		log.Printf("E! [agent] Error stopping API: %v", err)
+	}
+}
+
+func (s *apiServer) handlePlugins(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	cfg := s.agent.snapshot()
+	resp := pluginsResponse{
+		Inputs:      make([]pluginStatus, 0, len(cfg.Inputs)),
+		Processors:  make([]pluginStatus, 0, len(cfg.Processors)),
+		Aggregators: make([]pluginStatus, 0, len(cfg.Aggregators)),
+		Outputs:     make([]pluginStatus, 0, len(cfg.Outputs)),
+	}
+
+	for _, input := range cfg.Inputs {
+		status := pluginStatus{
+			ID:    input.ID,
+			Name:  input.Config.Name,
+			Alias: input.Config.Alias,
+			Stats: stats("gather", pluginTags("input", input.Config.Name, input.Config.Alias),
+				"metrics_gathered", "errors"),
+		}
+		if start, elapsed := input.LastGather(); !start.IsZero() {
+			status.LastGatherTime = &start
+			status.LastGatherDuration = elapsed.String()
+		}
+		status.setLastError(input.LastError())
+		resp.Inputs = append(resp.Inputs, status)
+	}
+
+	for _, processor := range cfg.Processors {
+		status := pluginStatus{
+			ID:    processor.ID,
+			Name:  processor.Config.Name,
+			Alias: processor.Config.Alias,
+			Stats: stats("process", pluginTags("processor", processor.Config.Name, processor.Config.Alias),
+				"errors"),
+		}
+		status.setLastError(processor.LastError())
+		resp.Processors = append(resp.Processors, status)
+	}
+
+	for _, aggregator := range cfg.Aggregators {
+		status := pluginStatus{
+			ID:    aggregator.ID,
+			Name:  aggregator.Config.Name,
+			Alias: aggregator.Config.Alias,
+			Stats: stats("aggregate", pluginTags("aggregator", aggregator.Config.Name, aggregator.Config.Alias),
+				"metrics_pushed", "metrics_filtered", "metrics_dropped", "errors"),
+		}
+		status.setLastError(aggregator.LastError())
+		resp.Aggregators = append(resp.Aggregators, status)
+	}
+
+	for _, output := range cfg.Outputs {
+		status := pluginStatus{
+			ID:    output.ID,
+			Name:  output.Config.Name,
+			Alias: output.Config.Alias,
+			Stats: stats("write", pluginTags("output", output.Config.Name, output.Config.Alias),
+				"metrics_added", "metrics_written", "metrics_dropped", "metrics_filtered",
+				"buffer_size", "buffer_limit", "errors"),
+		}
+		status.setLastError(output.LastError())
+		resp.Outputs = append(resp.Outputs, status)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("E! [agent] Error encoding API response: %v", err)
+	}
+}
+
+func (s *apiServer) handleReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if s.agent.Reload == nil {
+		http.Error(w, "reload is not supported", http.StatusNotImplemented)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	// Reloading might restart the agent and with it this server, so do not
+	// block the response on it.
+	go s.agent.Reload()
+}
+
+func (s *apiServer) handleOutput(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/outputs/"), "/")
+	if len(parts) != 2 || parts[1] != "flush" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		http.Error(w, "unknown output", http.StatusNotFound)
+		return
+	}
+	for _, output := range s.agent.snapshot().Outputs {
+		if output.ID == id {
+			output.RequestFlush()
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+	}
+	http.Error(w, "unknown output", http.StatusNotFound)
+}
+
+func (p *pluginStatus) setLastError(msg string, ts time.Time) {
+	if ts.IsZero() {
+		return
+	}
+	p.LastError = msg
+	p.LastErrorTime = &ts
+}
+
+// pluginTags returns the tags the plugin's selfstat metrics are registered with.
+func pluginTags(key, name, alias string) map[string]string {
+	tags := map[string]string{key: name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+	return tags
+}
+
+// stats reads the current value of the given selfstat fields.
+func stats(measurement string, tags map[string]string, fields ...string) map[string]int64 {
+	values := make(map[string]int64, len(fields))
+	for _, field := range fields {
+		values[field] = selfstat.Register(measurement, field, tags).Get()
+	}
+	return values
+}
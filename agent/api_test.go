@@ -0,0 +1,176 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type apiTestInput struct{}
+
+func (i *apiTestInput) SampleConfig() string              { return "" }
+func (i *apiTestInput) Description() string               { return "" }
+func (i *apiTestInput) Gather(telegraf.Accumulator) error { return nil }
+
+type apiTestOutput struct{}
+
+func (o *apiTestOutput) SampleConfig() string          { return "" }
+func (o *apiTestOutput) Description() string           { return "" }
+func (o *apiTestOutput) Connect() error                { return nil }
+func (o *apiTestOutput) Close() error                  { return nil }
+func (o *apiTestOutput) Write([]telegraf.Metric) error { return nil }
+
+func newAPITestAgent(t *testing.T) (*Agent, string) {
+	c := config.NewConfig()
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&apiTestInput{}, &models.InputConfig{
+		Name:  "apitest",
+		Alias: "first",
+	}))
+	c.Outputs = append(c.Outputs, models.NewRunningOutput(&apiTestOutput{}, &models.OutputConfig{
+		Name: "apitest",
+	}, 10, 100))
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	api, err := a.startAPI("127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(api.stop)
+
+	return a, "http://" + api.listener.Addr().String()
+}
+
+func TestAPI_Plugins(t *testing.T) {
+	a, url := newAPITestAgent(t)
+
+	input := a.Config.Inputs[0]
+	require.NoError(t, input.Gather(&testutil.Accumulator{}))
+	input.Log().Errorf("gather failed: %s", "timeout")
+
+	output := a.Config.Outputs[0]
+	output.AddMetric(testutil.TestMetric(42))
+
+	resp, err := http.Get(url + "/api/plugins")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var plugins pluginsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&plugins))
+
+	require.Len(t, plugins.Inputs, 1)
+	require.Equal(t, "apitest", plugins.Inputs[0].Name)
+	require.Equal(t, "first", plugins.Inputs[0].Alias)
+	require.NotNil(t, plugins.Inputs[0].LastGatherTime)
+	require.NotEmpty(t, plugins.Inputs[0].LastGatherDuration)
+	require.Equal(t, "gather failed: timeout", plugins.Inputs[0].LastError)
+	require.Equal(t, int64(1), plugins.Inputs[0].Stats["errors"])
+
+	require.Len(t, plugins.Outputs, 1)
+	require.Equal(t, output.ID, plugins.Outputs[0].ID)
+	require.Equal(t, int64(1), plugins.Outputs[0].Stats["buffer_size"])
+	require.Equal(t, int64(100), plugins.Outputs[0].Stats["buffer_limit"])
+	require.Empty(t, plugins.Outputs[0].LastError)
+
+	require.Empty(t, plugins.Processors)
+	require.Empty(t, plugins.Aggregators)
+}
+
+func TestAPI_FlushOutput(t *testing.T) {
+	a, url := newAPITestAgent(t)
+
+	output := a.Config.Outputs[0]
+	flushURL := fmt.Sprintf("%s/api/outputs/%d/flush", url, output.ID)
+
+	resp, err := http.Post(flushURL, "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case <-output.FlushRequested:
+	default:
+		require.Fail(t, "flush was not requested")
+	}
+
+	resp, err = http.Post(fmt.Sprintf("%s/api/outputs/%d/flush", url, output.ID+1000), "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(flushURL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestAPI_FlushOutputAfterReload(t *testing.T) {
+	a, url := newAPITestAgent(t)
+
+	removed := a.Config.Outputs[0]
+	kept := models.NewRunningOutput(&apiTestOutput{}, &models.OutputConfig{
+		Name:  "apitest",
+		Alias: "kept",
+	}, 10, 100)
+	a.mu.Lock()
+	a.Config.Outputs = []*models.RunningOutput{
+		models.NewRunningOutput(&apiTestOutput{}, &models.OutputConfig{
+			Name:  "apitest",
+			Alias: "added",
+		}, 10, 100),
+		kept,
+	}
+	a.mu.Unlock()
+
+	resp, err := http.Post(fmt.Sprintf("%s/api/outputs/%d/flush", url, removed.ID), "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Post(fmt.Sprintf("%s/api/outputs/%d/flush", url, kept.ID), "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case <-kept.FlushRequested:
+	default:
+		require.Fail(t, "flush was not requested")
+	}
+	select {
+	case <-a.Config.Outputs[0].FlushRequested:
+		require.Fail(t, "flush was requested on the wrong output")
+	default:
+	}
+}
+
+func TestAPI_Reload(t *testing.T) {
+	a, url := newAPITestAgent(t)
+
+	resp, err := http.Post(url+"/api/reload", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotImplemented, resp.StatusCode)
+
+	reloaded := make(chan struct{})
+	a.Reload = func() { close(reloaded) }
+
+	resp, err = http.Post(url+"/api/reload", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	select {
+	case <-reloaded:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "reload was not triggered")
+	}
+}
@@ -0,0 +1,513 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+)
+
+// ErrRestartRequired is returned by ApplyConfig if the changes cannot be
+// applied to the running agent and it must be restarted instead.
+var ErrRestartRequired = errors.New("configuration changes require a restart")
+
+// runningUnits are the units of a running agent along with the fingerprints
+// of the running plugins.
+type runningUnits struct {
+	inputs        *inputUnit
+	processors    []*processorUnit
+	aggregators   *aggregatorUnit
+	aggProcessors []*processorUnit
+	outputs       *outputUnit
+
+	fingerprints map[interface{}]string
+}
+
+// ApplyConfig updates the running agent to the given configuration, only
+// stopping, starting or replacing the plugins whose configuration changed.
+// Unchanged plugins keep running, so unchanged outputs keep their buffered
+// metrics.
+//
+// ErrRestartRequired is returned without changing anything if the agent
+// settings or global tags changed, or if processors or aggregators were
+// added, removed or reordered.  If a changed plugin cannot be initialized no
+// changes are made either.
+func (a *Agent) ApplyConfig(c *config.Config) error {
+	a.applyMu.Lock()
+	defer a.applyMu.Unlock()
+
+	a.mu.Lock()
+	r := a.running
+	a.mu.Unlock()
+	if r == nil {
+		return errors.New("agent is not running")
+	}
+
+	if r.fingerprints == nil || c.AgentFingerprint() != a.Config.AgentFingerprint() {
+		return ErrRestartRequired
+	}
+
+	if err := c.InitSecretStores(); err != nil {
+		return err
+	}
+	fingerprints, err := a.fingerprints(c)
+	if err != nil {
+		return err
+	}
+
+	// Processors and aggregators can only be replaced in place, as the
+	// channels connecting them are fixed when starting.
+	sortProcessors(c.Processors)
+	sortProcessors(c.AggProcessors)
+	if !sameProcessors(r.processors, c.Processors) {
+		return ErrRestartRequired
+	}
+	if (r.aggregators == nil) != (len(c.Aggregators) == 0) {
+		return ErrRestartRequired
+	}
+	if r.aggregators != nil {
+		if !sameProcessors(r.aggProcessors, c.AggProcessors) || !sameAggregators(r.aggregators, c.Aggregators) {
+			return ErrRestartRequired
+		}
+	}
+
+	changed := func(old, plugin interface{}) bool {
+		return r.fingerprints[old] != fingerprints[plugin]
+	}
+
+	var processors, aggProcessors []*models.RunningProcessor
+	for i, unit := range r.processors {
+		if changed(unit.processor, c.Processors[i]) {
+			processors = append(processors, c.Processors[i])
+		}
+	}
+	for i, unit := range r.aggProcessors {
+		if changed(unit.processor, c.AggProcessors[i]) {
+			aggProcessors = append(aggProcessors, c.AggProcessors[i])
+		}
+	}
+	var aggregators []*models.RunningAggregator
+	if r.aggregators != nil {
+		for i, agg := range r.aggregators.aggregators {
+			if changed(agg, c.Aggregators[i]) {
+				aggregators = append(aggregators, c.Aggregators[i])
+			}
+		}
+	}
+
+	// Inputs and outputs are matched by their fingerprints; the remaining
+	// ones are removed and added respectively.
+	keptInputs, addedInputs, removedInputs := matchInputs(r, a.Config.Inputs, c.Inputs, fingerprints)
+	keptOutputs, addedOutputs, removedOutputs := matchOutputs(r, a.Config.Outputs, c.Outputs, fingerprints)
+
+	if len(processors)+len(aggProcessors)+len(aggregators)+len(addedInputs)+len(removedInputs)+
+		len(addedOutputs)+len(removedOutputs) == 0 {
+		log.Printf("I! [agent] Configuration unchanged")
+		return nil
+	}
+
+	// Initialize all new plugins before changing anything.
+	for _, input := range addedInputs {
+		if err := c.ResolveSecrets(input.Input); err != nil {
+			return fmt.Errorf("could not resolve secrets of input %s: %v", input.LogName(), err)
+		}
+		if err := input.Init(); err != nil {
+			return fmt.Errorf("could not initialize input %s: %v", input.LogName(), err)
+		}
+	}
+	for _, processor := range append(append([]*models.RunningProcessor{}, processors...), aggProcessors...) {
+		if err := c.ResolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processor %s: %v", processor.LogName(), err)
+		}
+		if err := processor.Init(); err != nil {
+			return fmt.Errorf("could not initialize processor %s: %v", processor.LogName(), err)
+		}
+	}
+	for _, aggregator := range aggregators {
+		if err := c.ResolveSecrets(aggregator.Aggregator); err != nil {
+			return fmt.Errorf("could not resolve secrets of aggregator %s: %v", aggregator.LogName(), err)
+		}
+		if err := aggregator.Init(); err != nil {
+			return fmt.Errorf("could not initialize aggregator %s: %v", aggregator.LogName(), err)
+		}
+	}
+	for _, output := range addedOutputs {
+		if err := c.ResolveSecrets(output.Output); err != nil {
+			return fmt.Errorf("could not resolve secrets of output %s: %v", output.LogName(), err)
+		}
+		if err := output.Init(); err != nil {
+			return fmt.Errorf("could not initialize output %s: %v", output.LogName(), err)
+		}
+	}
+
+	var errs []string
+
+	for i, unit := range r.processors {
+		if !changed(unit.processor, c.Processors[i]) {
+			c.Processors[i] = unit.processor
+			continue
+		}
+		if err := a.replaceProcessor(unit, c.Processors[i]); err != nil {
+			errs = append(errs, err.Error())
+			c.Processors[i] = unit.processor
+		}
+	}
+	for i, unit := range r.aggProcessors {
+		if !changed(unit.processor, c.AggProcessors[i]) {
+			c.AggProcessors[i] = unit.processor
+			continue
+		}
+		if err := a.replaceProcessor(unit, c.AggProcessors[i]); err != nil {
+			errs = append(errs, err.Error())
+			c.AggProcessors[i] = unit.processor
+		}
+	}
+	if r.aggregators != nil {
+		for i, agg := range r.aggregators.aggregators {
+			if !changed(agg, c.Aggregators[i]) {
+				c.Aggregators[i] = agg
+				continue
+			}
+			if err := a.replaceAggregator(r.aggregators, agg, c.Aggregators[i]); err != nil {
+				errs = append(errs, err.Error())
+				c.Aggregators[i] = agg
+			}
+		}
+	}
+
+	// Remove plugins before adding their replacements, as they might use
+	// the same resources such as listening ports or buffer directories.  The
+	// disk buffer of an output is only opened when it is added, so a changed
+	// output continues with the metrics buffered by the old one.
+	for _, output := range removedOutputs {
+		a.removeOutput(r.outputs, output)
+	}
+	outputs := keptOutputs
+	for _, output := range addedOutputs {
+		if err := a.addOutput(r.outputs, output); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		outputs = append(outputs, output)
+	}
+
+	for _, input := range removedInputs {
+		a.removeInput(r.inputs, input)
+	}
+	inputs := keptInputs
+	for _, input := range addedInputs {
+		if err := a.addInput(r.inputs, input); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		inputs = append(inputs, input)
+	}
+
+	a.mu.Lock()
+	a.Config.Inputs = inputs
+	a.Config.Outputs = outputs
+	a.Config.Processors = c.Processors
+	a.Config.AggProcessors = c.AggProcessors
+	a.Config.Aggregators = c.Aggregators
+	a.Config.SecretStores = c.SecretStores
+	previous := r.fingerprints
+	r.fingerprints = make(map[interface{}]string)
+	for _, plugin := range pluginList(a.Config) {
+		if fp, ok := fingerprints[plugin]; ok {
+			r.fingerprints[plugin] = fp
+		} else {
+			r.fingerprints[plugin] = previous[plugin]
+		}
+	}
+	a.mu.Unlock()
+
+	log.Printf("I! [agent] Configuration applied: %d inputs added, %d inputs removed, "+
+		"%d outputs added, %d outputs removed, %d processors and %d aggregators replaced",
+		len(addedInputs), len(removedInputs), len(addedOutputs), len(removedOutputs),
+		len(processors)+len(aggProcessors), len(aggregators))
+
+	if len(errs) != 0 {
+		return fmt.Errorf("applying configuration failed: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// fingerprints returns the fingerprints of all plugins in the config.
+func (a *Agent) fingerprints(c *config.Config) (map[interface{}]string, error) {
+	fingerprints := make(map[interface{}]string)
+	for _, plugin := range pluginList(c) {
+		fp, err := c.Fingerprint(plugin)
+		if err != nil {
+			return nil, err
+		}
+		fingerprints[plugin] = fp
+	}
+	return fingerprints, nil
+}
+
+// pluginList returns the running plugins of the config.
+func pluginList(c *config.Config) []interface{} {
+	var plugins []interface{}
+	for _, input := range c.Inputs {
+		plugins = append(plugins, input)
+	}
+	for _, processor := range c.Processors {
+		plugins = append(plugins, processor)
+	}
+	for _, processor := range c.AggProcessors {
+		plugins = append(plugins, processor)
+	}
+	for _, aggregator := range c.Aggregators {
+		plugins = append(plugins, aggregator)
+	}
+	for _, output := range c.Outputs {
+		plugins = append(plugins, output)
+	}
+	return plugins
+}
+
+func sameProcessors(units []*processorUnit, processors models.RunningProcessors) bool {
+	if len(units) != len(processors) {
+		return false
+	}
+	for i, unit := range units {
+		if unit.processor.Config.Name != processors[i].Config.Name {
+			return false
+		}
+	}
+	return true
+}
+
+func sameAggregators(unit *aggregatorUnit, aggregators []*models.RunningAggregator) bool {
+	if len(unit.aggregators) != len(aggregators) {
+		return false
+	}
+	for i, agg := range unit.aggregators {
+		if agg.Config.Name != aggregators[i].Config.Name {
+			return false
+		}
+	}
+	return true
+}
+
+// matchInputs splits the inputs into the running ones to keep and the ones
+// to add and remove.
+func matchInputs(
+	r *runningUnits,
+	running, inputs []*models.RunningInput,
+	fingerprints map[interface{}]string,
+) (kept, added, removed []*models.RunningInput) {
+	unmatched := make(map[string][]*models.RunningInput)
+	for _, input := range running {
+		fp := r.fingerprints[input]
+		unmatched[fp] = append(unmatched[fp], input)
+	}
+	for _, input := range inputs {
+		fp := fingerprints[input]
+		if candidates := unmatched[fp]; len(candidates) != 0 {
+			kept = append(kept, candidates[0])
+			unmatched[fp] = candidates[1:]
+			continue
+		}
+		added = append(added, input)
+	}
+	for _, input := range running {
+		for _, candidate := range unmatched[r.fingerprints[input]] {
+			if candidate == input {
+				removed = append(removed, input)
+				break
+			}
+		}
+	}
+	return kept, added, removed
+}
+
+// matchOutputs splits the outputs into the running ones to keep and the ones
+// to add and remove.
+func matchOutputs(
+	r *runningUnits,
+	running, outputs []*models.RunningOutput,
+	fingerprints map[interface{}]string,
+) (kept, added, removed []*models.RunningOutput) {
+	unmatched := make(map[string][]*models.RunningOutput)
+	for _, output := range running {
+		fp := r.fingerprints[output]
+		unmatched[fp] = append(unmatched[fp], output)
+	}
+	for _, output := range outputs {
+		fp := fingerprints[output]
+		if candidates := unmatched[fp]; len(candidates) != 0 {
+			kept = append(kept, candidates[0])
+			unmatched[fp] = candidates[1:]
+			continue
+		}
+		added = append(added, output)
+	}
+	for _, output := range running {
+		for _, candidate := range unmatched[r.fingerprints[output]] {
+			if candidate == output {
+				removed = append(removed, output)
+				break
+			}
+		}
+	}
+	return kept, added, removed
+}
+
+// addInput starts the input and adds it to the running unit.
+func (a *Agent) addInput(unit *inputUnit, input *models.RunningInput) error {
+	unit.mu.Lock()
+	defer unit.mu.Unlock()
+	if unit.ctx == nil || unit.ctx.Err() != nil {
+		return fmt.Errorf("adding input %s: inputs are not running", input.LogName())
+	}
+
+	log.Printf("I! [agent] Starting input %s", input.LogName())
+	if err := startServiceInput(input, unit.dst); err != nil {
+		return err
+	}
+	unit.inputs = append(unit.inputs, input)
+	a.startGatherLoop(unit, input, time.Now())
+	return nil
+}
+
+// removeInput stops the input, waiting for an ongoing gather to complete.
+func (a *Agent) removeInput(unit *inputUnit, input *models.RunningInput) {
+	unit.mu.Lock()
+	if unit.ctx == nil || unit.ctx.Err() != nil {
+		// The input is stopped on shutdown
+		unit.mu.Unlock()
+		return
+	}
+	loop := unit.loops[input]
+	delete(unit.loops, input)
+	inputs := make([]*models.RunningInput, 0, len(unit.inputs))
+	for _, ri := range unit.inputs {
+		if ri != input {
+			inputs = append(inputs, ri)
+		}
+	}
+	unit.inputs = inputs
+	unit.mu.Unlock()
+
+	log.Printf("I! [agent] Stopping input %s", input.LogName())
+	if loop != nil {
+		loop.stop()
+	}
+	stopServiceInputs([]*models.RunningInput{input})
+}
+
+// addOutput connects the output and adds it to the running unit.
+func (a *Agent) addOutput(unit *outputUnit, output *models.RunningOutput) error {
+	unit.mu.RLock()
+	ctx := unit.ctx
+	unit.mu.RUnlock()
+	if ctx == nil {
+		return fmt.Errorf("adding output %s: outputs are not running", output.LogName())
+	}
+
+	log.Printf("I! [agent] Starting output %s", output.LogName())
+	if err := a.connectOutput(ctx, output); err != nil {
+		return fmt.Errorf("connecting output %s: %w", output.LogName(), err)
+	}
+
+	unit.mu.Lock()
+	defer unit.mu.Unlock()
+	if ctx.Err() != nil {
+		output.Close()
+		return fmt.Errorf("adding output %s: outputs are not running", output.LogName())
+	}
+	unit.outputs = append(unit.outputs, output)
+	a.startFlushLoop(unit, output)
+	return nil
+}
+
+// removeOutput removes the output from the running unit, writes its buffered
+// metrics one last time and closes it.
+func (a *Agent) removeOutput(unit *outputUnit, output *models.RunningOutput) {
+	unit.mu.Lock()
+	if unit.ctx == nil || unit.ctx.Err() != nil {
+		// The output is closed on shutdown
+		unit.mu.Unlock()
+		return
+	}
+	loop := unit.loops[output]
+	delete(unit.loops, output)
+	outputs := make([]*models.RunningOutput, 0, len(unit.outputs))
+	for _, ro := range unit.outputs {
+		if ro != output {
+			outputs = append(outputs, ro)
+		}
+	}
+	unit.outputs = outputs
+	unit.mu.Unlock()
+
+	log.Printf("I! [agent] Stopping output %s", output.LogName())
+	if loop != nil {
+		loop.stop()
+	}
+	output.Close()
+}
+
+// replaceProcessor starts the processor and replaces the one of the unit
+// with it.
+func (a *Agent) replaceProcessor(unit *processorUnit, processor *models.RunningProcessor) error {
+	log.Printf("I! [agent] Replacing processor %s", processor.LogName())
+	acc := NewAccumulator(processor, unit.dst)
+	if err := processor.Start(acc); err != nil {
+		return fmt.Errorf("starting processor %s: %w", processor.LogName(), err)
+	}
+
+	unit.mu.Lock()
+	defer unit.mu.Unlock()
+	if unit.stopped {
+		processor.Stop()
+		return fmt.Errorf("replacing processor %s: processors are not running", processor.LogName())
+	}
+	unit.processor.Stop()
+	unit.processor = processor
+	unit.acc = acc
+	return nil
+}
+
+// replaceAggregator replaces the running aggregator by the new one.  The old
+// aggregator pushes its current window one last time.
+func (a *Agent) replaceAggregator(unit *aggregatorUnit, old, agg *models.RunningAggregator) error {
+	unit.mu.Lock()
+	if unit.ctx == nil || unit.ctx.Err() != nil {
+		unit.mu.Unlock()
+		return fmt.Errorf("replacing aggregator %s: aggregators are not running", agg.LogName())
+	}
+	for i, ra := range unit.aggregators {
+		if ra == old {
+			unit.aggregators[i] = agg
+		}
+	}
+	loop := unit.loops[old]
+	delete(unit.loops, old)
+	a.startPushLoop(unit, agg, time.Now())
+	unit.mu.Unlock()
+
+	log.Printf("I! [agent] Replacing aggregator %s", agg.LogName())
+	if loop != nil {
+		loop.stop()
+	}
+	return nil
+}
+
+// snapshot returns the plugins of the config, safe for use while reloading.
+func (a *Agent) snapshot() config.Config {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return config.Config{
+		Inputs:        a.Config.Inputs,
+		Outputs:       a.Config.Outputs,
+		Aggregators:   a.Config.Aggregators,
+		Processors:    a.Config.Processors,
+		AggProcessors: a.Config.AggProcessors,
+	}
+}
@@ -0,0 +1,305 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/stretchr/testify/require"
+)
+
+type reloadTestInput struct {
+	Value string `toml:"value"`
+
+	mu      sync.Mutex
+	started bool
+	stopped bool
+}
+
+func (i *reloadTestInput) SampleConfig() string              { return "" }
+func (i *reloadTestInput) Description() string               { return "" }
+func (i *reloadTestInput) Gather(telegraf.Accumulator) error { return nil }
+func (i *reloadTestInput) Start(telegraf.Accumulator) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.started = true
+	return nil
+}
+func (i *reloadTestInput) Stop() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.stopped = true
+}
+func (i *reloadTestInput) state() (bool, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.started, i.stopped
+}
+
+type reloadTestOutput struct {
+	Value string `toml:"value"`
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (o *reloadTestOutput) SampleConfig() string          { return "" }
+func (o *reloadTestOutput) Description() string           { return "" }
+func (o *reloadTestOutput) Connect() error                { return nil }
+func (o *reloadTestOutput) Write([]telegraf.Metric) error { return nil }
+func (o *reloadTestOutput) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	return nil
+}
+func (o *reloadTestOutput) isClosed() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.closed
+}
+
+type reloadTestProcessor struct {
+	Value string `toml:"value"`
+}
+
+func (p *reloadTestProcessor) SampleConfig() string { return "" }
+func (p *reloadTestProcessor) Description() string  { return "" }
+func (p *reloadTestProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	return in
+}
+
+func init() {
+	processors.Add("reloadtest", func() telegraf.Processor { return &reloadTestProcessor{} })
+	inputs.Add("reloadtest", func() telegraf.Input { return &reloadTestInput{} })
+	outputs.Add("reloadtest", func() telegraf.Output { return &reloadTestOutput{} })
+}
+
+func loadReloadTestConfig(t *testing.T, data string) *config.Config {
+	c := config.NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(data)))
+	return c
+}
+
+// runReloadTestAgent runs the agent until the test ends and waits for all
+// units to be running.
+func runReloadTestAgent(t *testing.T, c *config.Config) *Agent {
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	require.Eventually(t, func() bool {
+		a.mu.Lock()
+		r := a.running
+		a.mu.Unlock()
+		if r == nil {
+			return false
+		}
+		r.inputs.mu.Lock()
+		inputsRunning := r.inputs.ctx != nil
+		r.inputs.mu.Unlock()
+		r.outputs.mu.RLock()
+		outputsRunning := r.outputs.ctx != nil
+		r.outputs.mu.RUnlock()
+		return inputsRunning && outputsRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return a
+}
+
+const reloadTestConfig = `
+[agent]
+  interval = "1h"
+  flush_interval = "1h"
+  omit_hostname = true
+
+[[inputs.reloadtest]]
+  value = "unchanged"
+
+[[inputs.reloadtest]]
+  value = "changed"
+
+[[outputs.reloadtest]]
+  value = "unchanged"
+
+[[outputs.reloadtest]]
+  value = "removed"
+`
+
+func TestAgent_ApplyConfig(t *testing.T) {
+	a := runReloadTestAgent(t, loadReloadTestConfig(t, reloadTestConfig))
+
+	unchangedInput := a.Config.Inputs[0]
+	changedInput := a.Config.Inputs[1].Input.(*reloadTestInput)
+	unchangedOutput := a.Config.Outputs[0]
+	removedOutput := a.Config.Outputs[1].Output.(*reloadTestOutput)
+
+	require.NoError(t, a.ApplyConfig(loadReloadTestConfig(t, `
+[agent]
+  interval = "1h"
+  flush_interval = "1h"
+  omit_hostname = true
+
+[[inputs.reloadtest]]
+  value = "unchanged"
+
+[[inputs.reloadtest]]
+  value = "new"
+
+[[outputs.reloadtest]]
+  value = "unchanged"
+`)))
+
+	cfg := a.snapshot()
+	require.Len(t, cfg.Inputs, 2)
+	require.Same(t, unchangedInput, cfg.Inputs[0])
+	require.Equal(t, "new", cfg.Inputs[1].Input.(*reloadTestInput).Value)
+	started, stopped := cfg.Inputs[1].Input.(*reloadTestInput).state()
+	require.True(t, started)
+	require.False(t, stopped)
+	_, stopped = unchangedInput.Input.(*reloadTestInput).state()
+	require.False(t, stopped)
+	_, stopped = changedInput.state()
+	require.True(t, stopped)
+
+	require.Len(t, cfg.Outputs, 1)
+	require.Same(t, unchangedOutput, cfg.Outputs[0])
+	require.False(t, unchangedOutput.Output.(*reloadTestOutput).isClosed())
+	require.True(t, removedOutput.isClosed())
+
+	// Applying the same config again does not change anything
+	require.NoError(t, a.ApplyConfig(loadReloadTestConfig(t, `
+[agent]
+  interval = "1h"
+  flush_interval = "1h"
+  omit_hostname = true
+
+[[inputs.reloadtest]]
+  value = "new"
+
+[[inputs.reloadtest]]
+  value = "unchanged"
+
+[[outputs.reloadtest]]
+  value = "unchanged"
+`)))
+	require.Equal(t, cfg.Inputs, a.snapshot().Inputs)
+	require.Equal(t, cfg.Outputs, a.snapshot().Outputs)
+}
+
+func TestAgent_ApplyConfigDiskBuffer(t *testing.T) {
+	dir := t.TempDir()
+	config := func(value string) string {
+		return fmt.Sprintf(`
+[agent]
+  interval = "1h"
+  flush_interval = "1h"
+  omit_hostname = true
+  buffer_strategy = "disk"
+  buffer_directory = %q
+
+[[outputs.reloadtest]]
+  value = %q
+`, dir, value)
+	}
+	a := runReloadTestAgent(t, loadReloadTestConfig(t, config("before")))
+	before := a.Config.Outputs[0].Output.(*reloadTestOutput)
+
+	// The replacement uses the buffer directory of the changed output
+	require.NoError(t, a.ApplyConfig(loadReloadTestConfig(t, config("after"))))
+
+	cfg := a.snapshot()
+	require.Len(t, cfg.Outputs, 1)
+	require.Equal(t, "after", cfg.Outputs[0].Output.(*reloadTestOutput).Value)
+	require.True(t, before.isClosed())
+}
+
+func TestAgent_ApplyConfigProcessors(t *testing.T) {
+	a := runReloadTestAgent(t, loadReloadTestConfig(t, reloadTestConfig+`
+[[processors.reloadtest]]
+  value = "before"
+`))
+	before := a.snapshot()
+
+	require.NoError(t, a.ApplyConfig(loadReloadTestConfig(t, reloadTestConfig+`
+[[processors.reloadtest]]
+  value = "after"
+`)))
+
+	after := a.snapshot()
+	require.Len(t, after.Processors, 1)
+	require.NotSame(t, before.Processors[0], after.Processors[0])
+	require.Equal(t, "after", after.Processors[0].Processor.(interface{ Unwrap() telegraf.Processor }).Unwrap().(*reloadTestProcessor).Value)
+	require.Equal(t, before.Inputs, after.Inputs)
+	require.Equal(t, before.Outputs, after.Outputs)
+}
+
+func TestAgent_ApplyConfigRestartRequired(t *testing.T) {
+	a := runReloadTestAgent(t, loadReloadTestConfig(t, reloadTestConfig))
+	before := a.snapshot()
+
+	err := a.ApplyConfig(loadReloadTestConfig(t, `
+[agent]
+  interval = "2h"
+  flush_interval = "1h"
+  omit_hostname = true
+
+[[inputs.reloadtest]]
+  value = "unchanged"
+
+[[outputs.reloadtest]]
+  value = "unchanged"
+`))
+	require.Equal(t, ErrRestartRequired, err)
+
+	err = a.ApplyConfig(loadReloadTestConfig(t, reloadTestConfig+`
+[[processors.reloadtest]]
+`))
+	require.Equal(t, ErrRestartRequired, err)
+
+	after := a.snapshot()
+	require.Equal(t, before.Inputs, after.Inputs)
+	require.Equal(t, before.Outputs, after.Outputs)
+}
+
+func TestAgent_ApplyConfigNotRunning(t *testing.T) {
+	a, err := NewAgent(loadReloadTestConfig(t, reloadTestConfig))
+	require.NoError(t, err)
+	require.Error(t, a.ApplyConfig(loadReloadTestConfig(t, reloadTestConfig)))
+}
+
+func TestAgent_Started(t *testing.T) {
+	a, err := NewAgent(loadReloadTestConfig(t, reloadTestConfig))
+	require.NoError(t, err)
+	started := make(chan struct{})
+	a.Started = func() { close(started) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- a.Run(ctx)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "agent not started")
+	}
+	cancel()
+	require.NoError(t, <-done)
+}
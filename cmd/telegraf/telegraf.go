@@ -1,10 +1,12 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	_ "net/http/pprof" // Comment this line to disable pprof endpoint.
@@ -12,6 +14,7 @@ import (
 	"os/signal"
 	"sort"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -27,6 +30,8 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 )
 
 type sliceFlags []string
@@ -93,6 +98,19 @@ var fRunAsConsole = flag.Bool("console", false,
 var fPlugins = flag.String("plugin-directory", "",
 	"path to directory containing external plugins")
 var fRunOnce = flag.Bool("once", false, "run one gather and exit")
+var fValidate = flag.Bool("validate", false, "validate the config and all plugins without starting them, then exit")
+var fWatchConfig = flag.Duration("watch-config", 0,
+	"check the config files for changes at this interval and reload them, disabled if zero")
+var fConfigURLPollInterval = flag.Duration("config-url-poll-interval", 0,
+	"check remote configs for changes at this interval and reload them, disabled if zero")
+var fConfigURLCacheDir = flag.String("config-url-cache-dir", "",
+	"directory keeping the last known good copy of remote configs")
+var fConfigURLChecksum = flag.Bool("config-url-checksum", false,
+	"require the SHA256 checksum of remote configs at '<url>.sha256'")
+var fConfigURLPublicKey = flag.String("config-url-public-key", "",
+	"file with the base64 encoded ed25519 public key verifying the signature of remote configs at '<url>.sig'")
+var fConfigURLTimeout = flag.Duration("config-url-timeout", config.DefaultRemoteTimeout,
+	"timeout of the requests fetching remote configs")
 
 var (
 	version string
@@ -112,60 +130,231 @@ func reloadLoop(
 		reload <- false
 		ctx, cancel := context.WithCancel(context.Background())
 
+		// restart stops the running agent and starts it again with a freshly
+		// loaded config; used if config changes cannot be applied while
+		// running.
+		var once sync.Once
+		restart := func() {
+			once.Do(func() {
+				log.Printf("I! Restarting Telegraf")
+				<-reload
+				reload <- true
+				cancel()
+			})
+		}
+
+		// requestReload asks the running agent to apply the current config;
+		// used by SIGHUP, the management API and the config watcher.
+		reloads := make(chan struct{}, 1)
+		requestReload := func() {
+			select {
+			case reloads <- struct{}{}:
+			default:
+			}
+		}
+
 		signals := make(chan os.Signal, 1)
 		signal.Notify(signals, os.Interrupt, syscall.SIGHUP,
 			syscall.SIGTERM, syscall.SIGINT)
 		go func() {
-			select {
-			case sig := <-signals:
-				if sig == syscall.SIGHUP {
-					log.Printf("I! Reloading Telegraf config")
-					<-reload
-					reload <- true
+			defer signal.Stop(signals)
+			for {
+				select {
+				case sig := <-signals:
+					if sig == syscall.SIGHUP {
+						requestReload()
+						continue
+					}
+					cancel()
+				case <-stop:
+					cancel()
+				case <-ctx.Done():
 				}
-				cancel()
-			case <-stop:
-				cancel()
+				return
 			}
 		}()
 
-		err := runAgent(ctx, inputFilters, outputFilters)
+		err := runAgent(ctx, inputFilters, outputFilters, requestReload, reloads, restart)
 		if err != nil && err != context.Canceled {
 			log.Fatalf("E! [telegraf] Error running agent: %v", err)
 		}
 	}
 }
 
-func runAgent(ctx context.Context,
-	inputFilters []string,
-	outputFilters []string,
-) error {
-	log.Printf("I! Starting Telegraf %s", version)
+// reloadConfig loads the config again and applies the changes to the running
+// agent, restarting it if the changes cannot be applied while running.  The
+// running config is kept if the new one cannot be loaded.  The remote configs
+// of an applied config are committed to the remote watcher, if any, and stored
+// as last known good copies.
+func reloadConfig(ag *agent.Agent, inputFilters, outputFilters []string, restart func(), remote *config.RemoteWatcher) {
+	log.Printf("I! Reloading Telegraf config")
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err == nil && len(c.Outputs) == 0 {
+		err = errors.New("no outputs found")
+	}
+	if err != nil {
+		log.Printf("E! [telegraf] Error reloading config, keeping the running config: %v", err)
+		return
+	}
+	for _, deprecation := range c.Deprecations {
+		log.Printf("W! DeprecationWarning: %s", deprecation)
+	}
 
-	// If no other options are specified, load the config file and run.
+	err = ag.ApplyConfig(c)
+	if errors.Is(err, agent.ErrRestartRequired) {
+		log.Printf("I! [telegraf] %v", err)
+		restart()
+		return
+	}
+	if err != nil {
+		log.Printf("E! [telegraf] Error applying config: %v", err)
+		return
+	}
+
+	c.StoreRemoteConfigs()
+	if remote != nil {
+		remote.Commit(c)
+	}
+}
+
+// loadConfig loads the config files and directories given on the command line.
+func loadConfig(inputFilters, outputFilters []string) (*config.Config, error) {
 	c := config.NewConfig()
 	c.OutputFilters = outputFilters
 	c.InputFilters = inputFilters
-	var err error
+	if err := setRemoteOptions(c); err != nil {
+		return nil, err
+	}
 	// providing no "config" flag should load default config
 	if len(fConfigs) == 0 {
-		err = c.LoadConfig("")
-		if err != nil {
-			return err
+		if err := c.LoadConfig(""); err != nil {
+			return nil, err
 		}
 	}
 	for _, fConfig := range fConfigs {
-		err = c.LoadConfig(fConfig)
-		if err != nil {
-			return err
+		if err := c.LoadConfig(fConfig); err != nil {
+			return nil, err
 		}
 	}
 
 	for _, fConfigDirectory := range fConfigDirs {
-		err = c.LoadDirectory(fConfigDirectory)
+		if err := c.LoadDirectory(fConfigDirectory); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// setRemoteOptions sets the options for loading remote configs given on the
+// command line.
+func setRemoteOptions(c *config.Config) error {
+	c.Remote.CacheDir = *fConfigURLCacheDir
+	c.Remote.Checksum = *fConfigURLChecksum
+	c.Remote.Timeout = *fConfigURLTimeout
+	if *fConfigURLPublicKey != "" {
+		key, err := config.LoadPublicKey(*fConfigURLPublicKey)
 		if err != nil {
 			return err
 		}
+		c.Remote.PublicKey = key
+	}
+	return nil
+}
+
+// validateConfig loads every config file and initializes all plugins without
+// starting them, reporting all problems found.  It returns false if the
+// config is invalid.
+func validateConfig() bool {
+	var files []string
+	files = append(files, fConfigs...)
+	if len(fConfigs) == 0 && len(fConfigDirs) == 0 {
+		// providing no "config" flag should check the default config
+		files = append(files, "")
+	}
+	for _, dir := range fConfigDirs {
+		dirFiles, err := config.DirectoryFiles(dir)
+		if err != nil {
+			log.Printf("E! %v", err)
+			return false
+		}
+		files = append(files, dirFiles...)
+	}
+
+	valid := true
+	c := config.NewConfig()
+	if err := setRemoteOptions(c); err != nil {
+		log.Printf("E! %v", err)
+		return false
+	}
+	for _, file := range files {
+		if err := c.LoadConfig(file); err != nil {
+			log.Printf("E! %v", err)
+			valid = false
+		}
+	}
+	for _, deprecation := range c.Deprecations {
+		log.Printf("W! DeprecationWarning: %s", deprecation)
+	}
+	if !valid {
+		return false
+	}
+
+	ag, err := agent.NewAgent(c)
+	if err != nil {
+		log.Printf("E! %v", err)
+		return false
+	}
+	for _, err := range ag.Validate() {
+		log.Printf("E! %v", err)
+		valid = false
+	}
+	if valid {
+		log.Printf("I! Config is valid: %d inputs, %d processors, %d aggregators, %d outputs",
+			len(c.Inputs), len(c.Processors), len(c.Aggregators), len(c.Outputs))
+	}
+	return valid
+}
+
+// setSecret stores a secret read from stdin in the secret store with the
+// given id.
+func setSecret(id, key string) error {
+	c, err := loadConfig(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	store, ok := c.SecretStores[id]
+	if !ok {
+		return fmt.Errorf("unknown secretstore %q", id)
+	}
+	setter, ok := store.(secretstores.Setter)
+	if !ok {
+		return fmt.Errorf("secretstore %q does not support storing secrets", id)
+	}
+	if err := store.Init(); err != nil {
+		return err
+	}
+
+	value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	return setter.Set(key, strings.TrimRight(value, "\r\n"))
+}
+
+func runAgent(ctx context.Context,
+	inputFilters []string,
+	outputFilters []string,
+	reload func(),
+	reloads <-chan struct{},
+	restart func(),
+) error {
+	log.Printf("I! Starting Telegraf %s", version)
+
+	// If no other options are specified, load the config file and run.
+	c, err := loadConfig(inputFilters, outputFilters)
+	if err != nil {
+		return err
 	}
 
 	if !*fTest && len(c.Outputs) == 0 {
@@ -187,6 +376,8 @@ func runAgent(ctx context.Context,
 	if err != nil {
 		return err
 	}
+	ag.Reload = reload
+	ag.Started = c.StoreRemoteConfigs
 
 	// Setup logging as configured.
 	telegraf.Debug = ag.Config.Agent.Debug || *fDebug
@@ -203,6 +394,10 @@ func runAgent(ctx context.Context,
 
 	logger.SetupLogging(logConfig)
 
+	for _, deprecation := range c.Deprecations {
+		log.Printf("W! DeprecationWarning: %s", deprecation)
+	}
+
 	if *fRunOnce {
 		wait := time.Duration(*fTestWait) * time.Second
 		return ag.Once(ctx, wait)
@@ -237,6 +432,29 @@ func runAgent(ctx context.Context,
 		}
 	}
 
+	var remote *config.RemoteWatcher
+	if *fConfigURLPollInterval > 0 {
+		remote = config.NewRemoteWatcher(c)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reloads:
+				reloadConfig(ag, inputFilters, outputFilters, restart, remote)
+			}
+		}
+	}()
+
+	if *fWatchConfig > 0 {
+		go config.NewWatcher(fConfigs, fConfigDirs).Watch(ctx, *fWatchConfig, reload)
+	}
+	if remote != nil {
+		go remote.Watch(ctx, *fConfigURLPollInterval, reload)
+	}
+
 	return ag.Run(ctx)
 }
 
@@ -327,7 +545,21 @@ func main() {
 		case "version":
 			fmt.Println(formatFullVersion())
 			return
+		case "secret":
+			if len(args) != 4 || args[1] != "set" {
+				log.Fatal("E! usage: telegraf secret set <store-id> <key>")
+			}
+			if err := setSecret(args[2], args[3]); err != nil {
+				log.Fatal("E! " + err.Error())
+			}
+			return
 		case "config":
+			if len(args) > 1 && args[1] == "check" {
+				if !validateConfig() {
+					os.Exit(1)
+				}
+				return
+			}
 			config.PrintSampleConfig(
 				sectionFilters,
 				inputFilters,
@@ -366,6 +598,11 @@ func main() {
 	case *fVersion:
 		fmt.Println(formatFullVersion())
 		return
+	case *fValidate:
+		if !validateConfig() {
+			os.Exit(1)
+		}
+		return
 	case *fSampleConfig:
 		config.PrintSampleConfig(
 			sectionFilters,
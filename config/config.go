@@ -27,6 +27,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/parsers/json_v2"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
@@ -56,6 +57,9 @@ var (
 	// fetchURLRe is a regex to determine whether the requested file should
 	// be fetched from a remote or read from the filesystem.
 	fetchURLRe = regexp.MustCompile(`^\w+://`)
+
+	// secretStoreIDRe is a regex for valid secret store ids
+	secretStoreIDRe = regexp.MustCompile(`^\w+$`)
 )
 
 // Config specifies the URL/user/password for the database that telegraf
@@ -77,6 +81,24 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+
+	// SecretStores by their id
+	SecretStores map[string]telegraf.SecretStore
+
+	// Deprecations lists the deprecated options used in the loaded config
+	Deprecations []string
+
+	// Remote are the options for configs loaded from http(s) URLs
+	Remote RemoteOptions
+
+	// sources holds the canonical configuration of each running plugin and
+	// agentSource the one of the agent and global tags, used for computing
+	// fingerprints when reloading.
+	sources     map[interface{}]string
+	agentSource string
+
+	// remoteConfigs holds the content of the loaded remote configs by URL
+	remoteConfigs map[string][]byte
 }
 
 // NewConfig creates a new struct to hold the Telegraf config.
@@ -84,7 +106,9 @@ type Config struct {
 // once the configuration is parsed.
 func NewConfig() *Config {
 	c := &Config{
-		UnusedFields: map[string]bool{},
+		UnusedFields:  map[string]bool{},
+		sources:       make(map[interface{}]string),
+		remoteConfigs: make(map[string][]byte),
 
 		// Agent defaults:
 		Agent: &AgentConfig{
@@ -100,6 +124,7 @@ func NewConfig() *Config {
 		Outputs:       make([]*models.RunningOutput, 0),
 		Processors:    make([]*models.RunningProcessor, 0),
 		AggProcessors: make([]*models.RunningProcessor, 0),
+		SecretStores:  make(map[string]telegraf.SecretStore),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
 	}
@@ -157,15 +182,34 @@ type AgentConfig struct {
 	// not be less than 2 times MetricBatchSize.
 	MetricBufferLimit int
 
+	// BufferStrategy selects where each output keeps unwritten metrics,
+	// either "memory" or "disk".  The disk buffer persists metrics in
+	// BufferDirectory so they are not lost when the agent restarts.
+	BufferStrategy string `toml:"buffer_strategy"`
+
+	// BufferDirectory is the directory in which the disk buffer of each
+	// output keeps its own subdirectory.
+	BufferDirectory string `toml:"buffer_directory"`
+
+	// BufferMaxSize is the maximum size on disk of the buffer of each output.
+	// When exceeded the oldest metrics are dropped.  Only used by the disk
+	// buffer; when set to 0 no size based retention is performed.
+	BufferMaxSize Size `toml:"buffer_max_size"`
+
+	// BufferMaxAge is the maximum age of metrics in the buffer of each output.
+	// Only used by the disk buffer; when set to 0 no age based retention is
+	// performed.
+	BufferMaxAge Duration `toml:"buffer_max_age"`
+
 	// FlushBufferWhenFull tells Telegraf to flush the metric buffer whenever
 	// it fills up, regardless of FlushInterval. Setting this option to true
 	// does _not_ deactivate FlushInterval.
-	FlushBufferWhenFull bool // deprecated in 0.13; has no effect
+	FlushBufferWhenFull bool `deprecated:"0.13.0;has no effect"`
 
 	// TODO(cam): Remove UTC and parameter, they are no longer
 	// valid for the agent config. Leaving them here for now for backwards-
 	// compatibility
-	UTC bool `toml:"utc"` // deprecated in 1.0.0; has no effect
+	UTC bool `toml:"utc" deprecated:"1.0.0;has no effect"`
 
 	// Debug is the option for running in debug mode
 	Debug bool `toml:"debug"`
@@ -199,6 +243,10 @@ type AgentConfig struct {
 
 	Hostname     string
 	OmitHostname bool
+
+	// APIAddress is the address the local management API listens on.  The
+	// API is disabled when empty.
+	APIAddress string `toml:"api_address"`
 }
 
 // InputNames returns a list of strings of the configured inputs.
@@ -315,6 +363,16 @@ var agentConfig = `
   ## cost of higher maximum memory usage.
   metric_buffer_limit = 10000
 
+  ## Buffer strategy for unwritten metrics, either "memory" or "disk".  The
+  ## disk buffer keeps the metrics in a write-ahead-log below buffer_directory
+  ## so they survive a restart of Telegraf.
+  # buffer_strategy = "memory"
+  # buffer_directory = ""
+  ## Maximum size and age of the disk buffer of each output, the oldest
+  ## metrics are dropped when exceeded.  Zero disables the limit.
+  # buffer_max_size = "0MB"
+  # buffer_max_age = "0s"
+
   ## Collection jitter is used to jitter the collection by a random amount.
   ## Each plugin will sleep for a random time within jitter before collecting.
   ## This can be used to avoid many plugins querying things like sysfs at the
@@ -373,6 +431,12 @@ var agentConfig = `
   hostname = ""
   ## If set to true, do no set the "host" tag in the telegraf agent.
   omit_hostname = false
+
+  ## Address of the local HTTP management API.  The API lists the loaded
+  ## plugins with their status and allows to reload the configuration or
+  ## flush an output.  It is not authenticated and should only listen on a
+  ## local address.  Disabled if empty.
+  # api_address = "localhost:8089"
 `
 
 var outputHeader = `
@@ -670,6 +734,22 @@ func PrintOutputConfig(name string) error {
 
 // LoadDirectory loads all toml config files found in the specified path, recursively.
 func (c *Config) LoadDirectory(path string) error {
+	files, err := DirectoryFiles(path)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := c.LoadConfig(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DirectoryFiles returns the config files (*.conf) in the directory and its
+// subdirectories in lexical order.
+func DirectoryFiles(path string) ([]string, error) {
+	var files []string
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info == nil {
 			log.Printf("W! Telegraf is not permitted to read %s", thispath)
@@ -688,13 +768,11 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			return nil
 		}
-		err := c.LoadConfig(thispath)
-		if err != nil {
-			return err
-		}
+		files = append(files, thispath)
 		return nil
 	}
-	return filepath.Walk(path, walkfn)
+	err := filepath.Walk(path, walkfn)
+	return files, err
 }
 
 // Try to find a default config file at these locations (in order):
@@ -743,19 +821,32 @@ func (c *Config) LoadConfig(path string) error {
 			return err
 		}
 	}
-	data, err := loadConfig(path)
+	data, err := c.loadConfig(path)
 	if err != nil {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
 
-	if err = c.LoadConfigData(data); err != nil {
+	n := len(c.Deprecations)
+	err = c.LoadConfigData(data)
+	for i := n; i < len(c.Deprecations); i++ {
+		c.Deprecations[i] = path + ": " + c.Deprecations[i]
+	}
+	if err != nil {
 		return fmt.Errorf("Error loading config file %s: %w", path, err)
 	}
+
+	if fetchURLRe.MatchString(path) {
+		c.remoteLoaded(path, data)
+	}
 	return nil
 }
 
 // LoadConfigData loads TOML-formatted config data
 func (c *Config) LoadConfigData(data []byte) error {
+	// Errors of previously loaded data must not leak into this one
+	c.errs = nil
+	c.UnusedFields = map[string]bool{}
+
 	tbl, err := parseConfig(data)
 	if err != nil {
 		return fmt.Errorf("Error parsing data: %s", err)
@@ -771,6 +862,7 @@ func (c *Config) LoadConfigData(data []byte) error {
 			if err = c.toml.UnmarshalTable(subTable, c.Tags); err != nil {
 				return fmt.Errorf("error parsing table name %q: %s", tableName, err)
 			}
+			c.agentSource += tableName + "\n" + tableSource(subTable)
 		}
 	}
 
@@ -783,6 +875,8 @@ func (c *Config) LoadConfigData(data []byte) error {
 		if err = c.toml.UnmarshalTable(subTable, c.Agent); err != nil {
 			return fmt.Errorf("error parsing [agent]: %w", err)
 		}
+		c.agentSource += "agent\n" + tableSource(subTable)
+		c.checkDeprecations("agent", subTable, c.Agent)
 	}
 
 	if !c.Agent.OmitHostname {
@@ -889,6 +983,23 @@ func (c *Config) LoadConfigData(data []byte) error {
 					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
 				}
 			}
+		case "secretstores":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addSecretStore(pluginName, t); err != nil {
+							return fmt.Errorf("error parsing %s, %w", pluginName, err)
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s",
+						pluginName)
+				}
+				if len(c.UnusedFields) > 0 {
+					return fmt.Errorf("plugin %s.%s: line %d: configuration specified the fields %q, but they weren't used", name, pluginName, subTable.Line, keys(c.UnusedFields))
+				}
+			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
@@ -917,7 +1028,7 @@ func escapeEnv(value string) string {
 	return envVarEscaper.Replace(value)
 }
 
-func loadConfig(config string) ([]byte, error) {
+func (c *Config) loadConfig(config string) ([]byte, error) {
 	if fetchURLRe.MatchString(config) {
 		u, err := url.Parse(config)
 		if err != nil {
@@ -926,7 +1037,7 @@ func loadConfig(config string) ([]byte, error) {
 
 		switch u.Scheme {
 		case "https", "http":
-			return fetchConfig(u)
+			return c.fetchRemoteConfig(u)
 		default:
 			return nil, fmt.Errorf("scheme %q not supported", u.Scheme)
 		}
@@ -936,7 +1047,7 @@ func loadConfig(config string) ([]byte, error) {
 	return ioutil.ReadFile(config)
 }
 
-func fetchConfig(u *url.URL) ([]byte, error) {
+func newConfigRequest(u *url.URL) (*http.Request, error) {
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
 		return nil, err
@@ -947,10 +1058,18 @@ func fetchConfig(u *url.URL) ([]byte, error) {
 	}
 	req.Header.Add("Accept", "application/toml")
 	req.Header.Set("User-Agent", internal.ProductToken())
+	return req, nil
+}
+
+func fetchConfig(client *http.Client, u *url.URL) ([]byte, error) {
+	req, err := newConfigRequest(u)
+	if err != nil {
+		return nil, err
+	}
 
 	retries := 3
 	for i := 0; i <= retries; i++ {
-		resp, err := http.DefaultClient.Do(req)
+		resp, err := client.Do(req)
 		if err != nil {
 			return nil, fmt.Errorf("Retry %d of %d failed connecting to HTTP config server %s", i, retries, err)
 		}
@@ -1016,8 +1135,38 @@ func (c *Config) addAggregator(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, aggregator); err != nil {
 		return err
 	}
+	c.checkDeprecations("aggregators."+name, table, aggregator)
+
+	ra := models.NewRunningAggregator(aggregator, conf)
+	c.sources[ra] = pluginSource("aggregators", name, table)
+	c.Aggregators = append(c.Aggregators, ra)
+	return nil
+}
+
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+
+	var id string
+	c.getFieldString(table, "id", &id)
+	delete(table.Fields, "id")
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("invalid secretstore id %q, it must only contain letters, digits and underscores", id)
+	}
+	if _, found := c.SecretStores[id]; found {
+		return fmt.Errorf("duplicate secretstore id %q", id)
+	}
+
+	if err := c.toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+	c.checkDeprecations("secretstores."+name, table, store)
 
-	c.Aggregators = append(c.Aggregators, models.NewRunningAggregator(aggregator, conf))
+	models.SetLoggerOnPlugin(store, models.NewLogger("secretstores", name, id))
+	c.SecretStores[id] = store
 	return nil
 }
 
@@ -1036,6 +1185,8 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	c.checkDeprecations("processors."+name, table, rf.Processor)
+	c.sources[rf] = pluginSource("processors", name, table)
 	c.Processors = append(c.Processors, rf)
 
 	// save a copy for the aggregator
@@ -1043,6 +1194,7 @@ func (c *Config) addProcessor(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	c.sources[rf] = pluginSource("processors", name, table)
 	c.AggProcessors = append(c.AggProcessors, rf)
 
 	return nil
@@ -1081,9 +1233,15 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var sc *serializers.Config
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := c.buildSerializer(table)
+		var err error
+		sc, err = c.buildSerializerConfig(table)
+		if err != nil {
+			return err
+		}
+		serializer, err := serializers.NewSerializer(sc)
 		if err != nil {
 			return err
 		}
@@ -1094,12 +1252,22 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	if err != nil {
 		return err
 	}
+	if outputConfig.MetricBatchBytes > 0 {
+		// Batch sizes are estimated with a serializer of their own, as
+		// serializing can change the state of the output's serializer.
+		outputConfig.SizeSerializer, err = serializers.NewSizeSerializer(sc)
+		if err != nil {
+			return err
+		}
+	}
 
 	if err := c.toml.UnmarshalTable(table, output); err != nil {
 		return err
 	}
+	c.checkDeprecations("outputs."+name, table, output)
 
 	ro := models.NewRunningOutput(output, outputConfig, c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
+	c.sources[ro] = pluginSource("outputs", name, table)
 	c.Outputs = append(c.Outputs, ro)
 	return nil
 }
@@ -1147,9 +1315,11 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	if err := c.toml.UnmarshalTable(table, input); err != nil {
 		return err
 	}
+	c.checkDeprecations("inputs."+name, table, input)
 
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
+	c.sources[rp] = pluginSource("inputs", name, table)
 	c.Inputs = append(c.Inputs, rp)
 	return nil
 }
@@ -1217,7 +1387,7 @@ func (c *Config) buildProcessor(name string, tbl *ast.Table) (*models.ProcessorC
 }
 
 // buildFilter builds a Filter
-// (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop) to
+// (tagpass/tagdrop/namepass/namedrop/fieldpass/fielddrop/metricpass) to
 // be inserted into the models.OutputConfig/models.InputConfig
 // to be used for glob filtering on tags and measurements
 func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
@@ -1238,12 +1408,14 @@ func (c *Config) buildFilter(tbl *ast.Table) (models.Filter, error) {
 	c.getFieldStringSlice(tbl, "tagexclude", &f.TagExclude)
 	c.getFieldStringSlice(tbl, "taginclude", &f.TagInclude)
 
+	c.getFieldString(tbl, "metricpass", &f.MetricPass)
+
 	if c.hasErrs() {
 		return f, c.firstErr()
 	}
 
 	if err := f.Compile(); err != nil {
-		return f, err
+		return f, fmt.Errorf("line %d: %w", tbl.Line, err)
 	}
 
 	return f, nil
@@ -1372,6 +1544,20 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 
 	c.getFieldString(tbl, "value_field_name", &pc.ValueFieldName)
 
+	//for schema registry parsers
+	c.getFieldString(tbl, "avro_schema_registry", &pc.AvroSchemaRegistry)
+	c.getFieldString(tbl, "avro_measurement_field", &pc.AvroMeasurementField)
+	c.getFieldStringSlice(tbl, "avro_tags", &pc.AvroTags)
+	c.getFieldString(tbl, "avro_timestamp", &pc.AvroTimestamp)
+	c.getFieldString(tbl, "avro_timestamp_format", &pc.AvroTimestampFormat)
+	c.getFieldString(tbl, "avro_field_separator", &pc.AvroFieldSeparator)
+	c.getFieldString(tbl, "protobuf_schema_registry", &pc.ProtobufSchemaRegistry)
+	c.getFieldString(tbl, "protobuf_measurement_field", &pc.ProtobufMeasurementField)
+	c.getFieldStringSlice(tbl, "protobuf_tags", &pc.ProtobufTags)
+	c.getFieldString(tbl, "protobuf_timestamp", &pc.ProtobufTimestamp)
+	c.getFieldString(tbl, "protobuf_timestamp_format", &pc.ProtobufTimestampFormat)
+	c.getFieldString(tbl, "protobuf_field_separator", &pc.ProtobufFieldSeparator)
+
 	//for XPath parser family
 	if choice.Contains(pc.DataFormat, []string{"xml", "xpath_json", "xpath_msgpack", "xpath_protobuf"}) {
 		c.getFieldString(tbl, "xpath_protobuf_file", &pc.XPathProtobufFile)
@@ -1477,11 +1663,11 @@ func (c *Config) getParserConfig(name string, tbl *ast.Table) (*parsers.Config,
 	return pc, nil
 }
 
-// buildSerializer grabs the necessary entries from the ast.Table for creating
-// a serializers.Serializer object, and creates it, which can then be added onto
-// an Output object.
-func (c *Config) buildSerializer(tbl *ast.Table) (serializers.Serializer, error) {
-	sc := &serializers.Config{TimestampUnits: 1 * time.Second}
+// buildSerializerConfig grabs the necessary entries from the ast.Table for
+// creating a serializers.Serializer object, which can then be added onto an
+// Output object.
+func (c *Config) buildSerializerConfig(tbl *ast.Table) (*serializers.Config, error) {
+	sc := &serializers.Config{TimestampUnits: 1 * time.Second, CSVHeader: true}
 
 	c.getFieldString(tbl, "data_format", &sc.DataFormat)
 
@@ -1492,8 +1678,16 @@ func (c *Config) buildSerializer(tbl *ast.Table) (serializers.Serializer, error)
 	c.getFieldString(tbl, "prefix", &sc.Prefix)
 	c.getFieldString(tbl, "template", &sc.Template)
 	c.getFieldStringSlice(tbl, "templates", &sc.Templates)
+	c.getFieldString(tbl, "batch_template", &sc.BatchTemplate)
+	c.getFieldString(tbl, "avro_schema_registry", &sc.AvroSchemaRegistry)
+	c.getFieldString(tbl, "avro_namespace", &sc.AvroNamespace)
+	c.getFieldStringMap(tbl, "avro_schemas", &sc.AvroSchemas)
 	c.getFieldString(tbl, "carbon2_format", &sc.Carbon2Format)
 	c.getFieldString(tbl, "carbon2_sanitize_replace_char", &sc.Carbon2SanitizeReplaceChar)
+	c.getFieldString(tbl, "csv_format", &sc.CSVFormat)
+	c.getFieldStringSlice(tbl, "csv_columns", &sc.CSVColumns)
+	c.getFieldBool(tbl, "csv_header", &sc.CSVHeader)
+	c.getFieldString(tbl, "csv_timestamp_format", &sc.CSVTimestampFormat)
 	c.getFieldInt(tbl, "influx_max_line_bytes", &sc.InfluxMaxLineBytes)
 
 	c.getFieldBool(tbl, "influx_sort_fields", &sc.InfluxSortFields)
@@ -1519,7 +1713,7 @@ func (c *Config) buildSerializer(tbl *ast.Table) (serializers.Serializer, error)
 		return nil, c.firstErr()
 	}
 
-	return serializers.NewSerializer(sc)
+	return sc, nil
 }
 
 // buildOutput parses output specific items from the ast.Table,
@@ -1534,6 +1728,10 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 	oc := &models.OutputConfig{
 		Name:   name,
 		Filter: filter,
+
+		BufferStrategy: c.Agent.BufferStrategy,
+		BufferMaxSize:  int64(c.Agent.BufferMaxSize),
+		BufferMaxAge:   time.Duration(c.Agent.BufferMaxAge),
 	}
 
 	// TODO: support FieldPass/FieldDrop on outputs
@@ -1543,23 +1741,50 @@ func (c *Config) buildOutput(name string, tbl *ast.Table) (*models.OutputConfig,
 
 	c.getFieldInt(tbl, "metric_buffer_limit", &oc.MetricBufferLimit)
 	c.getFieldInt(tbl, "metric_batch_size", &oc.MetricBatchSize)
+	c.getFieldSize(tbl, "metric_batch_bytes", &oc.MetricBatchBytes)
 	c.getFieldString(tbl, "alias", &oc.Alias)
 	c.getFieldString(tbl, "name_override", &oc.NameOverride)
 	c.getFieldString(tbl, "name_suffix", &oc.NameSuffix)
 	c.getFieldString(tbl, "name_prefix", &oc.NamePrefix)
 
+	c.getFieldString(tbl, "buffer_strategy", &oc.BufferStrategy)
+	c.getFieldString(tbl, "buffer_directory", &oc.BufferDirectory)
+	c.getFieldSize(tbl, "buffer_max_size", &oc.BufferMaxSize)
+	c.getFieldDuration(tbl, "buffer_max_age", &oc.BufferMaxAge)
+
 	if c.hasErrs() {
 		return nil, c.firstErr()
 	}
 
+	switch oc.BufferStrategy {
+	case "", models.BufferStrategyMemory:
+	case models.BufferStrategyDisk:
+		// Without a directory of its own, each output uses a subdirectory
+		// of the agent's buffer directory.
+		if oc.BufferDirectory == "" {
+			if c.Agent.BufferDirectory == "" {
+				return nil, fmt.Errorf("buffer_directory must be set for the %q buffer strategy", oc.BufferStrategy)
+			}
+			dirname := name
+			if oc.Alias != "" {
+				dirname += "-" + oc.Alias
+			}
+			oc.BufferDirectory = filepath.Join(c.Agent.BufferDirectory, dirname)
+		}
+	default:
+		return nil, fmt.Errorf("invalid buffer_strategy %q", oc.BufferStrategy)
+	}
+
 	return oc, nil
 }
 
 func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 	switch key {
-	case "alias", "carbon2_format", "carbon2_sanitize_replace_char", "collectd_auth_file",
+	case "alias", "avro_field_separator", "avro_measurement_field", "avro_namespace", "avro_schema_registry",
+		"avro_schemas", "avro_tags", "avro_timestamp", "avro_timestamp_format", "batch_template", "buffer_directory", "buffer_max_age", "buffer_max_size", "buffer_strategy", "carbon2_format", "carbon2_sanitize_replace_char", "collectd_auth_file",
 		"collectd_parse_multivalue", "collectd_security_level", "collectd_typesdb", "collection_jitter",
-		"csv_column_names", "csv_column_types", "csv_comment", "csv_delimiter", "csv_header_row_count",
+		"csv_column_names", "csv_column_types", "csv_columns", "csv_comment", "csv_delimiter", "csv_format",
+		"csv_header", "csv_header_row_count",
 		"csv_measurement_column", "csv_skip_columns", "csv_skip_rows", "csv_tag_columns",
 		"csv_timestamp_column", "csv_timestamp_format", "csv_timezone", "csv_trim_space", "csv_skip_values",
 		"data_format", "data_type", "delay", "drop", "drop_original", "dropwizard_metric_registry_path",
@@ -1570,10 +1795,11 @@ func (c *Config) missingTomlField(_ reflect.Type, key string) error {
 		"grok_timezone", "grok_unique_timestamp", "influx_max_line_bytes", "influx_sort_fields",
 		"influx_uint_support", "interval", "json_name_key", "json_query", "json_strict",
 		"json_string_fields", "json_time_format", "json_time_key", "json_timestamp_units", "json_timezone", "json_v2",
-		"metric_batch_size", "metric_buffer_limit", "name_override", "name_prefix",
+		"metric_batch_bytes", "metric_batch_size", "metric_buffer_limit", "metricpass", "name_override", "name_prefix",
 		"name_suffix", "namedrop", "namepass", "order", "pass", "period", "precision",
 		"prefix", "prometheus_export_timestamp", "prometheus_sort_metrics", "prometheus_string_as_label",
-		"separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
+		"protobuf_field_separator", "protobuf_measurement_field", "protobuf_schema_registry", "protobuf_tags",
+		"protobuf_timestamp", "protobuf_timestamp_format", "separator", "splunkmetric_hec_routing", "splunkmetric_multimetric", "tag_keys",
 		"tagdrop", "tagexclude", "taginclude", "tagpass", "tags", "template", "templates",
 		"value_field_name", "wavefront_source_override", "wavefront_use_strict",
 		"xml", "xpath", "xpath_json", "xpath_msgpack", "xpath_protobuf", "xpath_print_document",
@@ -1602,7 +1828,7 @@ func (c *Config) getFieldDuration(tbl *ast.Table, fieldName string, target inter
 			if str, ok := kv.Value.(*ast.String); ok {
 				d, err := time.ParseDuration(str.Value)
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("error parsing duration: %w", err))
+					c.addError(kv, fmt.Errorf("error parsing duration: %w", err))
 					return
 				}
 				targetVal := reflect.ValueOf(target).Elem()
@@ -1620,17 +1846,17 @@ func (c *Config) getFieldBool(tbl *ast.Table, fieldName string, target *bool) {
 			case *ast.Boolean:
 				*target, err = t.Boolean()
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
+					c.addError(kv, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
 					return
 				}
 			case *ast.String:
 				*target, err = strconv.ParseBool(t.Value)
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
+					c.addError(kv, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value))
 					return
 				}
 			default:
-				c.addError(tbl, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value.Source()))
+				c.addError(kv, fmt.Errorf("unknown boolean value type %q, expecting boolean", kv.Value.Source()))
 				return
 			}
 		}
@@ -1643,7 +1869,7 @@ func (c *Config) getFieldInt(tbl *ast.Table, fieldName string, target *int) {
 			if iAst, ok := kv.Value.(*ast.Integer); ok {
 				i, err := iAst.Int()
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
+					c.addError(kv, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
 					return
 				}
 				*target = int(i)
@@ -1652,13 +1878,26 @@ func (c *Config) getFieldInt(tbl *ast.Table, fieldName string, target *int) {
 	}
 }
 
+func (c *Config) getFieldSize(tbl *ast.Table, fieldName string, target *int64) {
+	if node, ok := tbl.Fields[fieldName]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			var size Size
+			if err := size.UnmarshalTOML([]byte(kv.Value.Source())); err != nil {
+				c.addError(kv, fmt.Errorf("error parsing size: %w", err))
+				return
+			}
+			*target = int64(size)
+		}
+	}
+}
+
 func (c *Config) getFieldInt64(tbl *ast.Table, fieldName string, target *int64) {
 	if node, ok := tbl.Fields[fieldName]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if iAst, ok := kv.Value.(*ast.Integer); ok {
 				i, err := iAst.Int()
 				if err != nil {
-					c.addError(tbl, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
+					c.addError(kv, fmt.Errorf("unexpected int type %q, expecting int", iAst.Value))
 					return
 				}
 				*target = i
@@ -1677,7 +1916,7 @@ func (c *Config) getFieldStringSlice(tbl *ast.Table, fieldName string, target *[
 					}
 				}
 			} else {
-				c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting string array/slice format", fieldName))
+				c.addError(kv, fmt.Errorf("found unexpected format while parsing %q, expecting string array/slice format", fieldName))
 				return
 			}
 		}
@@ -1697,7 +1936,7 @@ func (c *Config) getFieldTagFilter(tbl *ast.Table, fieldName string, target *[]m
 							}
 						}
 					} else {
-						c.addError(tbl, fmt.Errorf("found unexpected format while parsing %q, expecting string array/slice format on each entry", fieldName))
+						c.addError(kv, fmt.Errorf("found unexpected format while parsing %q, expecting string array/slice format on each entry", fieldName))
 						return
 					}
 					*target = append(*target, tagfilter)
@@ -1741,8 +1980,8 @@ func (c *Config) firstErr() error {
 	return c.errs[0]
 }
 
-func (c *Config) addError(tbl *ast.Table, err error) {
-	c.errs = append(c.errs, fmt.Errorf("line %d:%d: %w", tbl.Line, tbl.Position, err))
+func (c *Config) addError(kv *ast.KeyValue, err error) {
+	c.errs = append(c.errs, fmt.Errorf("line %d: %w", kv.Line, err))
 }
 
 // unwrappable lets you retrieve the original telegraf.Processor from the
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
@@ -16,6 +17,8 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -288,6 +291,139 @@ func TestConfig_AzureMonitorNamespacePrefix(t *testing.T) {
 	}
 }
 
+func TestConfig_BufferStrategy(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/disk_buffer.toml"))
+	require.Len(t, c.Outputs, 3)
+
+	outputs := make(map[string]*models.OutputConfig)
+	for _, o := range c.Outputs {
+		outputs[o.Config.Name+o.Config.Alias] = o.Config
+	}
+
+	require.Equal(t, "disk", outputs["http"].BufferStrategy)
+	require.Equal(t, filepath.Join("/var/lib/telegraf/buffer", "http"), outputs["http"].BufferDirectory)
+	require.Equal(t, 24*time.Hour, outputs["http"].BufferMaxAge)
+	require.Equal(t, int64(0), outputs["http"].BufferMaxSize)
+
+	require.Equal(t, "disk", outputs["httpbackup"].BufferStrategy)
+	require.Equal(t, "/tmp/backup", outputs["httpbackup"].BufferDirectory)
+	require.Equal(t, int64(100*1000*1000), outputs["httpbackup"].BufferMaxSize)
+
+	require.Equal(t, "memory", outputs["azure_monitor"].BufferStrategy)
+	require.Equal(t, "", outputs["azure_monitor"].BufferDirectory)
+}
+
+func TestConfig_BufferStrategyMissingDirectory(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080"
+  buffer_strategy = "disk"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "buffer_directory must be set")
+}
+
+func TestConfig_MetricBatchBytes(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[outputs.http]]
+  url = "http://localhost:8080"
+  metric_batch_bytes = "1MB"
+
+[[outputs.azure_monitor]]
+`)))
+	require.Len(t, c.Outputs, 2)
+
+	// The order of the outputs is not kept
+	outputs := make(map[string]*models.RunningOutput)
+	for _, ro := range c.Outputs {
+		outputs[ro.Config.Name] = ro
+	}
+	require.Equal(t, int64(1000*1000), outputs["http"].Config.MetricBatchBytes)
+	require.NotNil(t, outputs["http"].Config.SizeSerializer)
+	require.Equal(t, int64(0), outputs["azure_monitor"].Config.MetricBatchBytes)
+	require.Nil(t, outputs["azure_monitor"].Config.SizeSerializer)
+}
+
+func TestConfig_MetricBatchBytesSizeSerializer(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[outputs.file]]
+  metric_batch_bytes = "1MB"
+  data_format = "csv"
+`)))
+	require.Len(t, c.Outputs, 1)
+	ro := c.Outputs[0]
+	output := ro.Output.(*MockupSerializerOutput)
+	require.NotNil(t, ro.Config.SizeSerializer)
+
+	// Estimating the size does not write the header of the output's
+	// serializer
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 42.0}, time.Unix(0, 0))
+	estimated, err := ro.Config.SizeSerializer.Serialize(m)
+	require.NoError(t, err)
+	written, err := output.serializer.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, string(estimated), string(written))
+}
+
+func TestConfig_MetricPass(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  metricpass = 'fields.usage_idle > 95.0 && tags.host.startsWith("ci-")'
+`)))
+	require.Len(t, c.Inputs, 1)
+	require.Equal(t, `fields.usage_idle > 95.0 && tags.host.startsWith("ci-")`, c.Inputs[0].Config.Filter.MetricPass)
+	require.True(t, c.Inputs[0].Config.Filter.IsActive())
+}
+
+func TestConfig_MetricPassInvalid(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  metricpass = 'fields.usage_idle >'
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "memcached")
+	require.Contains(t, err.Error(), "error compiling 'metricpass'")
+}
+
+func TestConfig_Deprecations(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/deprecated.toml"))
+	require.Equal(t, []string{
+		`./testdata/deprecated.toml: line 3: option "utc" of agent is deprecated since version 1.0.0: has no effect`,
+		`./testdata/deprecated.toml: line 7: option "ssl_ca" of outputs.http is deprecated since version 1.7.0: use 'tls_ca' instead`,
+	}, c.Deprecations)
+}
+
+func TestConfig_ErrorLineNumbers(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/invalid_duration.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "./testdata/invalid_duration.toml: error parsing memcached, line 4: error parsing duration")
+
+	err = c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["cpu["]
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "line 2: error compiling 'namepass'")
+}
+
+func TestConfig_ErrorsDoNotLeakIntoNextFile(t *testing.T) {
+	c := NewConfig()
+	require.Error(t, c.LoadConfig("./testdata/invalid_field.toml"))
+	require.Error(t, c.LoadConfig("./testdata/invalid_duration.toml"))
+	require.NoError(t, c.LoadConfig("./testdata/single_plugin.toml"))
+}
+
 func TestConfig_URLRetries3Fails(t *testing.T) {
 	httpLoadConfigRetryInterval = 0 * time.Second
 	responseCounter := 0
@@ -391,6 +527,20 @@ func (m *MockupOuputPlugin) Description() string                   { return "Moc
 func (m *MockupOuputPlugin) SampleConfig() string                  { return "Mockup test output plugin" }
 func (m *MockupOuputPlugin) Write(metrics []telegraf.Metric) error { return nil }
 
+// MockupSerializerOutput is a mockup output plugin using a serializer.
+type MockupSerializerOutput struct {
+	serializer serializers.Serializer
+}
+
+func (m *MockupSerializerOutput) SetSerializer(serializer serializers.Serializer) {
+	m.serializer = serializer
+}
+func (m *MockupSerializerOutput) Connect() error                        { return nil }
+func (m *MockupSerializerOutput) Close() error                          { return nil }
+func (m *MockupSerializerOutput) Description() string                   { return "Mockup test output plugin" }
+func (m *MockupSerializerOutput) SampleConfig() string                  { return "Mockup test output plugin" }
+func (m *MockupSerializerOutput) Write(metrics []telegraf.Metric) error { return nil }
+
 // Register the mockup plugin on loading
 func init() {
 	// Register the mockup input plugin for the required names
@@ -402,4 +552,5 @@ func init() {
 	// Register the mockup output plugin for the required names
 	outputs.Add("azure_monitor", func() telegraf.Output { return &MockupOuputPlugin{NamespacePrefix: "Telegraf/"} })
 	outputs.Add("http", func() telegraf.Output { return &MockupOuputPlugin{} })
+	outputs.Add("file", func() telegraf.Output { return &MockupSerializerOutput{} })
 }
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// checkDeprecations records the options set in the table that are marked as
+// deprecated on the plugin.  Options are marked with a struct tag giving the
+// version they were deprecated in and a notice, separated by a semicolon:
+//
+//	EnableSSL bool `toml:"enable_ssl" deprecated:"1.7.0;use 'enable_tls' instead"`
+func (c *Config) checkDeprecations(name string, tbl *ast.Table, plugin interface{}) {
+	if p, ok := plugin.(unwrappable); ok {
+		plugin = p.Unwrap()
+	}
+
+	v := reflect.ValueOf(plugin)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		tag, ok := c.deprecatedField(v.Type(), key)
+		if !ok {
+			continue
+		}
+
+		since, notice := tag, ""
+		if i := strings.Index(tag, ";"); i >= 0 {
+			since, notice = tag[:i], tag[i+1:]
+		}
+		msg := fmt.Sprintf("option %q of %s is deprecated since version %s", key, name, since)
+		if notice != "" {
+			msg += ": " + notice
+		}
+		if kv, ok := tbl.Fields[key].(*ast.KeyValue); ok {
+			msg = fmt.Sprintf("line %d: %s", kv.Line, msg)
+		}
+		c.Deprecations = append(c.Deprecations, msg)
+	}
+}
+
+// deprecatedField returns the deprecation tag of the struct field the key is
+// decoded into, including fields of embedded structs.
+func (c *Config) deprecatedField(typ reflect.Type, key string) (string, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			if tag, ok := c.deprecatedField(field.Type, key); ok {
+				return tag, true
+			}
+			continue
+		}
+
+		name := field.Tag.Get("toml")
+		if name == "" {
+			if c.toml.NormFieldName(typ, key) != c.toml.NormFieldName(typ, field.Name) {
+				continue
+			}
+		} else if name != key {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("deprecated")
+		return tag, ok
+	}
+	return "", false
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/influxdata/toml/ast"
+)
+
+// AgentFingerprint returns a hash of the agent settings and global tags.
+func (c *Config) AgentFingerprint() string {
+	sum := sha256.Sum256([]byte(c.agentSource))
+	return hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns a hash of the configuration of a running plugin, such
+// as a *models.RunningInput, including the secrets it references.  Plugins
+// with the same fingerprint have the same configuration.  The secret stores
+// must be initialized.
+func (c *Config) Fingerprint(plugin interface{}) (string, error) {
+	source, ok := c.sources[plugin]
+	if !ok {
+		return "", fmt.Errorf("plugin %T is not part of the config", plugin)
+	}
+
+	h := sha256.New()
+	io.WriteString(h, source)
+	for _, match := range secretRefRe.FindAllStringSubmatch(source, -1) {
+		store, ok := c.SecretStores[match[1]]
+		if !ok {
+			return "", fmt.Errorf("unknown secretstore %q in %s", match[1], match[0])
+		}
+		secret, err := store.Get(match[2])
+		if err != nil {
+			return "", fmt.Errorf("resolving %s failed: %v", match[0], err)
+		}
+		io.WriteString(h, "\x00"+secret)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// pluginSource returns the canonical configuration of a plugin.
+func pluginSource(category, name string, tbl *ast.Table) string {
+	return category + "." + name + "\n" + tableSource(tbl)
+}
+
+// tableSource returns a canonical representation of the table, independent
+// of the order of the keys, comments and whitespace.
+func tableSource(tbl *ast.Table) string {
+	var b strings.Builder
+	writeTableSource(&b, tbl)
+	return b.String()
+}
+
+func writeTableSource(b *strings.Builder, tbl *ast.Table) {
+	keys := make([]string, 0, len(tbl.Fields))
+	for key := range tbl.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch v := tbl.Fields[key].(type) {
+		case *ast.KeyValue:
+			fmt.Fprintf(b, "%q=", key)
+			writeValueSource(b, v.Value)
+			b.WriteString("\n")
+		case *ast.Table:
+			fmt.Fprintf(b, "[%q]\n", key)
+			writeTableSource(b, v)
+		case []*ast.Table:
+			for _, t := range v {
+				fmt.Fprintf(b, "[[%q]]\n", key)
+				writeTableSource(b, t)
+			}
+		}
+	}
+}
+
+func writeValueSource(b *strings.Builder, value ast.Value) {
+	switch v := value.(type) {
+	case *ast.String:
+		fmt.Fprintf(b, "%q", v.Value)
+	case *ast.Array:
+		b.WriteString("[")
+		for i, elem := range v.Value {
+			if i > 0 {
+				b.WriteString(",")
+			}
+			writeValueSource(b, elem)
+		}
+		b.WriteString("]")
+	case *ast.Table:
+		b.WriteString("{\n")
+		writeTableSource(b, v)
+		b.WriteString("}")
+	default:
+		b.WriteString(value.Source())
+	}
+}
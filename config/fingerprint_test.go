@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func loadFingerprints(t *testing.T, data string) (*Config, []string) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(data)))
+	require.NoError(t, c.InitSecretStores())
+
+	var fingerprints []string
+	for _, input := range c.Inputs {
+		fp, err := c.Fingerprint(input)
+		require.NoError(t, err)
+		fingerprints = append(fingerprints, fp)
+	}
+	for _, output := range c.Outputs {
+		fp, err := c.Fingerprint(output)
+		require.NoError(t, err)
+		fingerprints = append(fingerprints, fp)
+	}
+	return c, fingerprints
+}
+
+func TestConfig_Fingerprint(t *testing.T) {
+	_, a := loadFingerprints(t, `
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["metricname1"]
+
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["metricname1"]
+`)
+	require.Len(t, a, 2)
+	require.Equal(t, a[0], a[1], "identical configs must have the same fingerprint")
+
+	_, b := loadFingerprints(t, `
+[[inputs.procstat]]
+  servers = ["localhost"]
+  namepass = ["metricname1"]
+`)
+	require.NotEqual(t, a[0], b[0], "different plugins must have different fingerprints")
+
+	// Key order, comments and whitespace do not matter
+	_, b = loadFingerprints(t, `
+[[inputs.memcached]]
+  # comment
+  namepass = [ "metricname1" ]
+  servers = ["localhost"]
+`)
+	require.Equal(t, a[0], b[0])
+
+	_, b = loadFingerprints(t, `
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["metricname2"]
+`)
+	require.NotEqual(t, a[0], b[0])
+
+	_, b = loadFingerprints(t, `
+[[inputs.memcached]]
+  servers = ["localhost"]
+  namepass = ["metricname1"]
+  [inputs.memcached.tags]
+    foo = "bar"
+`)
+	require.NotEqual(t, a[0], b[0])
+}
+
+func TestConfig_FingerprintSecrets(t *testing.T) {
+	config := `
+[[secretstores.mock]]
+  id = "vault"
+  secrets = { token = "%s" }
+
+[[outputs.http]]
+  headers = { Authorization = "@{vault:token}" }
+`
+	_, a := loadFingerprints(t, fmt.Sprintf(config, "1234"))
+	_, b := loadFingerprints(t, fmt.Sprintf(config, "1234"))
+	require.Equal(t, a, b)
+
+	_, b = loadFingerprints(t, fmt.Sprintf(config, "5678"))
+	require.NotEqual(t, a, b, "changed secrets must change the fingerprint")
+}
+
+func TestConfig_AgentFingerprint(t *testing.T) {
+	a, _ := loadFingerprints(t, `
+[global_tags]
+  dc = "us-east-1"
+[agent]
+  interval = "10s"
+`)
+	b, _ := loadFingerprints(t, `
+[agent]
+  interval = "10s"
+[global_tags]
+  dc = "us-east-1"
+`)
+	require.Equal(t, a.AgentFingerprint(), b.AgentFingerprint())
+
+	b, _ = loadFingerprints(t, `
+[global_tags]
+  dc = "us-east-1"
+[agent]
+  interval = "20s"
+`)
+	require.NotEqual(t, a.AgentFingerprint(), b.AgentFingerprint())
+}
+
+func TestConfig_FingerprintUnknownPlugin(t *testing.T) {
+	c := NewConfig()
+	_, err := c.Fingerprint(&MockupInputPlugin{})
+	require.Error(t, err)
+}
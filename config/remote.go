@@ -0,0 +1,292 @@
+package config
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultRemoteTimeout is the default timeout of the requests fetching remote
+// configs.
+const DefaultRemoteTimeout = 30 * time.Second
+
+// RemoteOptions configures loading configs from http(s) URLs.
+type RemoteOptions struct {
+	// Timeout of each request fetching a config, its checksum or signature.
+	// DefaultRemoteTimeout is used if zero.
+	Timeout time.Duration
+
+	// CacheDir keeps the last known good copy of each remote config, used if
+	// the config cannot be fetched or verified.
+	CacheDir string
+
+	// Checksum requires the SHA256 checksum of the config at "<url>.sha256",
+	// in the format written by sha256sum.
+	Checksum bool
+
+	// PublicKey requires a base64 encoded ed25519 signature of the config at
+	// "<url>.sig" made with the private key belonging to the public key.
+	PublicKey ed25519.PublicKey
+}
+
+// LoadPublicKey reads a base64 encoded ed25519 public key from a file.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key %s failed: %v", path, err)
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key %s has invalid size %d", path, len(key))
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// fetchRemoteConfig fetches and verifies the config, falling back to the
+// cached copy if that fails.
+func (c *Config) fetchRemoteConfig(u *url.URL) ([]byte, error) {
+	data, err := fetchConfig(c.Remote.client(), u)
+	if err == nil {
+		err = c.Remote.verify(u, data)
+	}
+	if err == nil {
+		return data, nil
+	}
+	if c.Remote.CacheDir == "" {
+		return nil, err
+	}
+
+	cached, cacheErr := ioutil.ReadFile(c.Remote.cachePath(u.String()))
+	if cacheErr != nil {
+		return nil, err
+	}
+	log.Printf("W! Using cached copy of config %s: %v", u.Redacted(), err)
+	return cached, nil
+}
+
+// remoteLoaded records the successfully loaded remote config.
+func (c *Config) remoteLoaded(path string, data []byte) {
+	c.remoteConfigs[path] = data
+}
+
+// StoreRemoteConfigs stores the loaded remote configs as last known good
+// copies.  It is called once the config is running, so that copies failing
+// to start are not kept.
+func (c *Config) StoreRemoteConfigs() {
+	if c.Remote.CacheDir == "" {
+		return
+	}
+	for path, data := range c.remoteConfigs {
+		if err := writeFileAtomic(c.Remote.cachePath(path), data); err != nil {
+			log.Printf("W! Could not cache config: %v", err)
+		}
+	}
+}
+
+// verify checks the checksum and signature of the config if required.
+func (o *RemoteOptions) verify(u *url.URL, data []byte) error {
+	if o.Checksum {
+		body, err := fetchURL(o.client(), withSuffix(u, ".sha256"))
+		if err != nil {
+			return fmt.Errorf("fetching checksum failed: %v", err)
+		}
+		fields := strings.Fields(string(body))
+		if len(fields) == 0 {
+			return errors.New("checksum is empty")
+		}
+		if !strings.EqualFold(fields[0], checksum(data)) {
+			return errors.New("checksum mismatch")
+		}
+	}
+
+	if o.PublicKey != nil {
+		body, err := fetchURL(o.client(), withSuffix(u, ".sig"))
+		if err != nil {
+			return fmt.Errorf("fetching signature failed: %v", err)
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(body)))
+		if err != nil {
+			return fmt.Errorf("decoding signature failed: %v", err)
+		}
+		if !ed25519.Verify(o.PublicKey, data, sig) {
+			return errors.New("invalid signature")
+		}
+	}
+	return nil
+}
+
+// client returns the HTTP client fetching the configs.
+func (o *RemoteOptions) client() *http.Client {
+	timeout := o.Timeout
+	if timeout <= 0 {
+		timeout = DefaultRemoteTimeout
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+func (o *RemoteOptions) cachePath(path string) string {
+	return filepath.Join(o.CacheDir, checksum([]byte(path))+".conf")
+}
+
+// RemoteWatcher detects changes of the remote configs loaded by a Config by
+// polling them.  Configs that cannot be fetched or verified are considered
+// unchanged, so the running config is kept while the server is unreachable.
+//
+// A change is reported on each poll until a config with the new content is
+// committed, so that reloads failing to apply it are retried.
+type RemoteWatcher struct {
+	options RemoteOptions
+
+	mu   sync.Mutex
+	sums map[string]string
+}
+
+// NewRemoteWatcher returns a RemoteWatcher for the remote configs loaded by
+// the Config.
+func NewRemoteWatcher(c *Config) *RemoteWatcher {
+	w := &RemoteWatcher{options: c.Remote}
+	w.Commit(c)
+	return w
+}
+
+// Commit records the remote configs of the Config, once it is running, as
+// the ones to compare the polled configs with.
+func (w *RemoteWatcher) Commit(c *Config) {
+	sums := make(map[string]string, len(c.remoteConfigs))
+	for path, data := range c.remoteConfigs {
+		sums[path] = checksum(data)
+	}
+
+	w.mu.Lock()
+	w.sums = sums
+	w.mu.Unlock()
+}
+
+// Changed fetches the remote configs and reports whether any of them differs
+// from the committed ones.
+func (w *RemoteWatcher) Changed() bool {
+	w.mu.Lock()
+	sums := w.sums
+	w.mu.Unlock()
+
+	var changed bool
+	for path, sum := range sums {
+		u, err := url.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		data, err := fetchURL(w.options.client(), u)
+		if err == nil {
+			err = w.options.verify(u, data)
+		}
+		if err != nil {
+			log.Printf("W! Checking config %s failed: %v", u.Redacted(), err)
+			continue
+		}
+
+		if checksum(data) != sum {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Watch polls the remote configs every interval and calls changed on each
+// change until the context is done.
+func (w *RemoteWatcher) Watch(ctx context.Context, interval time.Duration, changed func()) {
+	poll(ctx, interval, w.Changed, func() {
+		log.Printf("I! Remote config changed")
+		changed()
+	})
+}
+
+// poll calls check every interval and changed if it reports a change, until
+// the context is done.
+func poll(ctx context.Context, interval time.Duration, check func() bool, changed func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if check() {
+				changed()
+			}
+		}
+	}
+}
+
+// fetchURL fetches the URL once, failing on any status other than 200.
+func fetchURL(client *http.Client, u *url.URL) ([]byte, error) {
+	req, err := newConfigRequest(u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// withSuffix returns a copy of the URL with the suffix appended to the path.
+func withSuffix(u *url.URL, suffix string) *url.URL {
+	v := *u
+	v.Path += suffix
+	v.RawPath = ""
+	return &v
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFileAtomic writes the file readable only by the owner, replacing it
+// atomically.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(dir, ".telegraf-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// remoteConfigServer serves a config along with its checksum and signature.
+type remoteConfigServer struct {
+	mu       sync.Mutex
+	config   []byte
+	checksum string
+	sig      []byte
+	down     bool
+}
+
+func (s *remoteConfigServer) set(config []byte, key ed25519.PrivateKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	s.checksum = checksum(config) + "  telegraf.conf\n"
+	s.sig = []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(key, config)))
+}
+
+func (s *remoteConfigServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.down {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	switch r.URL.Path {
+	case "/telegraf.conf":
+		w.Write(s.config)
+	case "/telegraf.conf.sha256":
+		w.Write([]byte(s.checksum))
+	case "/telegraf.conf.sig":
+		w.Write(s.sig)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+const remoteTestConfig = `
+[[inputs.memcached]]
+  servers = ["localhost"]
+`
+
+func TestConfig_RemoteVerification(t *testing.T) {
+	httpLoadConfigRetryInterval = 0
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	srv := &remoteConfigServer{}
+	srv.set([]byte(remoteTestConfig), private)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.Checksum = true
+	c.Remote.PublicKey = public
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+
+	// Tampered checksum
+	srv.mu.Lock()
+	srv.checksum = checksum([]byte("something else"))
+	srv.mu.Unlock()
+	c = NewConfig()
+	c.Remote.Checksum = true
+	err = c.LoadConfig(ts.URL + "/telegraf.conf")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "checksum mismatch")
+
+	// Signed with another key
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv.set([]byte(remoteTestConfig), other)
+	c = NewConfig()
+	c.Remote.PublicKey = public
+	err = c.LoadConfig(ts.URL + "/telegraf.conf")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid signature")
+}
+
+func TestConfig_RemoteCache(t *testing.T) {
+	httpLoadConfigRetryInterval = 0
+	dir, err := ioutil.TempDir("", "telegraf-remote")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cacheDir := filepath.Join(dir, "cache")
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv := &remoteConfigServer{}
+	srv.set([]byte(remoteTestConfig), private)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.CacheDir = cacheDir
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+
+	// The copy is only stored once the config is running
+	_, err = os.Stat(cacheDir)
+	require.True(t, os.IsNotExist(err))
+	c.StoreRemoteConfigs()
+
+	files, err := ioutil.ReadDir(cacheDir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	if os.PathSeparator == '/' {
+		require.Equal(t, os.FileMode(0600), files[0].Mode().Perm())
+	}
+
+	// The cached copy is used while the server is down
+	srv.mu.Lock()
+	srv.down = true
+	srv.mu.Unlock()
+	c = NewConfig()
+	c.Remote.CacheDir = cacheDir
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Len(t, c.Inputs, 1)
+
+	// Without cache loading fails
+	c = NewConfig()
+	require.Error(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+}
+
+func TestRemoteWatcher_Changed(t *testing.T) {
+	httpLoadConfigRetryInterval = 0
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv := &remoteConfigServer{}
+	srv.set([]byte(remoteTestConfig), private)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	c := NewConfig()
+	c.Remote.PublicKey = public
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+
+	w := NewRemoteWatcher(c)
+	require.False(t, w.Changed())
+
+	changed := []byte(remoteTestConfig + "  methods = [\"stats\"]\n")
+	srv.set(changed, private)
+	require.True(t, w.Changed())
+
+	// The change is reported until a config with it is committed, so that
+	// failed reloads are retried
+	require.True(t, w.Changed())
+	c = NewConfig()
+	c.Remote.PublicKey = public
+	require.NoError(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	w.Commit(c)
+	require.False(t, w.Changed())
+
+	// Unverified changes are ignored
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	srv.set([]byte(remoteTestConfig), other)
+	require.False(t, w.Changed())
+
+	// An unreachable server is no change
+	srv.mu.Lock()
+	srv.down = true
+	srv.mu.Unlock()
+	require.False(t, w.Changed())
+}
+
+func TestConfig_RemoteTimeout(t *testing.T) {
+	done := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer ts.Close()
+	defer close(done)
+
+	c := NewConfig()
+	c.Remote.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	require.Error(t, c.LoadConfig(ts.URL+"/telegraf.conf"))
+	require.Less(t, int64(time.Since(start)), int64(5*time.Second))
+
+	w := NewRemoteWatcher(c)
+	w.sums[ts.URL+"/telegraf.conf"] = ""
+	require.False(t, w.Changed())
+}
+
+func TestLoadPublicKey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-remote")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	public, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "config.pub")
+	require.NoError(t, ioutil.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(public)+"\n"), 0644))
+
+	key, err := LoadPublicKey(path)
+	require.NoError(t, err)
+	require.Equal(t, public, key)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("c2hvcnQ="), 0644))
+	_, err = LoadPublicKey(path)
+	require.Error(t, err)
+}
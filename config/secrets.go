@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+// secretRefRe is a regex to find secret references like @{store:key}
+var secretRefRe = regexp.MustCompile(`@\{(\w+):([^{}]+)\}`)
+
+// InitSecretStores initializes all configured secret stores.
+func (c *Config) InitSecretStores() error {
+	for id, store := range c.SecretStores {
+		if err := store.Init(); err != nil {
+			return fmt.Errorf("could not initialize secretstore %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// ResolveSecrets replaces all secret references in the exported string
+// fields of the plugin, including nested structs, slices and maps, by the
+// secret from the referenced store.  The secret stores must be initialized.
+func (c *Config) ResolveSecrets(plugin interface{}) error {
+	if p, ok := plugin.(unwrappable); ok {
+		plugin = p.Unwrap()
+	}
+
+	r := &secretResolver{
+		config:  c,
+		visited: make(map[uintptr]bool),
+	}
+	return r.resolve(reflect.ValueOf(plugin))
+}
+
+type secretResolver struct {
+	config  *Config
+	visited map[uintptr]bool
+}
+
+func (r *secretResolver) resolve(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || r.visited[v.Pointer()] {
+			return nil
+		}
+		r.visited[v.Pointer()] = true
+		return r.resolve(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Field(i)
+			if !field.CanSet() {
+				continue
+			}
+			if err := r.resolve(field); err != nil {
+				return fmt.Errorf("%s: %w", v.Type().Field(i).Name, err)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolve(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			// Map values are not addressable, resolve a copy and store it back
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := r.resolve(elem); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if !v.CanSet() || !secretRefRe.MatchString(v.String()) {
+			return nil
+		}
+		s, err := r.replace(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(s)
+	}
+	return nil
+}
+
+// replace substitutes the secret references in s.  Errors must never contain
+// the secret itself.
+func (r *secretResolver) replace(s string) (string, error) {
+	var err error
+	result := secretRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := secretRefRe.FindStringSubmatch(ref)
+		id, key := match[1], match[2]
+
+		store, ok := r.config.SecretStores[id]
+		if !ok {
+			err = fmt.Errorf("unknown secretstore %q in %s", id, ref)
+			return ref
+		}
+		var secret string
+		secret, err = store.Get(key)
+		if err != nil {
+			err = fmt.Errorf("resolving %s failed: %v", ref, err)
+			return ref
+		}
+		return secret
+	})
+	return result, err
+}
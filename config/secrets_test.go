@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.SecretStores, 1)
+	require.Len(t, c.Outputs, 1)
+	require.NoError(t, c.InitSecretStores())
+
+	output := c.Outputs[0].Output.(*MockupOuputPlugin)
+	require.NoError(t, c.ResolveSecrets(output))
+	require.Equal(t, "http://admin@localhost:8080", output.URL)
+	require.Equal(t, map[string]string{"Authorization": "Bearer 1234"}, output.Headers)
+	require.Equal(t, []string{"read", "write"}, output.Scopes)
+	require.Equal(t, "/etc/telegraf/key.pem", output.TLSKey)
+}
+
+func TestConfig_ResolveSecretsErrors(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.NoError(t, c.InitSecretStores())
+
+	output := &MockupOuputPlugin{URL: "@{unknown:user}"}
+	err := c.ResolveSecrets(output)
+	require.EqualError(t, err, `URL: unknown secretstore "unknown" in @{unknown:user}`)
+
+	output = &MockupOuputPlugin{Headers: map[string]string{"Authorization": "@{vault:missing}"}}
+	err = c.ResolveSecrets(output)
+	require.EqualError(t, err, `Headers: resolving @{vault:missing} failed: secret "missing" not found`)
+}
+
+func TestConfig_SecretStoreDuplicateID(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/secrets_duplicate_id.toml")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `duplicate secretstore id "vault"`)
+}
+
+/*** Mockup SECRETSTORE plugin for testing ***/
+type MockupSecretStore struct {
+	Secrets map[string]string `toml:"secrets"`
+}
+
+func (s *MockupSecretStore) Init() error          { return nil }
+func (s *MockupSecretStore) Description() string  { return "Mockup test secret store plugin" }
+func (s *MockupSecretStore) SampleConfig() string { return "Mockup test secret store plugin" }
+func (s *MockupSecretStore) Get(key string) (string, error) {
+	value, ok := s.Secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	return value, nil
+}
+
+func init() {
+	secretstores.Add("mock", func() telegraf.SecretStore { return &MockupSecretStore{} })
+}
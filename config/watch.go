@@ -0,0 +1,83 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Watcher detects changes of local config files and of the config files in
+// config directories by polling their size and modification time.  Remote
+// configs given as URL are not watched.
+type Watcher struct {
+	files       []string
+	directories []string
+	state       string
+}
+
+// NewWatcher returns a Watcher for the given files and directories.  If both
+// are empty the default config file is watched.
+func NewWatcher(files, directories []string) *Watcher {
+	w := &Watcher{
+		files:       files,
+		directories: directories,
+	}
+	if len(files) == 0 && len(directories) == 0 {
+		if path, err := getDefaultConfigPath(); err == nil {
+			w.files = []string{path}
+		}
+	}
+	w.state = w.currentState()
+	return w
+}
+
+// Changed reports whether the config files changed since the previous call
+// or since the Watcher was created.
+func (w *Watcher) Changed() bool {
+	state := w.currentState()
+	if state == w.state {
+		return false
+	}
+	w.state = state
+	return true
+}
+
+// Watch polls the config files every interval and calls changed on each
+// change until the context is done.
+func (w *Watcher) Watch(ctx context.Context, interval time.Duration, changed func()) {
+	poll(ctx, interval, w.Changed, func() {
+		log.Printf("I! Config files changed")
+		changed()
+	})
+}
+
+func (w *Watcher) currentState() string {
+	files := make([]string, 0, len(w.files))
+	for _, path := range w.files {
+		if !isURL(path) {
+			files = append(files, path)
+		}
+	}
+	for _, dir := range w.directories {
+		dirFiles, err := DirectoryFiles(dir)
+		if err != nil {
+			files = append(files, dir)
+			continue
+		}
+		files = append(files, dirFiles...)
+	}
+
+	var b strings.Builder
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s missing\n", path)
+			continue
+		}
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatcher_Changed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "telegraf-watch")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "telegraf.conf")
+	confDir := filepath.Join(dir, "telegraf.d")
+	require.NoError(t, os.Mkdir(confDir, 0755))
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n"), 0644))
+
+	w := NewWatcher([]string{file, "http://example.org/telegraf.conf"}, []string{confDir})
+	require.False(t, w.Changed())
+
+	// Modified file
+	require.NoError(t, ioutil.WriteFile(file, []byte("[agent]\n  debug = true\n"), 0644))
+	require.True(t, w.Changed())
+	require.False(t, w.Changed())
+
+	// New file in a directory
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "cpu.conf"), []byte("[[inputs.cpu]]\n"), 0644))
+	require.True(t, w.Changed())
+
+	// Files not ending with .conf are ignored
+	require.NoError(t, ioutil.WriteFile(filepath.Join(confDir, "README"), []byte("notes\n"), 0644))
+	require.False(t, w.Changed())
+
+	// Touched file
+	mtime := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(confDir, "cpu.conf"), mtime, mtime))
+	require.True(t, w.Changed())
+
+	// Removed file
+	require.NoError(t, os.Remove(file))
+	require.True(t, w.Changed())
+	require.False(t, w.Changed())
+}
@@ -5,15 +5,27 @@ import (
 	"bytes"
 	"compress/gzip"
 	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
+// ErrDecompressedTooLarge is returned when a decoded stream is larger than
+// allowed.
+var ErrDecompressedTooLarge = errors.New("decompressed content too large")
+
 // NewStreamContentDecoder returns a reader that will decode the stream
 // according to the encoding type.
 func NewStreamContentDecoder(encoding string, r io.Reader) (io.Reader, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipReader(r)
+	case "zstd":
+		return NewZstdReader(r)
 	case "identity", "":
 		return r, nil
 	default:
@@ -67,11 +79,168 @@ func (r *GzipReader) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// RequestContentEncoding returns the encoding to decode a request body with,
+// given its Content-Encoding header.  Bodies of other encodings are read as
+// is, as the listeners always did.
+func RequestContentEncoding(header string) string {
+	switch header {
+	case "gzip", "zstd", "snappy":
+		return header
+	}
+	return "identity"
+}
+
+// NewLimitedStreamContentDecoder returns a reader that will decode the stream
+// according to the encoding type, and fail with ErrDecompressedTooLarge once
+// more than maxSize bytes are decoded.  The reader must be closed to release
+// its resources.
+func NewLimitedStreamContentDecoder(encoding string, r io.Reader, maxSize int64) (io.ReadCloser, error) {
+	var rc io.ReadCloser
+	switch encoding {
+	case "gzip":
+		z, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		rc = z
+	case "zstd":
+		z, err := NewZstdReader(r)
+		if err != nil {
+			return nil, err
+		}
+		rc = z
+	case "snappy":
+		// The snappy block format cannot be decoded as a stream, but its
+		// header tells the decoded size.
+		data, err := readSnappyBlock(r, maxSize)
+		if err != nil {
+			return nil, err
+		}
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if int64(size) > maxSize {
+			return nil, ErrDecompressedTooLarge
+		}
+		decoded, err := snappy.Decode(nil, data)
+		if err != nil {
+			return nil, err
+		}
+		rc = ioutil.NopCloser(bytes.NewReader(decoded))
+	case "identity", "":
+		rc = ioutil.NopCloser(r)
+	default:
+		return nil, errors.New("invalid value for content_encoding")
+	}
+	return &limitedReader{ReadCloser: rc, remaining: maxSize}, nil
+}
+
+// readSnappyBlock reads a snappy block, up to the largest encoding of the
+// maximum decoded size.
+func readSnappyBlock(r io.Reader, maxSize int64) ([]byte, error) {
+	limit := int64(snappy.MaxEncodedLen(int(maxSize)))
+	if limit < 0 {
+		// Larger than the blocks supported by snappy
+		return ioutil.ReadAll(r)
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrDecompressedTooLarge
+	}
+	return data, nil
+}
+
+// limitedReader fails once more than the remaining bytes are read.
+type limitedReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *limitedReader) Read(b []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, ErrDecompressedTooLarge
+	}
+	if int64(len(b)) > r.remaining+1 {
+		b = b[:r.remaining+1]
+	}
+	n, err := r.ReadCloser.Read(b)
+	r.remaining -= int64(n)
+	if r.remaining < 0 {
+		return n + int(r.remaining), ErrDecompressedTooLarge
+	}
+	return n, err
+}
+
+// NewZstdReader returns a reader decoding a zstd stream, made of one or more
+// frames.  The reader must be closed to release its resources.
+func NewZstdReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// ValidateContentEncoding returns an error if the encoding is not supported
+// by CompressWithEncoding, so that outputs can check it once when starting.
+func ValidateContentEncoding(encoding string) error {
+	switch encoding {
+	case "gzip", "zstd", "snappy", "identity", "":
+		return nil
+	}
+	return fmt.Errorf("invalid value %q for content_encoding", encoding)
+}
+
+// CompressWithEncoding returns a reader of the data compressed according to
+// the encoding type.  The gzip and zstd encodings are streamed, while the
+// snappy block format requires the whole data.
+func CompressWithEncoding(encoding string, data io.Reader) (io.ReadCloser, error) {
+	switch encoding {
+	case "gzip":
+		return CompressWithGzip(data)
+	case "zstd":
+		pipeReader, pipeWriter := io.Pipe()
+		zstdWriter, err := zstd.NewWriter(pipeWriter)
+		if err != nil {
+			return nil, err
+		}
+		go func() {
+			_, err := io.Copy(zstdWriter, data)
+			if cerr := zstdWriter.Close(); err == nil {
+				err = cerr
+			}
+			// subsequent reads from the read half of the pipe will
+			// return no bytes and the error err, or EOF if err is nil.
+			pipeWriter.CloseWithError(err)
+		}()
+		return pipeReader, nil
+	case "snappy":
+		buf, err := ioutil.ReadAll(data)
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(bytes.NewReader(snappy.Encode(nil, buf))), nil
+	case "identity", "":
+		return ioutil.NopCloser(data), nil
+	default:
+		return nil, errors.New("invalid value for content_encoding")
+	}
+}
+
 // NewContentEncoder returns a ContentEncoder for the encoding type.
 func NewContentEncoder(encoding string) (ContentEncoder, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipEncoder()
+	case "zstd":
+		return NewZstdEncoder()
+	case "snappy":
+		return NewSnappyEncoder(), nil
 	case "identity", "":
 		return NewIdentityEncoder(), nil
 	default:
@@ -84,6 +253,10 @@ func NewContentDecoder(encoding string) (ContentDecoder, error) {
 	switch encoding {
 	case "gzip":
 		return NewGzipDecoder()
+	case "zstd":
+		return NewZstdDecoder()
+	case "snappy":
+		return NewSnappyDecoder(), nil
 	case "identity", "":
 		return NewIdentityDecoder(), nil
 	default:
@@ -125,6 +298,34 @@ func (e *GzipEncoder) Encode(data []byte) ([]byte, error) {
 	return e.buf.Bytes(), nil
 }
 
+// ZstdEncoder compresses the buffer using zstd at the default level.
+type ZstdEncoder struct {
+	encoder *zstd.Encoder
+}
+
+func NewZstdEncoder() (*ZstdEncoder, error) {
+	e, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ZstdEncoder{encoder: e}, nil
+}
+
+func (e *ZstdEncoder) Encode(data []byte) ([]byte, error) {
+	return e.encoder.EncodeAll(data, nil), nil
+}
+
+// SnappyEncoder compresses the buffer using the snappy block format.
+type SnappyEncoder struct{}
+
+func NewSnappyEncoder() *SnappyEncoder {
+	return &SnappyEncoder{}
+}
+
+func (*SnappyEncoder) Encode(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
 // IdentityEncoder is a null encoder that applies no transformation.
 type IdentityEncoder struct{}
 
@@ -169,6 +370,44 @@ func (d *GzipDecoder) Decode(data []byte) ([]byte, error) {
 	return d.buf.Bytes(), nil
 }
 
+// zstdDecoder is shared by the zstd decoders, as it is safe for concurrent
+// use and runs goroutines until closed.
+var (
+	zstdDecoder     *zstd.Decoder
+	zstdDecoderErr  error
+	zstdDecoderOnce sync.Once
+)
+
+// ZstdDecoder decompresses buffers with zstd compression.
+type ZstdDecoder struct {
+	decoder *zstd.Decoder
+}
+
+func NewZstdDecoder() (*ZstdDecoder, error) {
+	zstdDecoderOnce.Do(func() {
+		zstdDecoder, zstdDecoderErr = zstd.NewReader(nil)
+	})
+	if zstdDecoderErr != nil {
+		return nil, zstdDecoderErr
+	}
+	return &ZstdDecoder{decoder: zstdDecoder}, nil
+}
+
+func (d *ZstdDecoder) Decode(data []byte) ([]byte, error) {
+	return d.decoder.DecodeAll(data, nil)
+}
+
+// SnappyDecoder decompresses buffers in the snappy block format.
+type SnappyDecoder struct{}
+
+func NewSnappyDecoder() *SnappyDecoder {
+	return &SnappyDecoder{}
+}
+
+func (*SnappyDecoder) Decode(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
 // IdentityDecoder is a null decoder that returns the input.
 type IdentityDecoder struct{}
 
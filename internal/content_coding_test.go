@@ -92,3 +92,122 @@ func TestStreamGzipDecode(t *testing.T) {
 
 	require.Equal(t, []byte("howdy"), b[:n])
 }
+
+func TestZstdEncodeDecode(t *testing.T) {
+	enc, err := NewZstdEncoder()
+	require.NoError(t, err)
+	dec, err := NewZstdDecoder()
+	require.NoError(t, err)
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+}
+
+func TestStreamZstdDecode(t *testing.T) {
+	enc, err := NewZstdEncoder()
+	require.NoError(t, err)
+
+	// A stream of several frames
+	var w bytes.Buffer
+	for _, s := range []string{"howdy", "doody"} {
+		written, err := enc.Encode([]byte(s))
+		require.NoError(t, err)
+		w.Write(written)
+	}
+
+	dec, err := NewStreamContentDecoder("zstd", &w)
+	require.NoError(t, err)
+
+	data, err := ioutil.ReadAll(dec)
+	require.NoError(t, err)
+	require.Equal(t, []byte("howdydoody"), data)
+}
+
+func TestSnappyEncodeDecode(t *testing.T) {
+	enc, err := NewContentEncoder("snappy")
+	require.NoError(t, err)
+	dec, err := NewContentDecoder("snappy")
+	require.NoError(t, err)
+
+	payload, err := enc.Encode([]byte("howdy"))
+	require.NoError(t, err)
+
+	actual, err := dec.Decode(payload)
+	require.NoError(t, err)
+
+	require.Equal(t, "howdy", string(actual))
+}
+
+func TestCompressWithEncoding(t *testing.T) {
+	data := bytes.Repeat([]byte("howdy "), 1000)
+	for _, encoding := range []string{"gzip", "zstd", "snappy", "identity"} {
+		t.Run(encoding, func(t *testing.T) {
+			rc, err := CompressWithEncoding(encoding, bytes.NewReader(data))
+			require.NoError(t, err)
+			compressed, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			require.NoError(t, rc.Close())
+
+			dec, err := NewLimitedStreamContentDecoder(encoding, bytes.NewReader(compressed), int64(len(data)))
+			require.NoError(t, err)
+			actual, err := ioutil.ReadAll(dec)
+			require.NoError(t, err)
+			require.NoError(t, dec.Close())
+			require.Equal(t, data, actual)
+		})
+	}
+
+	_, err := CompressWithEncoding("deflate", bytes.NewReader(data))
+	require.Error(t, err)
+}
+
+func TestLimitedStreamContentDecoderTooLarge(t *testing.T) {
+	data := bytes.Repeat([]byte("howdy "), 1000)
+	for _, encoding := range []string{"gzip", "zstd", "snappy", "identity"} {
+		t.Run(encoding, func(t *testing.T) {
+			rc, err := CompressWithEncoding(encoding, bytes.NewReader(data))
+			require.NoError(t, err)
+			compressed, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+
+			dec, err := NewLimitedStreamContentDecoder(encoding, bytes.NewReader(compressed), int64(len(data)-1))
+			if err == nil {
+				var actual []byte
+				actual, err = ioutil.ReadAll(dec)
+				require.Len(t, actual, len(data)-1)
+			}
+			require.Equal(t, ErrDecompressedTooLarge, err)
+		})
+	}
+}
+
+func TestLimitedStreamContentDecoderInvalid(t *testing.T) {
+	_, err := NewLimitedStreamContentDecoder("deflate", bytes.NewReader(nil), 10)
+	require.Error(t, err)
+
+	_, err = NewLimitedStreamContentDecoder("gzip", bytes.NewReader([]byte("howdy")), 10)
+	require.Error(t, err)
+
+	_, err = NewLimitedStreamContentDecoder("snappy", bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}), 10)
+	require.Error(t, err)
+}
+
+func TestRequestContentEncoding(t *testing.T) {
+	require.Equal(t, "gzip", RequestContentEncoding("gzip"))
+	require.Equal(t, "zstd", RequestContentEncoding("zstd"))
+	require.Equal(t, "snappy", RequestContentEncoding("snappy"))
+	require.Equal(t, "identity", RequestContentEncoding(""))
+	require.Equal(t, "identity", RequestContentEncoding("deflate"))
+}
+
+func TestValidateContentEncoding(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd", "snappy", "identity", ""} {
+		require.NoError(t, ValidateContentEncoding(encoding))
+	}
+	require.Error(t, ValidateContentEncoding("deflate"))
+}
@@ -34,6 +34,7 @@ type FileWriter struct {
 	maxArchives              int
 	expireTime               time.Time
 	bytesWritten             int64
+	onRotate                 func()
 	sync.Mutex
 }
 
@@ -71,6 +72,14 @@ func getFilenameRotationTemplate(filename string) string {
 	return stem + ".%s-%s" + fileExt
 }
 
+// OnRotate sets a function called after the file has been rotated, before
+// writing to the new file.
+func (w *FileWriter) OnRotate(fn func()) {
+	w.Lock()
+	defer w.Unlock()
+	w.onRotate = fn
+}
+
 // Write writes p to the current file, then checks to see if
 // rotation is necessary.
 func (w *FileWriter) Write(p []byte) (n int, err error) {
@@ -133,7 +142,12 @@ func (w *FileWriter) rotateIfNeeded() error {
 			//Ignore rotation errors and keep the log open
 			fmt.Printf("unable to rotate the file '%s', %s", w.filename, err.Error())
 		}
-		return w.openCurrent()
+		if err := w.openCurrent(); err != nil {
+			return err
+		}
+		if w.onRotate != nil {
+			w.onRotate()
+		}
 	}
 	return nil
 }
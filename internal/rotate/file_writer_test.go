@@ -146,3 +146,22 @@ func TestFileWriter_CloseRotates(t *testing.T) {
 	assert.Equal(t, 1, len(files))
 	assert.Regexp(t, "^test\\.[^\\.]+\\.log$", files[0].Name())
 }
+
+func TestFileWriter_OnRotate(t *testing.T) {
+	tempDir, err := ioutil.TempDir("", "RotationCallback")
+	require.NoError(t, err)
+	maxSize := int64(9)
+	writer, err := NewFileWriter(filepath.Join(tempDir, "test.log"), 0, maxSize, -1)
+	require.NoError(t, err)
+	defer func() { writer.Close(); os.RemoveAll(tempDir) }()
+
+	rotations := 0
+	writer.(*FileWriter).OnRotate(func() { rotations++ })
+
+	_, err = writer.Write([]byte("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, 0, rotations)
+	_, err = writer.Write([]byte("World"))
+	require.NoError(t, err)
+	require.Equal(t, 1, rotations)
+}
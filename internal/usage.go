@@ -10,12 +10,24 @@ Usage:
 
 The commands & flags are:
 
-  config              print out full sample configuration to stdout
-  version             print the version to stdout
+  config                 print out full sample configuration to stdout
+  config check           validate the config and all plugins without starting them
+  secret set <id> <key>  store a secret read from stdin in the given secret store
+  version                print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
+  --config-url-cache-dir <dir>   directory keeping the last known good copy of remote configs
+  --config-url-checksum          require the SHA256 checksum of remote configs at '<url>.sha256'
+  --config-url-poll-interval <interval>
+                                 check remote configs for changes at this interval
+                                 and reload them, ie, '--config-url-poll-interval 5m'
+  --config-url-public-key <file> file with the base64 encoded ed25519 public key verifying
+                                 the signature of remote configs at '<url>.sig'
+  --config-url-timeout <interval>
+                                 timeout of the requests fetching remote configs, 30s
+                                 by default
   --plugin-directory             directory containing *.so files, this directory will be
                                  searched recursively. Any Plugin found will be loaded
                                  and namespaced.
@@ -37,7 +49,11 @@ The commands & flags are:
   --test-wait                    wait up to this many seconds for service
                                  inputs to complete in test or once mode
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
+  --validate                     validate the config and all plugins without starting
+                                 them, exits non-zero if the config is invalid
   --version                      display the version and exit
+  --watch-config <interval>      check the config files for changes at this interval
+                                 and reload them, ie, '--watch-config 30s'
 
 Examples:
 
@@ -47,6 +63,9 @@ Examples:
   # generate config with only cpu input & influxdb output plugins defined
   telegraf --input-filter cpu --output-filter influxdb config
 
+  # check a config for errors and deprecated options
+  telegraf --config telegraf.conf --validate
+
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
@@ -18,6 +18,12 @@ func WithTracking(metric telegraf.Metric, fn NotifyFunc) (telegraf.Metric, teleg
 	return newTrackingMetric(metric, fn)
 }
 
+// IsTracking returns true if the delivery of the metric is tracked.
+func IsTracking(metric telegraf.Metric) bool {
+	_, ok := metric.(*trackingMetric)
+	return ok
+}
+
 // WithBatchTracking adds tracking to the metrics and registers the notify
 // function to be called when processing is complete.
 func WithGroupTracking(metric []telegraf.Metric, fn NotifyFunc) ([]telegraf.Metric, telegraf.TrackingID) {
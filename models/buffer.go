@@ -24,6 +24,12 @@ type Buffer struct {
 	batchFirst int // index of the first metric in the batch
 	batchSize  int // number of metrics currently in the batch
 
+	BufferStats
+}
+
+// BufferStats holds the internal statistics shared by all buffer
+// implementations of an output.
+type BufferStats struct {
 	MetricsAdded   selfstat.Stat
 	MetricsWritten selfstat.Stat
 	MetricsDropped selfstat.Stat
@@ -33,11 +39,6 @@ type Buffer struct {
 
 // NewBuffer returns a new empty Buffer with the given capacity.
 func NewBuffer(name string, alias string, capacity int) *Buffer {
-	tags := map[string]string{"output": name}
-	if alias != "" {
-		tags["alias"] = alias
-	}
-
 	b := &Buffer{
 		buf:   make([]telegraf.Metric, capacity),
 		first: 0,
@@ -45,6 +46,19 @@ func NewBuffer(name string, alias string, capacity int) *Buffer {
 		size:  0,
 		cap:   capacity,
 
+		BufferStats: NewBufferStats(name, alias, capacity),
+	}
+	return b
+}
+
+// NewBufferStats registers the buffer statistics of the given output.
+func NewBufferStats(name string, alias string, capacity int) BufferStats {
+	tags := map[string]string{"output": name}
+	if alias != "" {
+		tags["alias"] = alias
+	}
+
+	s := BufferStats{
 		MetricsAdded: selfstat.Register(
 			"write",
 			"metrics_added",
@@ -71,9 +85,9 @@ func NewBuffer(name string, alias string, capacity int) *Buffer {
 			tags,
 		),
 	}
-	b.BufferSize.Set(int64(0))
-	b.BufferLimit.Set(int64(capacity))
-	return b
+	s.BufferSize.Set(int64(0))
+	s.BufferLimit.Set(int64(capacity))
+	return s
 }
 
 // Len returns the number of metrics currently in the buffer.
@@ -88,19 +102,19 @@ func (b *Buffer) length() int {
 	return min(b.size+b.batchSize, b.cap)
 }
 
-func (b *Buffer) metricAdded() {
-	b.MetricsAdded.Incr(1)
+func (s *BufferStats) metricAdded() {
+	s.MetricsAdded.Incr(1)
 }
 
-func (b *Buffer) metricWritten(metric telegraf.Metric) {
+func (s *BufferStats) metricWritten(metric telegraf.Metric) {
 	AgentMetricsWritten.Incr(1)
-	b.MetricsWritten.Incr(1)
+	s.MetricsWritten.Incr(1)
 	metric.Accept()
 }
 
-func (b *Buffer) metricDropped(metric telegraf.Metric) {
+func (s *BufferStats) metricDropped(metric telegraf.Metric) {
 	AgentMetricsDropped.Incr(1)
-	b.MetricsDropped.Incr(1)
+	s.MetricsDropped.Incr(1)
 	metric.Reject()
 }
 
@@ -174,7 +188,9 @@ func (b *Buffer) Batch(batchSize int) []telegraf.Metric {
 	return out
 }
 
-// Accept marks the batch, acquired from Batch(), as successfully written.
+// Accept marks the batch, acquired from Batch(), as successfully written.  If
+// only the first metrics of the batch are given, the remaining metrics must be
+// returned with Reject().
 func (b *Buffer) Accept(batch []telegraf.Metric) {
 	b.Lock()
 	defer b.Unlock()
@@ -247,6 +263,12 @@ func (b *Buffer) prevby(index, count int) int {
 	return index
 }
 
+// Close releases the resources held by the buffer.  The memory buffer holds
+// none, the method only exists to satisfy the buffer contract.
+func (b *Buffer) Close() error {
+	return nil
+}
+
 func (b *Buffer) resetBatch() {
 	b.batchFirst = 0
 	b.batchSize = 0
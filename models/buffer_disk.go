@@ -0,0 +1,718 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+const (
+	// Buffer strategies selectable on outputs.
+	BufferStrategyMemory = "memory"
+	BufferStrategyDisk   = "disk"
+
+	// Default maximum size of a single write-ahead-log segment file.
+	DefaultDiskBufferSegmentSize = 8 * 1024 * 1024
+
+	// Size of the length and checksum header preceding each record.
+	diskRecordHeaderSize = 8
+
+	diskSegmentSuffix = ".wal"
+	diskHeadFile      = "head"
+)
+
+var (
+	errDiskRecordCorrupt = errors.New("corrupt record")
+
+	// diskBufferDirs tracks the directories in use so that two outputs can
+	// not write to the same log.
+	diskBufferDirs   = make(map[string]bool)
+	diskBufferDirsMu sync.Mutex
+)
+
+// DiskBufferConfig contains the settings of a DiskBuffer.
+type DiskBufferConfig struct {
+	// Directory holding the log segments, exclusive to one output.
+	Directory string
+
+	// Maximum number of unwritten metrics, the oldest are dropped first.
+	Capacity int
+
+	// Maximum bytes used on disk, enforced by dropping the oldest segment.
+	// When zero no size based retention is performed.
+	MaxSize int64
+
+	// Maximum age of a segment since its last write, enforced by dropping
+	// the oldest segment.  When zero no age based retention is performed.
+	MaxAge time.Duration
+
+	// Size at which a new segment is started.
+	SegmentSize int64
+}
+
+// diskSegment is a single log file.  Records are appended to the last
+// segment only and segments are removed once all of their records are
+// written or dropped.
+type diskSegment struct {
+	id        uint64
+	path      string
+	count     int   // number of records in the file
+	size      int64 // size of the file in bytes
+	lastWrite time.Time
+}
+
+// diskPosition identifies a record boundary in the log.
+type diskPosition struct {
+	segment uint64
+	offset  int64
+	index   int // number of records in the segment before offset
+}
+
+// diskRecordID identifies a record by its segment and index in the segment.
+type diskRecordID struct {
+	segment uint64
+	index   int
+}
+
+// DiskBuffer stores metrics in a write-ahead-log on the local disk so that
+// they survive a restart of the agent.  It fulfills the same contract as
+// Buffer: metrics are returned by Batch in the order they were added and are
+// only removed from the log after the batch is accepted.
+//
+// Records are not synced to disk on every write; metrics survive a crash or
+// kill of the process but may be lost on a power failure.
+//
+// Tracking metrics are kept in memory until they are written or dropped, so
+// that inputs only acknowledge messages once the output wrote them.  Their
+// number is bounded by the undelivered messages limit of the inputs.  When
+// the buffer is closed the remaining tracking metrics are accepted, as their
+// delivery is then left to the replay of the log.
+type DiskBuffer struct {
+	sync.Mutex
+	cfg DiskBufferConfig
+	log telegraf.Logger
+
+	segments []*diskSegment // oldest first, the last one is written to
+	writer   *os.File
+
+	head diskPosition // first unwritten record
+	size int          // number of unwritten records
+
+	batchEnds []diskPosition // position after each record of the batch
+	batchSize int            // number of metrics currently in the batch
+
+	encoded bytes.Buffer      // records encoded but not yet written
+	pending []telegraf.Metric // metrics of the encoded records
+
+	tracked map[diskRecordID]telegraf.Metric // unwritten tracking metrics
+
+	BufferStats
+}
+
+// NewDiskBuffer opens the log in the configured directory, creating it if
+// needed, and replays any metrics left from a previous run.
+func NewDiskBuffer(name string, alias string, cfg DiskBufferConfig, log telegraf.Logger) (*DiskBuffer, error) {
+	if cfg.Directory == "" {
+		return nil, errors.New("no buffer directory given")
+	}
+	if cfg.SegmentSize <= 0 {
+		cfg.SegmentSize = DefaultDiskBufferSegmentSize
+	}
+	// Keep several segments within the size limit so retention does not
+	// need to discard most of the buffer at once.
+	if cfg.MaxSize > 0 && cfg.SegmentSize > cfg.MaxSize/4 {
+		cfg.SegmentSize = cfg.MaxSize / 4
+	}
+
+	dir, err := filepath.Abs(cfg.Directory)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Directory = dir
+
+	diskBufferDirsMu.Lock()
+	defer diskBufferDirsMu.Unlock()
+	if diskBufferDirs[dir] {
+		return nil, fmt.Errorf("buffer directory %q is used by another output", dir)
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("creating buffer directory failed: %w", err)
+	}
+
+	b := &DiskBuffer{
+		cfg:         cfg,
+		log:         log,
+		tracked:     make(map[diskRecordID]telegraf.Metric),
+		BufferStats: NewBufferStats(name, alias, cfg.Capacity),
+	}
+	if err := b.open(); err != nil {
+		b.closeWriter()
+		return nil, err
+	}
+	diskBufferDirs[dir] = true
+
+	if b.size > 0 {
+		b.log.Infof("Replaying %d metrics from buffer directory %q", b.size, dir)
+	}
+
+	b.enforceRetention()
+	b.BufferSize.Set(int64(b.size))
+	return b, nil
+}
+
+// open scans the existing segments, restores the position of the first
+// unwritten record and opens the newest segment for writing.
+func (b *DiskBuffer) open() error {
+	files, err := ioutil.ReadDir(b.cfg.Directory)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, diskSegmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, diskSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, &diskSegment{
+			id:        id,
+			path:      filepath.Join(b.cfg.Directory, name),
+			lastWrite: file.ModTime(),
+		})
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].id < b.segments[j].id })
+
+	head, err := b.readHead()
+	if err != nil {
+		b.log.Warnf("Reading buffer head failed, replaying all segments: %v", err)
+	}
+
+	// Remove segments that were completely written before the restart.
+	for len(b.segments) > 0 && b.segments[0].id < head.segment {
+		if err := os.Remove(b.segments[0].path); err != nil {
+			return err
+		}
+		b.segments = b.segments[1:]
+	}
+	if len(b.segments) == 0 || b.segments[0].id != head.segment {
+		head = diskPosition{}
+	}
+
+	for i, seg := range b.segments {
+		offsets, err := scanSegment(seg)
+		if err != nil {
+			return fmt.Errorf("scanning segment %q failed: %w", seg.path, err)
+		}
+
+		if i == 0 {
+			b.head = diskPosition{segment: seg.id}
+			if head.offset == seg.size {
+				b.head = diskPosition{segment: seg.id, offset: seg.size, index: seg.count}
+			} else if idx := sort.Search(len(offsets), func(k int) bool { return offsets[k] >= head.offset }); idx < len(offsets) && offsets[idx] == head.offset {
+				b.head = diskPosition{segment: seg.id, offset: head.offset, index: idx}
+			} else {
+				b.log.Warnf("Buffer head does not match a record in %q, replaying the segment", seg.path)
+			}
+		}
+		b.size += seg.count
+	}
+	b.size -= b.head.index
+
+	if len(b.segments) == 0 || b.segments[len(b.segments)-1].size >= b.cfg.SegmentSize {
+		return b.rotate()
+	}
+
+	seg := b.segments[len(b.segments)-1]
+	b.writer, err = os.OpenFile(seg.path, os.O_WRONLY|os.O_APPEND, 0640)
+	return err
+}
+
+// scanSegment counts the records of a segment and truncates the file after
+// the last valid record, for example if the process stopped in the middle of
+// a write.  The offsets of all records are returned.
+func scanSegment(seg *diskSegment) ([]int64, error) {
+	f, err := os.OpenFile(seg.path, os.O_RDWR, 0640)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var offsets []int64
+	var offset int64
+	r := bufio.NewReader(f)
+	for {
+		n, err := skipRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if err := f.Truncate(offset); err != nil {
+				return nil, err
+			}
+			break
+		}
+		offsets = append(offsets, offset)
+		offset += n
+	}
+
+	seg.count = len(offsets)
+	seg.size = offset
+	return offsets, nil
+}
+
+// Len returns the number of metrics currently in the buffer.
+func (b *DiskBuffer) Len() int {
+	b.Lock()
+	defer b.Unlock()
+
+	return b.size
+}
+
+// Add adds metrics to the buffer and returns number of dropped metrics.
+func (b *DiskBuffer) Add(metrics ...telegraf.Metric) int {
+	b.Lock()
+	defer b.Unlock()
+
+	dropped := 0
+	for _, m := range metrics {
+		seg := b.segments[len(b.segments)-1]
+		if seg.size+int64(b.encoded.Len()) >= b.cfg.SegmentSize {
+			dropped += b.flush()
+			if err := b.rotate(); err != nil {
+				b.log.Errorf("Starting new buffer segment failed: %v", err)
+				b.metricDropped(m)
+				dropped++
+				continue
+			}
+		}
+
+		if err := appendRecord(&b.encoded, m); err != nil {
+			b.log.Errorf("Writing metric to buffer failed: %v", err)
+			b.metricDropped(m)
+			dropped++
+			continue
+		}
+		b.pending = append(b.pending, m)
+	}
+	dropped += b.flush()
+
+	dropped += b.enforceRetention()
+	b.BufferSize.Set(int64(b.size))
+	return dropped
+}
+
+// flush appends the encoded records to the newest segment with a single
+// write and returns the number of metrics dropped if the write fails.
+func (b *DiskBuffer) flush() int {
+	pending := b.pending
+	b.pending = b.pending[:0]
+	defer b.encoded.Reset()
+	if len(pending) == 0 {
+		return 0
+	}
+
+	seg := b.segments[len(b.segments)-1]
+	if _, err := b.writer.Write(b.encoded.Bytes()); err != nil {
+		b.log.Errorf("Writing metrics to buffer failed: %v", err)
+		// Do not leave a partial record behind.
+		if err := b.writer.Truncate(seg.size); err != nil {
+			b.log.Errorf("Truncating buffer segment failed: %v", err)
+		}
+		for _, m := range pending {
+			b.metricDropped(m)
+		}
+		return len(pending)
+	}
+
+	for i, m := range pending {
+		if metric.IsTracking(m) {
+			b.tracked[diskRecordID{segment: seg.id, index: seg.count + i}] = m
+		}
+		b.metricAdded()
+	}
+	seg.count += len(pending)
+	seg.size += int64(b.encoded.Len())
+	seg.lastWrite = time.Now()
+	b.size += len(pending)
+	return 0
+}
+
+// Batch returns a slice containing up to batchSize of the oldest metrics not
+// yet dropped.  Metrics are ordered from oldest to newest in the batch.  The
+// batch must not be modified by the client.
+func (b *DiskBuffer) Batch(batchSize int) []telegraf.Metric {
+	b.Lock()
+	defer b.Unlock()
+
+	outLen := min(b.size, batchSize)
+	out := make([]telegraf.Metric, 0, outLen)
+	if outLen == 0 {
+		return out
+	}
+	b.batchEnds = b.batchEnds[:0]
+
+	pos := b.head
+	for _, seg := range b.segments {
+		if len(out) == outLen {
+			break
+		}
+		if seg.id != pos.segment {
+			pos = diskPosition{segment: seg.id}
+		}
+		if pos.index == seg.count {
+			continue
+		}
+
+		var err error
+		out, pos, err = b.readSegment(seg, pos, out, outLen)
+		if err != nil {
+			b.log.Errorf("Reading metrics from buffer failed: %v", err)
+			break
+		}
+	}
+
+	b.batchSize = len(out)
+	return out
+}
+
+// readSegment decodes records starting at pos until the batch is full or the
+// end of the segment is reached, recording the position after each record.
+func (b *DiskBuffer) readSegment(seg *diskSegment, pos diskPosition, out []telegraf.Metric, outLen int) ([]telegraf.Metric, diskPosition, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return out, pos, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return out, pos, err
+	}
+
+	r := bufio.NewReader(f)
+	for len(out) < outLen && pos.index < seg.count {
+		m, n, err := readRecord(r)
+		if err != nil {
+			return out, pos, err
+		}
+		if tm, ok := b.tracked[diskRecordID{segment: seg.id, index: pos.index}]; ok {
+			m = tm
+		}
+		out = append(out, m)
+		pos.offset += n
+		pos.index++
+		b.batchEnds = append(b.batchEnds, pos)
+	}
+	return out, pos, nil
+}
+
+// Accept marks the batch, acquired from Batch(), as successfully written.  If
+// only the first metrics of the batch are given, the remaining metrics must be
+// returned with Reject().
+func (b *DiskBuffer) Accept(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	for _, m := range batch {
+		b.metricWritten(m)
+	}
+
+	if count := min(len(batch), b.batchSize); count > 0 {
+		for _, end := range b.batchEnds[:count] {
+			delete(b.tracked, diskRecordID{segment: end.segment, index: end.index - 1})
+		}
+		b.advance(b.batchEnds[count-1], count)
+		if err := b.writeHead(); err != nil {
+			b.log.Errorf("Persisting buffer head failed: %v", err)
+		}
+	}
+
+	b.resetBatch()
+	b.enforceRetention()
+	b.BufferSize.Set(int64(b.size))
+}
+
+// Reject returns the batch, acquired from Batch(), to the buffer and marks it
+// as unsent.  The metrics are still in the log so only the batch is reset.
+func (b *DiskBuffer) Reject(batch []telegraf.Metric) {
+	b.Lock()
+	defer b.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	b.resetBatch()
+	b.enforceRetention()
+	b.BufferSize.Set(int64(b.size))
+}
+
+// Close closes the segment being written and releases the directory.
+func (b *DiskBuffer) Close() error {
+	b.Lock()
+	defer b.Unlock()
+
+	diskBufferDirsMu.Lock()
+	delete(diskBufferDirs, b.cfg.Directory)
+	diskBufferDirsMu.Unlock()
+
+	for id, m := range b.tracked {
+		m.Accept()
+		delete(b.tracked, id)
+	}
+
+	if err := b.writeHead(); err != nil {
+		b.closeWriter()
+		return err
+	}
+	return b.closeWriter()
+}
+
+func (b *DiskBuffer) closeWriter() error {
+	if b.writer == nil {
+		return nil
+	}
+	err := b.writer.Close()
+	b.writer = nil
+	return err
+}
+
+func (b *DiskBuffer) resetBatch() {
+	b.batchEnds = b.batchEnds[:0]
+	b.batchSize = 0
+}
+
+// advance moves the head to pos, which is count records further, and removes
+// the segments that are completely written.
+func (b *DiskBuffer) advance(pos diskPosition, count int) {
+	b.size -= count
+	b.head = pos
+	for len(b.segments) > 1 && b.segments[0].id != b.head.segment {
+		b.removeOldest()
+	}
+	if len(b.segments) > 1 && b.head.index == b.segments[0].count {
+		b.removeOldest()
+		b.head = diskPosition{segment: b.segments[0].id}
+	}
+}
+
+// enforceRetention drops the oldest metrics exceeding the configured age,
+// size and capacity limits and returns the number of dropped metrics.  The
+// limits are only enforced while no batch is in flight; the metrics of a
+// batch are dropped after it is returned if it is still over the limit.
+func (b *DiskBuffer) enforceRetention() int {
+	if b.batchSize > 0 {
+		return 0
+	}
+
+	dropped := 0
+	if b.cfg.MaxAge > 0 {
+		cutoff := time.Now().Add(-b.cfg.MaxAge)
+		for b.size > 0 && b.segments[0].lastWrite.Before(cutoff) {
+			dropped += b.dropSegment()
+		}
+	}
+
+	if b.cfg.MaxSize > 0 {
+		for b.size > 0 && b.diskSize() > b.cfg.MaxSize {
+			dropped += b.dropSegment()
+		}
+	}
+
+	if b.cfg.Capacity > 0 && b.size > b.cfg.Capacity {
+		dropped += b.dropRecords(b.size - b.cfg.Capacity)
+	}
+
+	if dropped > 0 {
+		if err := b.writeHead(); err != nil {
+			b.log.Errorf("Persisting buffer head failed: %v", err)
+		}
+	}
+	return dropped
+}
+
+func (b *DiskBuffer) diskSize() int64 {
+	var size int64
+	for _, seg := range b.segments {
+		size += seg.size
+	}
+	return size
+}
+
+// dropSegment drops all unwritten records of the oldest segment.
+func (b *DiskBuffer) dropSegment() int {
+	seg := b.segments[0]
+	n := seg.count - b.head.index
+	b.rejectTracked(seg.id, b.head.index, seg.count)
+	b.countDropped(n)
+	b.size -= n
+
+	if len(b.segments) == 1 {
+		if err := b.rotate(); err != nil {
+			b.log.Errorf("Starting new buffer segment failed: %v", err)
+			b.head = diskPosition{segment: seg.id, offset: seg.size, index: seg.count}
+			return n
+		}
+	}
+	b.removeOldest()
+	b.head = diskPosition{segment: b.segments[0].id}
+	return n
+}
+
+// dropRecords drops up to count of the oldest unwritten records.
+func (b *DiskBuffer) dropRecords(count int) int {
+	dropped := 0
+	for dropped < count && b.size > 0 {
+		seg := b.segments[0]
+		if b.head.index == seg.count {
+			if len(b.segments) == 1 {
+				break
+			}
+			b.removeOldest()
+			b.head = diskPosition{segment: b.segments[0].id}
+			continue
+		}
+
+		pos, n, err := skipRecords(seg, b.head, count-dropped)
+		if err != nil {
+			b.log.Errorf("Dropping metrics from buffer failed: %v", err)
+			break
+		}
+		b.rejectTracked(seg.id, b.head.index, pos.index)
+		b.countDropped(n)
+		b.size -= n
+		b.head = pos
+		dropped += n
+	}
+
+	if len(b.segments) > 1 && b.head.index == b.segments[0].count {
+		b.removeOldest()
+		b.head = diskPosition{segment: b.segments[0].id}
+	}
+	return dropped
+}
+
+func (b *DiskBuffer) countDropped(n int) {
+	AgentMetricsDropped.Incr(int64(n))
+	b.MetricsDropped.Incr(int64(n))
+}
+
+// rejectTracked rejects the tracking metrics of the dropped records of a
+// segment, from index first up to but excluding index last.
+func (b *DiskBuffer) rejectTracked(segment uint64, first, last int) {
+	if len(b.tracked) == 0 {
+		return
+	}
+	for index := first; index < last; index++ {
+		id := diskRecordID{segment: segment, index: index}
+		if m, ok := b.tracked[id]; ok {
+			m.Reject()
+			delete(b.tracked, id)
+		}
+	}
+}
+
+// removeOldest deletes the oldest segment file.
+func (b *DiskBuffer) removeOldest() {
+	seg := b.segments[0]
+	if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+		b.log.Errorf("Removing buffer segment failed: %v", err)
+	}
+	b.segments = b.segments[1:]
+}
+
+// rotate closes the current segment and starts a new one.
+func (b *DiskBuffer) rotate() error {
+	var id uint64 = 1
+	if len(b.segments) > 0 {
+		id = b.segments[len(b.segments)-1].id + 1
+	}
+
+	seg := &diskSegment{
+		id:        id,
+		path:      filepath.Join(b.cfg.Directory, fmt.Sprintf("%020d%s", id, diskSegmentSuffix)),
+		lastWrite: time.Now(),
+	}
+	f, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+
+	if err := b.closeWriter(); err != nil {
+		b.log.Errorf("Closing buffer segment failed: %v", err)
+	}
+	b.writer = f
+	b.segments = append(b.segments, seg)
+	if len(b.segments) == 1 {
+		b.head = diskPosition{segment: seg.id}
+	}
+	return nil
+}
+
+// readHead loads the persisted position of the first unwritten record.
+func (b *DiskBuffer) readHead() (diskPosition, error) {
+	var pos diskPosition
+	buf, err := ioutil.ReadFile(filepath.Join(b.cfg.Directory, diskHeadFile))
+	if os.IsNotExist(err) {
+		return pos, nil
+	}
+	if err != nil {
+		return pos, err
+	}
+	_, err = fmt.Sscanf(string(buf), "%d %d", &pos.segment, &pos.offset)
+	return pos, err
+}
+
+// writeHead persists the position of the first unwritten record.
+func (b *DiskBuffer) writeHead() error {
+	filename := filepath.Join(b.cfg.Directory, diskHeadFile)
+	tmp := filename + ".tmp"
+	content := fmt.Sprintf("%d %d\n", b.head.segment, b.head.offset)
+	if err := ioutil.WriteFile(tmp, []byte(content), 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// skipRecords moves forward by up to count records from pos within the
+// segment and returns the new position and the number of records skipped.
+func skipRecords(seg *diskSegment, pos diskPosition, count int) (diskPosition, int, error) {
+	f, err := os.Open(seg.path)
+	if err != nil {
+		return pos, 0, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(pos.offset, io.SeekStart); err != nil {
+		return pos, 0, err
+	}
+
+	r := bufio.NewReader(f)
+	skipped := 0
+	for skipped < count && pos.index < seg.count {
+		n, err := skipRecord(r)
+		if err != nil {
+			return pos, skipped, err
+		}
+		pos.offset += n
+		pos.index++
+		skipped++
+	}
+	return pos, skipped, nil
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// Records consist of a header holding the length and checksum of the payload,
+// followed by the payload:
+//
+//	name, time, value type, tag count, tags, field count, fields
+//
+// Strings and counts are length prefixed with unsigned varints, the time is a
+// signed varint and each field value is preceded by a byte giving its type.
+// Floats are stored as their eight byte IEEE 754 representation.
+const (
+	diskFieldInt byte = iota + 1
+	diskFieldUint
+	diskFieldFloat
+	diskFieldString
+	diskFieldBool
+)
+
+// appendRecord encodes a metric, prefixed with its length and checksum, to
+// the end of the buffer.  The buffer is left unchanged on error.
+func appendRecord(buf *bytes.Buffer, m telegraf.Metric) error {
+	start := buf.Len()
+	buf.Write(make([]byte, diskRecordHeaderSize))
+
+	e := recordEncoder{buf: buf}
+	e.string(m.Name())
+	e.varint(m.Time().UnixNano())
+	buf.WriteByte(byte(m.Type()))
+
+	tags := m.TagList()
+	e.uvarint(uint64(len(tags)))
+	for _, tag := range tags {
+		e.string(tag.Key)
+		e.string(tag.Value)
+	}
+
+	fields := m.FieldList()
+	e.uvarint(uint64(len(fields)))
+	for _, field := range fields {
+		e.string(field.Key)
+		switch v := field.Value.(type) {
+		case int64:
+			buf.WriteByte(diskFieldInt)
+			e.varint(v)
+		case uint64:
+			buf.WriteByte(diskFieldUint)
+			e.uvarint(v)
+		case float64:
+			buf.WriteByte(diskFieldFloat)
+			e.float(v)
+		case string:
+			buf.WriteByte(diskFieldString)
+			e.string(v)
+		case bool:
+			buf.WriteByte(diskFieldBool)
+			if v {
+				buf.WriteByte(1)
+			} else {
+				buf.WriteByte(0)
+			}
+		default:
+			buf.Truncate(start)
+			return fmt.Errorf("unsupported type %T of field %q", field.Value, field.Key)
+		}
+	}
+
+	record := buf.Bytes()[start:]
+	payload := record[diskRecordHeaderSize:]
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	return nil
+}
+
+type recordEncoder struct {
+	buf     *bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *recordEncoder) uvarint(v uint64) {
+	n := binary.PutUvarint(e.scratch[:], v)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *recordEncoder) varint(v int64) {
+	n := binary.PutVarint(e.scratch[:], v)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *recordEncoder) float(v float64) {
+	binary.BigEndian.PutUint64(e.scratch[:8], math.Float64bits(v))
+	e.buf.Write(e.scratch[:8])
+}
+
+func (e *recordEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// readPayload reads and verifies a single record returning its payload.
+func readPayload(r io.Reader) ([]byte, error) {
+	var header [diskRecordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errDiskRecordCorrupt
+		}
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[0:4]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errDiskRecordCorrupt
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errDiskRecordCorrupt
+	}
+	return payload, nil
+}
+
+// skipRecord verifies a single record and returns its size.
+func skipRecord(r io.Reader) (int64, error) {
+	payload, err := readPayload(r)
+	if err != nil {
+		return 0, err
+	}
+	return int64(diskRecordHeaderSize + len(payload)), nil
+}
+
+// readRecord decodes a single record and returns the metric and the size of
+// the record.
+func readRecord(r io.Reader) (telegraf.Metric, int64, error) {
+	payload, err := readPayload(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	d := recordDecoder{r: bytes.NewReader(payload)}
+	name := d.string()
+	ts := d.varint()
+	vtype := telegraf.ValueType(d.byte())
+
+	n := d.count()
+	tags := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		key := d.string()
+		tags[key] = d.string()
+	}
+
+	n = d.count()
+	fields := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key := d.string()
+		switch kind := d.byte(); kind {
+		case diskFieldInt:
+			fields[key] = d.varint()
+		case diskFieldUint:
+			fields[key] = d.uvarint()
+		case diskFieldFloat:
+			fields[key] = d.float()
+		case diskFieldString:
+			fields[key] = d.string()
+		case diskFieldBool:
+			fields[key] = d.byte() != 0
+		default:
+			if d.err == nil {
+				d.err = fmt.Errorf("unknown type %d of field %q", kind, key)
+			}
+		}
+	}
+	if d.err != nil {
+		return nil, 0, d.err
+	}
+
+	m := metric.New(name, tags, fields, time.Unix(0, ts), vtype)
+	return m, int64(diskRecordHeaderSize + len(payload)), nil
+}
+
+// recordDecoder reads the values of a payload, keeping the first error.  Once
+// an error occurred zero values are returned.
+type recordDecoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *recordDecoder) fail(err error) {
+	if d.err == nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		d.err = err
+	}
+}
+
+func (d *recordDecoder) byte() byte {
+	if d.err != nil {
+		return 0
+	}
+	v, err := d.r.ReadByte()
+	d.fail(err)
+	return v
+}
+
+func (d *recordDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	d.fail(err)
+	return v
+}
+
+func (d *recordDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(d.r)
+	d.fail(err)
+	return v
+}
+
+func (d *recordDecoder) float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	var buf [8]byte
+	_, err := io.ReadFull(d.r, buf[:])
+	d.fail(err)
+	return math.Float64frombits(binary.BigEndian.Uint64(buf[:]))
+}
+
+// count reads the number of following items, bounded by the remaining
+// payload as each item takes at least one byte.
+func (d *recordDecoder) count() int {
+	n := d.uvarint()
+	if n > uint64(d.r.Len()) {
+		d.fail(errDiskRecordCorrupt)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *recordDecoder) string() string {
+	n := d.count()
+	if d.err != nil || n == 0 {
+		return ""
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(d.r, buf)
+	d.fail(err)
+	return string(buf)
+}
@@ -0,0 +1,311 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiskBuffer(t *testing.T, cfg DiskBufferConfig) *DiskBuffer {
+	t.Helper()
+	if cfg.Directory == "" {
+		cfg.Directory = t.TempDir()
+	}
+	b, err := NewDiskBuffer("test", "", cfg, testutil.Logger{})
+	require.NoError(t, err)
+	b.MetricsAdded.Set(0)
+	b.MetricsWritten.Set(0)
+	b.MetricsDropped.Set(0)
+	return b
+}
+
+func TestDiskBuffer_BatchAccept(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	require.Equal(t, 3, b.Len())
+
+	batch := b.Batch(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(1), MetricTime(2)}, batch)
+	require.Equal(t, 3, b.Len())
+
+	b.Accept(batch)
+	require.Equal(t, 1, b.Len())
+	require.Equal(t, int64(3), b.MetricsAdded.Get())
+	require.Equal(t, int64(2), b.MetricsWritten.Get())
+
+	batch = b.Batch(2)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_PartialAccept(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(4))
+	batch := b.Batch(3)
+	b.Accept(batch[:1])
+	b.Reject(batch[1:])
+	require.Equal(t, 3, b.Len())
+	require.Equal(t, int64(1), b.MetricsWritten.Get())
+
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(2), MetricTime(3), MetricTime(4)}, batch)
+}
+
+func TestDiskBuffer_Reject(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	batch := b.Batch(2)
+	b.Reject(batch)
+	require.Equal(t, 2, b.Len())
+
+	b.Add(MetricTime(3))
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, batch)
+	require.Equal(t, int64(0), b.MetricsDropped.Get())
+}
+
+func TestDiskBuffer_FieldTypes(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+	defer b.Close()
+
+	m := metric.New(
+		"cpu",
+		map[string]string{"host": "localhost", "cpu": "cpu0"},
+		map[string]interface{}{
+			"int":    int64(0),
+			"uint":   uint64(42),
+			"float":  float64(0.5),
+			"string": "value",
+			"bool":   true,
+		},
+		time.Unix(0, 1234567890),
+		telegraf.Counter,
+	)
+	b.Add(m.Copy())
+
+	batch := b.Batch(1)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{m}, batch)
+	require.Equal(t, telegraf.Counter, batch[0].Type())
+}
+
+func TestDiskBuffer_Replay(t *testing.T) {
+	dir := t.TempDir()
+
+	b := newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3))
+	b.Accept(b.Batch(1))
+	b.Batch(1)
+	require.NoError(t, b.Close())
+
+	b = newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	defer b.Close()
+	require.Equal(t, 2, b.Len())
+
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_ReplayTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+
+	b := newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	b.Add(MetricTime(1), MetricTime(2))
+	seg := b.segments[0].path
+	size := b.segments[0].size
+	require.NoError(t, b.Close())
+
+	// Simulate a crash during the write of the second record
+	require.NoError(t, os.Truncate(seg, size-3))
+
+	b = newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	defer b.Close()
+	require.Equal(t, 1, b.Len())
+
+	b.Add(MetricTime(3))
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(1), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_SegmentRotation(t *testing.T) {
+	dir := t.TempDir()
+	b := newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 100, SegmentSize: 1})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(4))
+	require.Len(t, b.segments, 4)
+
+	batch := b.Batch(3)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, batch)
+	b.Accept(batch)
+	require.Len(t, b.segments, 1)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	batch = b.Batch(3)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(4)}, batch)
+}
+
+func TestDiskBuffer_CapacityDropsOldest(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 3, SegmentSize: 1})
+	defer b.Close()
+
+	dropped := b.Add(MetricTime(1), MetricTime(2), MetricTime(3), MetricTime(4), MetricTime(5))
+	require.Equal(t, 2, dropped)
+	require.Equal(t, 3, b.Len())
+	require.Equal(t, int64(2), b.MetricsDropped.Get())
+
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(3), MetricTime(4), MetricTime(5)}, batch)
+}
+
+func TestDiskBuffer_CapacityDeferredDuringBatch(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 2})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	batch := b.Batch(2)
+	b.Add(MetricTime(3))
+	require.Equal(t, 3, b.Len())
+
+	b.Reject(batch)
+	require.Equal(t, 2, b.Len())
+	require.Equal(t, int64(1), b.MetricsDropped.Get())
+
+	batch = b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_MaxSize(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 100, SegmentSize: 1})
+	defer b.Close()
+
+	b.Add(MetricTime(1))
+	// Leave room for two records, their encoded size differs slightly
+	b.cfg.MaxSize = 2*b.diskSize() + 8
+
+	b.Add(MetricTime(2), MetricTime(3))
+	require.Equal(t, 2, b.Len())
+	require.LessOrEqual(t, b.diskSize(), b.cfg.MaxSize)
+
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_MaxAge(t *testing.T) {
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 100, SegmentSize: 1, MaxAge: time.Hour})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	b.segments[0].lastWrite = time.Now().Add(-2 * time.Hour)
+
+	b.Add(MetricTime(3))
+	require.Equal(t, 2, b.Len())
+	require.Equal(t, int64(1), b.MetricsDropped.Get())
+
+	batch := b.Batch(5)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{MetricTime(2), MetricTime(3)}, batch)
+}
+
+func TestDiskBuffer_DirectoryInUse(t *testing.T) {
+	dir := t.TempDir()
+	b := newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+
+	_, err := NewDiskBuffer("test", "other", DiskBufferConfig{Directory: dir}, testutil.Logger{})
+	require.Error(t, err)
+
+	require.NoError(t, b.Close())
+	b = newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	require.NoError(t, b.Close())
+}
+
+func newTrackingMetric(m telegraf.Metric, delivered *int, rejected *int) telegraf.Metric {
+	tm, _ := metric.WithTracking(m, func(info telegraf.DeliveryInfo) {
+		if info.Delivered() {
+			*delivered++
+		} else {
+			*rejected++
+		}
+	})
+	return tm
+}
+
+func TestDiskBuffer_TrackingMetricAcceptedOnWrite(t *testing.T) {
+	var delivered, rejected int
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+	defer b.Close()
+
+	b.Add(
+		newTrackingMetric(MetricTime(1), &delivered, &rejected),
+		MetricTime(2),
+		newTrackingMetric(MetricTime(3), &delivered, &rejected),
+	)
+	require.Equal(t, 0, delivered)
+
+	batch := b.Batch(3)
+	testutil.RequireMetricsEqual(t,
+		[]telegraf.Metric{MetricTime(1), MetricTime(2), MetricTime(3)}, batch)
+	b.Accept(batch[:2])
+	b.Reject(batch[2:])
+	require.Equal(t, 1, delivered)
+
+	b.Accept(b.Batch(1))
+	require.Equal(t, 2, delivered)
+	require.Equal(t, 0, rejected)
+	require.Empty(t, b.tracked)
+}
+
+func TestDiskBuffer_TrackingMetricRejectedOnDrop(t *testing.T) {
+	var delivered, rejected int
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 2})
+	defer b.Close()
+
+	b.Add(
+		newTrackingMetric(MetricTime(1), &delivered, &rejected),
+		newTrackingMetric(MetricTime(2), &delivered, &rejected),
+		newTrackingMetric(MetricTime(3), &delivered, &rejected),
+	)
+	require.Equal(t, 0, delivered)
+	require.Equal(t, 1, rejected)
+	require.Len(t, b.tracked, 2)
+}
+
+func TestDiskBuffer_TrackingMetricAcceptedOnClose(t *testing.T) {
+	var delivered, rejected int
+	b := newDiskBuffer(t, DiskBufferConfig{Capacity: 10})
+
+	b.Add(newTrackingMetric(MetricTime(1), &delivered, &rejected))
+	require.Equal(t, 0, delivered)
+	require.NoError(t, b.Close())
+	require.Equal(t, 1, delivered)
+}
+
+func TestDiskBuffer_HeadPersisted(t *testing.T) {
+	dir := t.TempDir()
+	b := newDiskBuffer(t, DiskBufferConfig{Directory: dir, Capacity: 10})
+	defer b.Close()
+
+	b.Add(MetricTime(1), MetricTime(2))
+	b.Accept(b.Batch(1))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, diskHeadFile))
+	require.NoError(t, err)
+	require.NotEmpty(t, content)
+}
@@ -1,10 +1,20 @@
 package models
 
 import (
+	"errors"
 	"fmt"
-
+	"sync"
+	"time"
+
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/filter"
+	exprpb "google.golang.org/genproto/googleapis/api/expr/v1alpha1"
+	"google.golang.org/protobuf/proto"
 )
 
 // TagFilter is the name of a tag, and the values on which to filter
@@ -34,9 +44,45 @@ type Filter struct {
 	TagInclude []string
 	tagInclude filter.Filter
 
+	// MetricPass is a CEL expression evaluated on the name, tags, fields and
+	// time of a metric; the metric is selected if it returns true.
+	MetricPass     string
+	metricPass     cel.Program
+	metricPassKeys []metricPassKey
+	evalErrors     *errorLimiter
+
 	isActive bool
 }
 
+// metricPassErrorInterval is the minimum interval between two metricpass
+// evaluation errors returned by a filter.
+const metricPassErrorInterval = time.Minute
+
+// metricPassKey is a tag or field the metricpass expression reads.
+type metricPassKey struct {
+	variable string
+	key      string
+}
+
+// errorLimiter limits the rate at which errors are reported.
+type errorLimiter struct {
+	sync.Mutex
+	last time.Time
+}
+
+// allow returns true if an error may be reported, at most once per interval.
+func (l *errorLimiter) allow(interval time.Duration) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	if now.Sub(l.last) < interval {
+		return false
+	}
+	l.last = now
+	return true
+}
+
 // Compile all Filter lists into filter.Filter objects.
 func (f *Filter) Compile() error {
 	if len(f.NameDrop) == 0 &&
@@ -46,7 +92,8 @@ func (f *Filter) Compile() error {
 		len(f.TagInclude) == 0 &&
 		len(f.TagExclude) == 0 &&
 		len(f.TagPass) == 0 &&
-		len(f.TagDrop) == 0 {
+		len(f.TagDrop) == 0 &&
+		f.MetricPass == "" {
 		return nil
 	}
 
@@ -91,25 +138,130 @@ func (f *Filter) Compile() error {
 			return fmt.Errorf("error compiling 'tagpass', %s", err)
 		}
 	}
+
+	f.metricPass, f.metricPassKeys, err = compileMetricPass(f.MetricPass)
+	if err != nil {
+		return fmt.Errorf("error compiling 'metricpass', %s", err)
+	}
+	f.evalErrors = &errorLimiter{}
 	return nil
 }
 
+// compileMetricPass compiles the metricpass expression into a program, and
+// returns the tags and fields it reads.
+func compileMetricPass(expression string) (cel.Program, []metricPassKey, error) {
+	if expression == "" {
+		return nil, nil, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Declarations(
+			decls.NewVar("name", decls.String),
+			decls.NewVar("tags", decls.NewMapType(decls.String, decls.String)),
+			decls.NewVar("fields", decls.NewMapType(decls.String, decls.Dyn)),
+			decls.NewVar("time", decls.Timestamp),
+		),
+		ext.Strings(),
+		ext.Encoders(),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ast, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, nil, issues.Err()
+	}
+	if !proto.Equal(ast.ResultType(), decls.Bool) {
+		return nil, nil, fmt.Errorf("expression must return a boolean, not %s", cel.FormatType(ast.ResultType()))
+	}
+
+	program, err := env.Program(ast, cel.EvalOptions(cel.OptOptimize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return program, readKeys(ast.Expr(), nil), nil
+}
+
+// readKeys appends the tags and fields read by the expression with a constant
+// key, outside of a has() test.  Reading them fails the evaluation when they
+// are missing from the metric.
+func readKeys(e *exprpb.Expr, keys []metricPassKey) []metricPassKey {
+	switch kind := e.GetExprKind().(type) {
+	case *exprpb.Expr_SelectExpr:
+		sel := kind.SelectExpr
+		if name := metricVariable(sel.GetOperand()); name != "" && !sel.GetTestOnly() {
+			keys = append(keys, metricPassKey{variable: name, key: sel.GetField()})
+		}
+		keys = readKeys(sel.GetOperand(), keys)
+	case *exprpb.Expr_CallExpr:
+		call := kind.CallExpr
+		if call.GetFunction() == operators.Index && len(call.GetArgs()) == 2 {
+			name := metricVariable(call.GetArgs()[0])
+			key, ok := call.GetArgs()[1].GetConstExpr().GetConstantKind().(*exprpb.Constant_StringValue)
+			if name != "" && ok {
+				keys = append(keys, metricPassKey{variable: name, key: key.StringValue})
+			}
+		}
+		if call.GetTarget() != nil {
+			keys = readKeys(call.GetTarget(), keys)
+		}
+		for _, arg := range call.GetArgs() {
+			keys = readKeys(arg, keys)
+		}
+	case *exprpb.Expr_ListExpr:
+		for _, elem := range kind.ListExpr.GetElements() {
+			keys = readKeys(elem, keys)
+		}
+	case *exprpb.Expr_StructExpr:
+		for _, entry := range kind.StructExpr.GetEntries() {
+			if entry.GetMapKey() != nil {
+				keys = readKeys(entry.GetMapKey(), keys)
+			}
+			keys = readKeys(entry.GetValue(), keys)
+		}
+	case *exprpb.Expr_ComprehensionExpr:
+		comp := kind.ComprehensionExpr
+		for _, sub := range []*exprpb.Expr{comp.GetIterRange(), comp.GetAccuInit(),
+			comp.GetLoopCondition(), comp.GetLoopStep(), comp.GetResult()} {
+			keys = readKeys(sub, keys)
+		}
+	}
+	return keys
+}
+
+// metricVariable returns the name of the expression if it is the tags or
+// fields variable, or an empty string otherwise.
+func metricVariable(e *exprpb.Expr) string {
+	if name := e.GetIdentExpr().GetName(); name == "tags" || name == "fields" {
+		return name
+	}
+	return ""
+}
+
 // Select returns true if the metric matches according to the
-// namepass/namedrop and tagpass/tagdrop filters.  The metric is not modified.
-func (f *Filter) Select(metric telegraf.Metric) bool {
+// namepass/namedrop, tagpass/tagdrop and metricpass filters.  The metric is
+// not modified.  A metric for which the metricpass expression can not be
+// evaluated does not match; an error is returned for it, at most once per
+// minute, unless the expression referenced a missing tag or field.
+func (f *Filter) Select(metric telegraf.Metric) (bool, error) {
 	if !f.isActive {
-		return true
+		return true, nil
 	}
 
 	if !f.shouldNamePass(metric.Name()) {
-		return false
+		return false, nil
 	}
 
 	if !f.shouldTagsPass(metric.TagList()) {
-		return false
+		return false, nil
 	}
 
-	return true
+	if f.metricPass != nil {
+		return f.shouldMetricPass(metric)
+	}
+
+	return true, nil
 }
 
 // Modify removes any tags and fields from the metric according to the
@@ -213,6 +365,46 @@ func (f *Filter) shouldTagsPass(tags []*telegraf.Tag) bool {
 	return true
 }
 
+// shouldMetricPass returns the result of the metricpass expression.
+func (f *Filter) shouldMetricPass(metric telegraf.Metric) (bool, error) {
+	out, _, err := f.metricPass.Eval(map[string]interface{}{
+		"name":   metric.Name(),
+		"tags":   metric.Tags(),
+		"fields": metric.Fields(),
+		"time":   metric.Time(),
+	})
+	if err != nil {
+		// A missing tag or field does not match, like with the other filters.
+		if f.missingKey(metric) {
+			return false, nil
+		}
+		if !f.evalErrors.allow(metricPassErrorInterval) {
+			return false, nil
+		}
+		return false, fmt.Errorf("evaluating 'metricpass' failed: %w", err)
+	}
+
+	result, ok := out.(types.Bool)
+	if !ok {
+		return false, errors.New("'metricpass' did not return a boolean")
+	}
+	return bool(result), nil
+}
+
+// missingKey returns true if a tag or field read by the metricpass expression
+// is missing from the metric.
+func (f *Filter) missingKey(metric telegraf.Metric) bool {
+	for _, k := range f.metricPassKeys {
+		if k.variable == "tags" && !metric.HasTag(k.key) {
+			return true
+		}
+		if k.variable == "fields" && !metric.HasField(k.key) {
+			return true
+		}
+	}
+	return false
+}
+
 // filterFields removes fields according to fieldpass/fielddrop.
 func (f *Filter) filterFields(metric telegraf.Metric) {
 	filterKeys := []string{}
@@ -19,7 +19,9 @@ func TestFilter_ApplyEmpty(t *testing.T) {
 		map[string]string{},
 		map[string]interface{}{"value": int64(1)},
 		time.Now())
-	require.True(t, f.Select(m))
+	selected, err := f.Select(m)
+	require.NoError(t, err)
+	require.True(t, selected)
 }
 
 func TestFilter_ApplyTagsDontPass(t *testing.T) {
@@ -40,7 +42,9 @@ func TestFilter_ApplyTagsDontPass(t *testing.T) {
 		map[string]string{"cpu": "cpu-total"},
 		map[string]interface{}{"value": int64(1)},
 		time.Now())
-	require.False(t, f.Select(m))
+	selected, err := f.Select(m)
+	require.NoError(t, err)
+	require.False(t, selected)
 }
 
 func TestFilter_ApplyDeleteFields(t *testing.T) {
@@ -58,7 +62,9 @@ func TestFilter_ApplyDeleteFields(t *testing.T) {
 			"value2": int64(2),
 		},
 		time.Now())
-	require.True(t, f.Select(m))
+	selected, err := f.Select(m)
+	require.NoError(t, err)
+	require.True(t, selected)
 	f.Modify(m)
 	require.Equal(t, map[string]interface{}{"value2": int64(2)}, m.Fields())
 }
@@ -78,11 +84,166 @@ func TestFilter_ApplyDeleteAllFields(t *testing.T) {
 			"value2": int64(2),
 		},
 		time.Now())
-	require.True(t, f.Select(m))
+	selected, err := f.Select(m)
+	require.NoError(t, err)
+	require.True(t, selected)
 	f.Modify(m)
 	require.Len(t, m.FieldList(), 0)
 }
 
+func TestFilter_MetricPass(t *testing.T) {
+	m := metric.New("cpu",
+		map[string]string{
+			"host":   "ci-runner-1",
+			"status": "ok",
+		},
+		map[string]interface{}{
+			"usage_idle":   float64(97.5),
+			"usage_system": int64(3),
+			"state":        "running",
+		},
+		time.Unix(1600000000, 0),
+	)
+
+	tests := []struct {
+		name       string
+		expression string
+		expected   bool
+	}{
+		{
+			name:       "name match",
+			expression: `name == "cpu"`,
+			expected:   true,
+		},
+		{
+			name:       "name mismatch",
+			expression: `name.startsWith("mem")`,
+			expected:   false,
+		},
+		{
+			name:       "field and tag",
+			expression: `fields.usage_idle > 95.0 && tags.host.startsWith("ci-")`,
+			expected:   true,
+		},
+		{
+			name:       "integer field",
+			expression: `fields.usage_system >= 5`,
+			expected:   false,
+		},
+		{
+			name:       "missing tag",
+			expression: `has(tags.region) && tags.region == "eu"`,
+			expected:   false,
+		},
+		{
+			name:       "string field",
+			expression: `fields.state in ["running", "sleeping"]`,
+			expected:   true,
+		},
+		{
+			name:       "time",
+			expression: `time < timestamp("2021-01-01T00:00:00Z")`,
+			expected:   true,
+		},
+		{
+			name:       "string extension",
+			expression: `tags.status.upperAscii() == "OK"`,
+			expected:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := Filter{MetricPass: tt.expression}
+			require.NoError(t, f.Compile())
+			require.True(t, f.IsActive())
+
+			selected, err := f.Select(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, selected)
+		})
+	}
+}
+
+func TestFilter_MetricPassCombined(t *testing.T) {
+	f := Filter{
+		NamePass:   []string{"cpu"},
+		MetricPass: `fields.value > 10`,
+	}
+	require.NoError(t, f.Compile())
+
+	m := metric.New("mem", map[string]string{}, map[string]interface{}{"value": int64(42)}, time.Now())
+	selected, err := f.Select(m)
+	require.NoError(t, err)
+	require.False(t, selected)
+
+	m = metric.New("cpu", map[string]string{}, map[string]interface{}{"value": int64(42)}, time.Now())
+	selected, err = f.Select(m)
+	require.NoError(t, err)
+	require.True(t, selected)
+}
+
+func TestFilter_MetricPassInvalid(t *testing.T) {
+	f := Filter{MetricPass: `fields.value >`}
+	require.Error(t, f.Compile())
+
+	f = Filter{MetricPass: `name + "suffix"`}
+	err := f.Compile()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must return a boolean")
+
+	f = Filter{MetricPass: `unknown == 1`}
+	require.Error(t, f.Compile())
+}
+
+func TestFilter_MetricPassMissingKey(t *testing.T) {
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": int64(42)}, time.Now())
+
+	for _, expression := range []string{
+		`fields.missing > 1 || tags.missing == "a"`,
+		`fields["missing"] > 1`,
+		`tags.missing.startsWith("a")`,
+	} {
+		f := Filter{MetricPass: expression}
+		require.NoError(t, f.Compile())
+		ok, err := f.Select(m)
+		require.NoError(t, err, expression)
+		require.False(t, ok, expression)
+	}
+
+	// Only testing for a missing key does not hide other errors
+	f := Filter{MetricPass: `has(tags.missing) || fields.value > 1.0`}
+	require.NoError(t, f.Compile())
+	ok, err := f.Select(m)
+	require.Error(t, err)
+	require.False(t, ok)
+}
+
+func TestFilter_MetricPassKeys(t *testing.T) {
+	f := Filter{MetricPass: `has(tags.a) && tags.b == "x" && fields["c"] > 1 && [1].exists(i, fields.d == i)`}
+	require.NoError(t, f.Compile())
+	require.ElementsMatch(t, []metricPassKey{
+		{variable: "tags", key: "b"},
+		{variable: "fields", key: "c"},
+		{variable: "fields", key: "d"},
+	}, f.metricPassKeys)
+}
+
+func TestFilter_MetricPassEvaluationError(t *testing.T) {
+	f := Filter{MetricPass: `fields.value > 1`}
+	require.NoError(t, f.Compile())
+
+	m := metric.New("cpu", map[string]string{}, map[string]interface{}{"value": "high"}, time.Now())
+	ok, err := f.Select(m)
+	require.Error(t, err)
+	require.False(t, ok)
+
+	// Errors are only reported once per interval
+	ok, err = f.Select(m)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
 func TestFilter_Empty(t *testing.T) {
 	f := Filter{}
 
@@ -508,7 +669,7 @@ func BenchmarkFilter(b *testing.B) {
 		b.Run(tt.name, func(b *testing.B) {
 			require.NoError(b, tt.filter.Compile())
 			for n := 0; n < b.N; n++ {
-				tt.filter.Select(tt.metric)
+				_, _ = tt.filter.Select(tt.metric)
 			}
 		})
 	}
@@ -1,8 +1,11 @@
 package models
 
 import (
+	"fmt"
 	"log"
 	"reflect"
+	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 )
@@ -11,6 +14,10 @@ import (
 type Logger struct {
 	OnErrs []func()
 	Name   string // Name is the plugin name, will be printed in the `[]`.
+
+	mu            sync.Mutex
+	lastError     string
+	lastErrorTime time.Time
 }
 
 // NewLogger creates a new logger instance
@@ -25,11 +32,26 @@ func (l *Logger) OnErr(f func()) {
 	l.OnErrs = append(l.OnErrs, f)
 }
 
+// LastError returns the most recent error message and the time it was logged.
+func (l *Logger) LastError() (string, time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lastError, l.lastErrorTime
+}
+
+func (l *Logger) setLastError(msg string) {
+	l.mu.Lock()
+	l.lastError = msg
+	l.lastErrorTime = time.Now()
+	l.mu.Unlock()
+}
+
 // Errorf logs an error message, patterned after log.Printf.
 func (l *Logger) Errorf(format string, args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
+	l.setLastError(fmt.Sprintf(format, args...))
 	log.Printf("E! ["+l.Name+"] "+format, args...)
 }
 
@@ -38,6 +60,7 @@ func (l *Logger) Error(args ...interface{}) {
 	for _, f := range l.OnErrs {
 		f()
 	}
+	l.setLastError(fmt.Sprint(args...))
 	log.Print(append([]interface{}{"E! [" + l.Name + "] "}, args...)...)
 }
 
@@ -79,6 +102,14 @@ func logName(pluginType, name, alias string) string {
 	return pluginType + "." + name + "::" + alias
 }
 
+// lastError returns the most recent error logged by the plugin logger.
+func lastError(l telegraf.Logger) (string, time.Time) {
+	if logger, ok := l.(*Logger); ok {
+		return logger.LastError()
+	}
+	return "", time.Time{}
+}
+
 func SetLoggerOnPlugin(i interface{}, log telegraf.Logger) {
 	valI := reflect.ValueOf(i)
 
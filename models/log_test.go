@@ -22,3 +22,19 @@ func TestErrorCounting(t *testing.T) {
 
 	require.Equal(t, int64(2), reg.Get())
 }
+
+func TestLastError(t *testing.T) {
+	iLog := Logger{Name: "inputs.test"}
+	msg, ts := iLog.LastError()
+	require.Empty(t, msg)
+	require.True(t, ts.IsZero())
+
+	iLog.Errorf("gather failed: %s", "timeout")
+	msg, ts = iLog.LastError()
+	require.Equal(t, "gather failed: timeout", msg)
+	require.False(t, ts.IsZero())
+
+	iLog.Error("connection refused")
+	msg, _ = iLog.LastError()
+	require.Equal(t, "connection refused", msg)
+}
@@ -0,0 +1,12 @@
+package models
+
+import "sync/atomic"
+
+var lastPluginID uint64
+
+// newPluginID returns an identifier unique to the plugin instance within the
+// process.  Unlike the position of the plugin in the configuration it does not
+// change when other plugins are added or removed by a reload.
+func newPluginID() uint64 {
+	return atomic.AddUint64(&lastPluginID, 1)
+}
@@ -11,6 +11,7 @@ import (
 
 type RunningAggregator struct {
 	sync.Mutex
+	ID          uint64
 	Aggregator  telegraf.Aggregator
 	Config      *AggregatorConfig
 	periodStart time.Time
@@ -38,6 +39,7 @@ func NewRunningAggregator(aggregator telegraf.Aggregator, config *AggregatorConf
 	SetLoggerOnPlugin(aggregator, logger)
 
 	return &RunningAggregator{
+		ID:         newPluginID(),
 		Aggregator: aggregator,
 		Config:     config,
 		MetricsPushed: selfstat.Register(
@@ -125,7 +127,11 @@ func (r *RunningAggregator) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 // Add a metric to the aggregator and return true if the original metric
 // should be dropped.
 func (r *RunningAggregator) Add(m telegraf.Metric) bool {
-	if ok := r.Config.Filter.Select(m); !ok {
+	ok, err := r.Config.Filter.Select(m)
+	if err != nil {
+		r.log.Debugf("filtering failed: %v", err)
+	}
+	if !ok {
 		return false
 	}
 
@@ -177,3 +183,8 @@ func (r *RunningAggregator) push(acc telegraf.Accumulator) {
 func (r *RunningAggregator) Log() telegraf.Logger {
 	return r.log
 }
+
+// LastError returns the most recent error logged by the aggregator.
+func (r *RunningAggregator) LastError() (string, time.Time) {
+	return lastError(r.log)
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -13,6 +14,7 @@ var (
 )
 
 type RunningInput struct {
+	ID     uint64
 	Input  telegraf.Input
 	Config *InputConfig
 
@@ -21,6 +23,10 @@ type RunningInput struct {
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+
+	gatherMu       sync.Mutex
+	lastGather     time.Time
+	lastGatherTime time.Duration
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -38,6 +44,7 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 	SetLoggerOnPlugin(input, logger)
 
 	return &RunningInput{
+		ID:     newPluginID(),
 		Input:  input,
 		Config: config,
 		MetricsGathered: selfstat.Register(
@@ -88,7 +95,11 @@ func (r *RunningInput) Init() error {
 }
 
 func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
-	if ok := r.Config.Filter.Select(metric); !ok {
+	ok, err := r.Config.Filter.Select(metric)
+	if err != nil {
+		r.log.Debugf("filtering failed: %v", err)
+	}
+	if !ok {
 		r.metricFiltered(metric)
 		return nil
 	}
@@ -117,9 +128,26 @@ func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
 	err := r.Input.Gather(acc)
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
+
+	r.gatherMu.Lock()
+	r.lastGather = start
+	r.lastGatherTime = elapsed
+	r.gatherMu.Unlock()
 	return err
 }
 
+// LastGather returns the start time and duration of the most recent gather.
+func (r *RunningInput) LastGather() (time.Time, time.Duration) {
+	r.gatherMu.Lock()
+	defer r.gatherMu.Unlock()
+	return r.lastGather, r.lastGatherTime
+}
+
+// LastError returns the most recent error logged by the input.
+func (r *RunningInput) LastError() (string, time.Time) {
+	return lastError(r.log)
+}
+
 func (r *RunningInput) SetDefaultTags(tags map[string]string) {
 	r.defaultTags = tags
 }
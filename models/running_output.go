@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -28,17 +29,47 @@ type OutputConfig struct {
 	MetricBufferLimit int
 	MetricBatchSize   int
 
+	// MetricBatchBytes limits the size of batches in bytes, as estimated
+	// with the SizeSerializer, if greater than zero.  The SizeSerializer must
+	// not be the one the output writes with, as serializing may change its
+	// state.  Batches are not split without a SizeSerializer.
+	MetricBatchBytes int64
+	SizeSerializer   MetricSerializer
+
+	BufferStrategy  string
+	BufferDirectory string
+	BufferMaxSize   int64
+	BufferMaxAge    time.Duration
+
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
 }
 
+// MetricSerializer serializes a single metric.
+type MetricSerializer interface {
+	Serialize(metric telegraf.Metric) ([]byte, error)
+}
+
+// metricBuffer is the contract between RunningOutput and the buffer holding
+// the metrics not yet written.  Accept may be called with only the first
+// metrics of a batch, followed by Reject with the remaining ones.
+type metricBuffer interface {
+	Len() int
+	Add(metrics ...telegraf.Metric) int
+	Batch(batchSize int) []telegraf.Metric
+	Accept(batch []telegraf.Metric)
+	Reject(batch []telegraf.Metric)
+	Close() error
+}
+
 // RunningOutput contains the output configuration
 type RunningOutput struct {
 	// Must be 64-bit aligned
 	newMetricsCount int64
 	droppedMetrics  int64
 
+	ID                uint64
 	Output            telegraf.Output
 	Config            *OutputConfig
 	MetricBufferLimit int
@@ -47,9 +78,10 @@ type RunningOutput struct {
 	MetricsFiltered selfstat.Stat
 	WriteTime       selfstat.Stat
 
-	BatchReady chan time.Time
+	BatchReady     chan time.Time
+	FlushRequested chan time.Time
 
-	buffer *Buffer
+	buffer metricBuffer
 	log    telegraf.Logger
 
 	aggMutex sync.Mutex
@@ -85,10 +117,11 @@ func NewRunningOutput(
 	if batchSize == 0 {
 		batchSize = DefaultMetricBatchSize
 	}
-
 	ro := &RunningOutput{
 		buffer:            NewBuffer(config.Name, config.Alias, bufferLimit),
 		BatchReady:        make(chan time.Time, 1),
+		FlushRequested:    make(chan time.Time, 1),
+		ID:                newPluginID(),
 		Output:            output,
 		Config:            config,
 		MetricBufferLimit: bufferLimit,
@@ -125,6 +158,34 @@ func (r *RunningOutput) Init() error {
 			return err
 		}
 	}
+
+	switch r.Config.BufferStrategy {
+	case "", BufferStrategyMemory, BufferStrategyDisk:
+	default:
+		return fmt.Errorf("unknown buffer strategy %q", r.Config.BufferStrategy)
+	}
+	return nil
+}
+
+// OpenBuffer opens the disk buffer of the output, replaying the metrics left
+// from a previous run, if the output uses one.  It is separate from Init so
+// that outputs can be initialized without touching their buffer, and must be
+// called before the output is started.
+func (r *RunningOutput) OpenBuffer() error {
+	if r.Config.BufferStrategy != BufferStrategyDisk {
+		return nil
+	}
+
+	buffer, err := NewDiskBuffer(r.Config.Name, r.Config.Alias, DiskBufferConfig{
+		Directory: r.Config.BufferDirectory,
+		Capacity:  r.MetricBufferLimit,
+		MaxSize:   r.Config.BufferMaxSize,
+		MaxAge:    r.Config.BufferMaxAge,
+	}, r.log)
+	if err != nil {
+		return fmt.Errorf("opening disk buffer: %w", err)
+	}
+	r.buffer = buffer
 	return nil
 }
 
@@ -132,7 +193,11 @@ func (r *RunningOutput) Init() error {
 //
 // Takes ownership of metric
 func (r *RunningOutput) AddMetric(metric telegraf.Metric) {
-	if ok := r.Config.Filter.Select(metric); !ok {
+	ok, err := r.Config.Filter.Select(metric)
+	if err != nil {
+		r.log.Debugf("filtering failed: %v", err)
+	}
+	if !ok {
 		r.metricFiltered(metric)
 		return
 	}
@@ -198,12 +263,10 @@ func (r *RunningOutput) Write() error {
 			break
 		}
 
-		err := r.write(batch)
+		err := r.writeBatch(batch)
 		if err != nil {
-			r.buffer.Reject(batch)
 			return err
 		}
-		r.buffer.Accept(batch)
 	}
 	return nil
 }
@@ -215,14 +278,7 @@ func (r *RunningOutput) WriteBatch() error {
 		return nil
 	}
 
-	err := r.write(batch)
-	if err != nil {
-		r.buffer.Reject(batch)
-		return err
-	}
-	r.buffer.Accept(batch)
-
-	return nil
+	return r.writeBatch(batch)
 }
 
 // Close closes the output
@@ -231,6 +287,72 @@ func (r *RunningOutput) Close() {
 	if err != nil {
 		r.log.Errorf("Error closing output: %v", err)
 	}
+
+	r.CloseBuffer()
+}
+
+// CloseBuffer closes the buffer of the output, releasing the directory of a
+// disk buffer.
+func (r *RunningOutput) CloseBuffer() {
+	if err := r.buffer.Close(); err != nil {
+		r.log.Errorf("Error closing buffer: %v", err)
+	}
+}
+
+// writeBatch writes the batch acquired from the buffer in parts not exceeding
+// the maximum batch size in bytes.  If writing a part fails, the parts written
+// before are accepted while the failed part and the parts after it are
+// returned to the buffer, without trying the later parts.
+//
+// Writing stops at the first failed part so that the metrics are written in
+// order: the failed part is retried before the metrics following it, and the
+// buffers only accept the start of a batch, the disk buffer acknowledging its
+// records in order.
+func (r *RunningOutput) writeBatch(batch []telegraf.Metric) error {
+	var written int
+	for _, part := range r.splitBatch(batch) {
+		err := r.write(part)
+		if err != nil {
+			if written > 0 {
+				r.buffer.Accept(batch[:written])
+			}
+			r.buffer.Reject(batch[written:])
+			return err
+		}
+		written += len(part)
+	}
+	r.buffer.Accept(batch)
+	return nil
+}
+
+// splitBatch splits the batch into parts not exceeding the maximum batch size
+// in bytes.  A metric exceeding the size on its own forms a part by itself.
+func (r *RunningOutput) splitBatch(batch []telegraf.Metric) [][]telegraf.Metric {
+	if r.Config.MetricBatchBytes <= 0 || r.Config.SizeSerializer == nil {
+		return [][]telegraf.Metric{batch}
+	}
+
+	var parts [][]telegraf.Metric
+	var start int
+	var size int64
+	for i, m := range batch {
+		octets, err := r.Config.SizeSerializer.Serialize(m)
+		if err != nil {
+			r.log.Debugf("Could not serialize metric to estimate its size: %v", err)
+		}
+		n := int64(len(octets))
+		if n > r.Config.MetricBatchBytes {
+			r.log.Warnf("Metric of %d bytes exceeds the batch size of %d bytes", n, r.Config.MetricBatchBytes)
+		}
+
+		if i > start && size+n > r.Config.MetricBatchBytes {
+			parts = append(parts, batch[start:i])
+			start = i
+			size = 0
+		}
+		size += n
+	}
+	return append(parts, batch[start:])
 }
 
 func (r *RunningOutput) write(metrics []telegraf.Metric) error {
@@ -263,3 +385,16 @@ func (r *RunningOutput) Log() telegraf.Logger {
 func (r *RunningOutput) BufferLength() int {
 	return r.buffer.Len()
 }
+
+// RequestFlush asks the agent to flush the output as soon as possible.
+func (r *RunningOutput) RequestFlush() {
+	select {
+	case r.FlushRequested <- time.Now():
+	default:
+	}
+}
+
+// LastError returns the most recent error logged by the output.
+func (r *RunningOutput) LastError() (string, time.Time) {
+	return lastError(r.log)
+}
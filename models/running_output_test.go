@@ -460,6 +460,89 @@ func TestRunningOutputWriteFailOrder3(t *testing.T) {
 	assert.Equal(t, expected, m.Metrics())
 }
 
+func TestRunningOutputBatchBytes(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		MetricBatchBytes: 20,
+		SizeSerializer:   sizeSerializer(10),
+	}
+
+	m := &batchOutput{}
+	ro := NewRunningOutput(m, conf, 5, 1000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	require.NoError(t, ro.Write())
+	require.Equal(t, [][]telegraf.Metric{
+		{first5[0], first5[1]},
+		{first5[2], first5[3]},
+		{first5[4]},
+	}, m.batches)
+	require.Equal(t, 0, ro.BufferLength())
+}
+
+func TestRunningOutputBatchBytesPartialFailure(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		MetricBatchBytes: 20,
+		SizeSerializer:   sizeSerializer(10),
+	}
+
+	m := &batchOutput{failWrites: map[int]bool{2: true}}
+	ro := NewRunningOutput(m, conf, 5, 1000)
+	for _, metric := range first5 {
+		ro.AddMetric(metric)
+	}
+
+	// The first part is accepted, the failed part and the parts after it are
+	// kept in the buffer.  The last part, which would be written, is not
+	// tried so that it is not written before the failed part.
+	require.Error(t, ro.WriteBatch())
+	require.Equal(t, [][]telegraf.Metric{{first5[0], first5[1]}}, m.batches)
+	require.Equal(t, 2, m.writes)
+	require.Equal(t, 3, ro.BufferLength())
+
+	require.NoError(t, ro.Write())
+	require.Equal(t, [][]telegraf.Metric{
+		{first5[0], first5[1]},
+		{first5[2], first5[3]},
+		{first5[4]},
+	}, m.batches)
+	require.Equal(t, 0, ro.BufferLength())
+}
+
+func TestRunningOutputBatchBytesOversizedMetric(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		MetricBatchBytes: 1,
+		SizeSerializer:   sizeSerializer(10),
+	}
+
+	m := &batchOutput{}
+	ro := NewRunningOutput(m, conf, 5, 1000)
+	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[1])
+
+	require.NoError(t, ro.Write())
+	require.Equal(t, [][]telegraf.Metric{{first5[0]}, {first5[1]}}, m.batches)
+}
+
+func TestRunningOutputBatchBytesWithoutSerializer(t *testing.T) {
+	conf := &OutputConfig{
+		Filter:           Filter{},
+		MetricBatchBytes: 1,
+	}
+
+	m := &batchOutput{}
+	ro := NewRunningOutput(m, conf, 5, 1000)
+	ro.AddMetric(first5[0])
+	ro.AddMetric(first5[1])
+
+	require.NoError(t, ro.Write())
+	require.Equal(t, [][]telegraf.Metric{{first5[0], first5[1]}}, m.batches)
+}
+
 func TestInternalMetrics(t *testing.T) {
 	_ = NewRunningOutput(
 		&mockOutput{},
@@ -549,6 +632,34 @@ func (m *mockOutput) Metrics() []telegraf.Metric {
 	return m.metrics
 }
 
+// batchOutput records the written batches and fails the writes given by
+// their number.
+type batchOutput struct {
+	batches    [][]telegraf.Metric
+	writes     int
+	failWrites map[int]bool
+}
+
+func (m *batchOutput) Connect() error       { return nil }
+func (m *batchOutput) Close() error         { return nil }
+func (m *batchOutput) Description() string  { return "" }
+func (m *batchOutput) SampleConfig() string { return "" }
+func (m *batchOutput) Write(metrics []telegraf.Metric) error {
+	m.writes++
+	if m.failWrites[m.writes] {
+		return fmt.Errorf("failed write")
+	}
+	m.batches = append(m.batches, metrics)
+	return nil
+}
+
+// sizeSerializer serializes every metric to the same number of bytes.
+type sizeSerializer int
+
+func (s sizeSerializer) Serialize(_ telegraf.Metric) ([]byte, error) {
+	return make([]byte, s), nil
+}
+
 type perfOutput struct {
 	// if true, mock a write failure
 	failWrite bool
@@ -2,6 +2,7 @@ package models
 
 import (
 	"sync"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/selfstat"
@@ -9,6 +10,7 @@ import (
 
 type RunningProcessor struct {
 	sync.Mutex
+	ID        uint64
 	log       telegraf.Logger
 	Processor telegraf.StreamingProcessor
 	Config    *ProcessorConfig
@@ -42,6 +44,7 @@ func NewRunningProcessor(processor telegraf.StreamingProcessor, config *Processo
 	SetLoggerOnPlugin(processor, logger)
 
 	return &RunningProcessor{
+		ID:        newPluginID(),
 		Processor: processor,
 		Config:    config,
 		log:       logger,
@@ -79,7 +82,11 @@ func (rp *RunningProcessor) Start(acc telegraf.Accumulator) error {
 }
 
 func (rp *RunningProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
-	if ok := rp.Config.Filter.Select(m); !ok {
+	ok, err := rp.Config.Filter.Select(m)
+	if err != nil {
+		rp.log.Debugf("filtering failed: %v", err)
+	}
+	if !ok {
 		// pass downstream
 		acc.AddMetric(m)
 		return nil
@@ -98,3 +105,8 @@ func (rp *RunningProcessor) Add(m telegraf.Metric, acc telegraf.Accumulator) err
 func (rp *RunningProcessor) Stop() {
 	rp.Processor.Stop()
 }
+
+// LastError returns the most recent error logged by the processor.
+func (rp *RunningProcessor) LastError() (string, time.Time) {
+	return lastError(rp.log)
+}
@@ -0,0 +1,220 @@
+package starlark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newStarlark(source, script string) *Starlark {
+	return &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source:           source,
+			Script:           script,
+			Log:              testutil.Logger{},
+			StarlarkLoadFunc: common.LoadFunc,
+		},
+	}
+}
+
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name:   "no source",
+			source: "",
+		},
+		{
+			name: "add must be defined",
+			source: `
+def push():
+  return None
+
+def reset():
+  pass
+`,
+		},
+		{
+			name: "push must be a function",
+			source: `
+push = 42
+
+def add(metric):
+  pass
+
+def reset():
+  pass
+`,
+		},
+		{
+			name: "add function must take one arg",
+			source: `
+def add():
+  pass
+
+def push():
+  return None
+
+def reset():
+  pass
+`,
+		},
+		{
+			name: "reset function must take no arg",
+			source: `
+def add(metric):
+  pass
+
+def push():
+  return None
+
+def reset(metric):
+  pass
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, newStarlark(tt.source, "").Init())
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	plugin := newStarlark("", "testdata/weighted_average.star")
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(0, 0)
+	plugin.Add(testutil.MustMetric("sensor", map[string]string{"zone": "a"},
+		map[string]interface{}{"value": 10.0, "weight": 1.0}, now))
+	plugin.Add(testutil.MustMetric("sensor", map[string]string{"zone": "a"},
+		map[string]interface{}{"value": 20.0, "weight": 3.0}, now.Add(time.Second)))
+	plugin.Add(testutil.MustMetric("sensor", map[string]string{"zone": "b"},
+		map[string]interface{}{"value": 5.0, "weight": 2.0}, now.Add(time.Second)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("sensor", map[string]string{"zone": "a"},
+			map[string]interface{}{"value": 17.5}, now.Add(time.Second)),
+		testutil.MustMetric("sensor", map[string]string{"zone": "b"},
+			map[string]interface{}{"value": 5.0}, now.Add(time.Second)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.SortMetrics())
+
+	plugin.Reset()
+	acc.ClearMetrics()
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+func TestMinMax(t *testing.T) {
+	plugin := newStarlark("", "testdata/min_max.star")
+	require.NoError(t, plugin.Init())
+
+	now := time.Unix(0, 0)
+	plugin.Add(testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 90.0, "usage_user": 5.0, "state": "ok"}, now))
+	plugin.Add(testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+		map[string]interface{}{"usage_idle": 80.0, "usage_user": 15.0}, now.Add(time.Second)))
+
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{
+				"usage_idle_min": 80.0,
+				"usage_idle_max": 90.0,
+				"usage_user_min": 5.0,
+				"usage_user_max": 15.0,
+			}, now),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestStatePersistsAcrossPeriods(t *testing.T) {
+	plugin := newStarlark(`
+state = {"count": 0}
+
+def add(metric):
+  state["count"] += 1
+
+def push():
+  m = Metric("count")
+  m.fields["value"] = state["count"]
+  return [m]
+
+def reset():
+  pass
+`, "")
+	require.NoError(t, plugin.Init())
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+
+	var acc testutil.Accumulator
+	plugin.Add(m)
+	plugin.Push(&acc)
+	plugin.Reset()
+	plugin.Add(m)
+	plugin.Add(m)
+	plugin.Push(&acc)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(1), metrics[0].Fields()["value"])
+	require.Equal(t, int64(3), metrics[1].Fields()["value"])
+}
+
+func TestPushedMetricIsCopied(t *testing.T) {
+	plugin := newStarlark(`
+state = {}
+
+def add(metric):
+  state["last"] = metric
+
+def push():
+  return state.get("last")
+
+def reset():
+  pass
+`, "")
+	require.NoError(t, plugin.Init())
+
+	plugin.Add(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	plugin.Add(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(0, 0)))
+	plugin.Push(&acc)
+
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 2)
+	require.Equal(t, int64(1), metrics[0].Fields()["value"])
+	require.Equal(t, int64(2), metrics[1].Fields()["value"])
+}
+
+func TestErrorsAreLogged(t *testing.T) {
+	plugin := newStarlark(`
+def add(metric):
+  fail("add failed")
+
+def push():
+  return 42
+
+def reset():
+  pass
+`, "")
+	require.NoError(t, plugin.Init())
+
+	plugin.Add(testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0)))
+	var acc testutil.Accumulator
+	plugin.Push(&acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
@@ -0,0 +1,81 @@
+// Package framing implements the framing of the messages sent over stream
+// sockets, and their acknowledgement.
+//
+// A length-prefixed frame is made of the length of its payload, as a 4 bytes
+// big-endian unsigned integer, followed by the payload.  When acknowledged,
+// the receiver answers each frame with an Ack byte once it is processed, or a
+// Nak byte if the payload is invalid.
+package framing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// None sends the messages as a plain stream.
+	None = "none"
+	// LengthPrefixed prefixes each message with its length.
+	LengthPrefixed = "length_prefixed"
+)
+
+const (
+	// Ack acknowledges a processed frame.
+	Ack byte = 0x06
+	// Nak rejects a frame with an invalid payload.
+	Nak byte = 0x15
+)
+
+const headerSize = 4
+
+// ErrFrameTooLarge is returned when reading a frame larger than allowed.
+var ErrFrameTooLarge = errors.New("frame too large")
+
+// Validate checks the name of a framing, the empty name being None.
+func Validate(framing string) error {
+	switch framing {
+	case "", None, LengthPrefixed:
+		return nil
+	}
+	return fmt.Errorf("invalid framing %q", framing)
+}
+
+// WriteFrame writes a payload as a length-prefixed frame, with a single write
+// so that frames are not interleaved.
+func WriteFrame(w io.Writer, payload []byte) error {
+	if uint64(len(payload)) > uint64(^uint32(0)) {
+		return ErrFrameTooLarge
+	}
+
+	frame := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(len(payload)))
+	copy(frame[headerSize:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// ReadFrame reads the payload of a length-prefixed frame of at most maxSize
+// bytes.  It returns io.EOF if the stream ends before the frame, and
+// io.ErrUnexpectedEOF if it ends within the frame.
+func ReadFrame(r io.Reader, maxSize int) ([]byte, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if uint64(size) > uint64(maxSize) {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, size)
+	}
+
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return payload, nil
+}
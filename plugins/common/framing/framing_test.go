@@ -0,0 +1,50 @@
+package framing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteReadFrame(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, []byte("cpu value=42 1622745000000000000\n")))
+	require.NoError(t, WriteFrame(&buf, []byte{}))
+	require.Equal(t, []byte{0, 0, 0, 33}, buf.Bytes()[:4])
+
+	payload, err := ReadFrame(&buf, 1024)
+	require.NoError(t, err)
+	require.Equal(t, "cpu value=42 1622745000000000000\n", string(payload))
+
+	payload, err = ReadFrame(&buf, 1024)
+	require.NoError(t, err)
+	require.Empty(t, payload)
+
+	_, err = ReadFrame(&buf, 1024)
+	require.Equal(t, io.EOF, err)
+}
+
+func TestReadFrameErrors(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, []byte("howdy")))
+	_, err := ReadFrame(&buf, 4)
+	require.True(t, errors.Is(err, ErrFrameTooLarge))
+
+	// Truncated payload
+	_, err = ReadFrame(bytes.NewReader([]byte{0, 0, 0, 5, 'h', 'o'}), 1024)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+
+	// Truncated header
+	_, err = ReadFrame(bytes.NewReader([]byte{0, 0}), 1024)
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(""))
+	require.NoError(t, Validate(None))
+	require.NoError(t, Validate(LengthPrefixed))
+	require.Error(t, Validate("newline"))
+}
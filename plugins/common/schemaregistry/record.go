@@ -0,0 +1,129 @@
+package schemaregistry
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// RecordConfig maps the decoded records of the schema registry formats to
+// metrics.  The nested values of a record are flattened, joining their keys
+// with the field separator, and the flattened values are the fields of the
+// metric except the ones used for the measurement, tags and timestamp.
+type RecordConfig struct {
+	// MetricName is the measurement of the metrics without measurement
+	// field.
+	MetricName string
+	// MeasurementField is the flattened key of the measurement.
+	MeasurementField string
+	// Tags are the flattened keys of the tags.
+	Tags []string
+	// Timestamp is the flattened key of the timestamp, the metrics are
+	// timestamped with the current time when not set.
+	Timestamp string
+	// TimestampFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a Go time
+	// layout, used when the timestamp is not a time.  Defaults to "unix".
+	TimestampFormat string
+	// FieldSeparator joins the keys of nested values, defaults to "_".
+	FieldSeparator string
+	// DefaultTags are added to all the metrics.
+	DefaultTags map[string]string
+}
+
+// Metric creates the metric of a record.  The values of the record are maps,
+// slices, nil, or values of the types int64, uint64, float64, bool, string and
+// time.Time.
+func (c *RecordConfig) Metric(record map[string]interface{}) (telegraf.Metric, error) {
+	separator := c.FieldSeparator
+	if separator == "" {
+		separator = "_"
+	}
+
+	fields := make(map[string]interface{})
+	flatten(fields, "", separator, record)
+
+	name := c.MetricName
+	if c.MeasurementField != "" {
+		if value, ok := fields[c.MeasurementField]; ok {
+			name = toString(value)
+			delete(fields, c.MeasurementField)
+		}
+	}
+
+	tags := make(map[string]string, len(c.DefaultTags)+len(c.Tags))
+	for k, v := range c.DefaultTags {
+		tags[k] = v
+	}
+	for _, key := range c.Tags {
+		if value, ok := fields[key]; ok {
+			tags[key] = toString(value)
+			delete(fields, key)
+		}
+	}
+
+	tm := time.Now()
+	if c.Timestamp != "" {
+		value, ok := fields[c.Timestamp]
+		if !ok {
+			return nil, fmt.Errorf("timestamp %q not found", c.Timestamp)
+		}
+		delete(fields, c.Timestamp)
+
+		if t, ok := value.(time.Time); ok {
+			tm = t
+		} else {
+			format := c.TimestampFormat
+			if format == "" {
+				format = "unix"
+			}
+
+			var err error
+			tm, err = internal.ParseTimestamp(format, value, "")
+			if err != nil {
+				return nil, fmt.Errorf("parsing timestamp %q failed: %v", c.Timestamp, err)
+			}
+		}
+	}
+
+	for key, value := range fields {
+		if t, ok := value.(time.Time); ok {
+			fields[key] = t.UnixNano()
+		}
+	}
+
+	return metric.New(name, tags, fields, tm), nil
+}
+
+func flatten(fields map[string]interface{}, prefix, separator string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, item := range v {
+			flatten(fields, join(prefix, key, separator), separator, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			flatten(fields, join(prefix, strconv.Itoa(i), separator), separator, item)
+		}
+	default:
+		fields[prefix] = v
+	}
+}
+
+func join(prefix, key, separator string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + separator + key
+}
+
+func toString(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
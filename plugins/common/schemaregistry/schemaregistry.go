@@ -0,0 +1,183 @@
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// magicByte starts the messages of the Confluent wire format, followed
+	// by the schema ID as a 4 bytes big-endian integer.
+	magicByte  = 0
+	headerSize = 5
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Reference is a schema referenced by another schema, such as an imported
+// Protobuf file.
+type Reference struct {
+	Name    string `json:"name"`
+	Subject string `json:"subject"`
+	Version int    `json:"version"`
+}
+
+// Schema is a schema of the registry.
+type Schema struct {
+	// Type is "AVRO", "PROTOBUF" or "JSON".
+	Type       string      `json:"schemaType,omitempty"`
+	Schema     string      `json:"schema"`
+	References []Reference `json:"references,omitempty"`
+}
+
+type errorResponse struct {
+	ErrorCode int    `json:"error_code"`
+	Message   string `json:"message"`
+}
+
+// Client fetches schemas from a Confluent compatible schema registry and
+// caches them, as the schemas of an ID or of a subject version never change.
+// Credentials can be given in the user info of the URL.
+type Client struct {
+	url    string
+	client *http.Client
+
+	sync.Mutex
+	schemas map[string]*Schema
+}
+
+func NewClient(registryURL string) (*Client, error) {
+	u, err := url.Parse(registryURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema registry URL: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid schema registry URL %q: scheme must be http or https", registryURL)
+	}
+
+	return &Client{
+		url:     strings.TrimSuffix(registryURL, "/"),
+		client:  &http.Client{Timeout: defaultTimeout},
+		schemas: make(map[string]*Schema),
+	}, nil
+}
+
+// SchemaByID returns the schema with the given ID.
+func (c *Client) SchemaByID(id int) (*Schema, error) {
+	return c.schema(fmt.Sprintf("/schemas/ids/%d", id))
+}
+
+// SchemaBySubject returns the given version of the schema of a subject, as
+// needed to resolve the references of a schema.
+func (c *Client) SchemaBySubject(subject string, version int) (*Schema, error) {
+	return c.schema(fmt.Sprintf("/subjects/%s/versions/%d", url.PathEscape(subject), version))
+}
+
+func (c *Client) schema(path string) (*Schema, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if schema, ok := c.schemas[path]; ok {
+		return schema, nil
+	}
+
+	schema := &Schema{}
+	if err := c.do(http.MethodGet, path, nil, schema); err != nil {
+		return nil, err
+	}
+	if schema.Type == "" {
+		schema.Type = "AVRO"
+	}
+
+	c.schemas[path] = schema
+	return schema, nil
+}
+
+// Register registers a schema under a subject and returns its ID.  The
+// registry returns the ID of the existing schema if it was already
+// registered, so registering the same schema again is harmless.
+func (c *Client) Register(subject string, schema *Schema) (int, error) {
+	body, err := json.Marshal(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		ID int `json:"id"`
+	}
+	path := fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject))
+	if err := c.do(http.MethodPost, path, body, &result); err != nil {
+		return 0, err
+	}
+	return result.ID, nil
+}
+
+func (c *Client) do(method, path string, body []byte, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, c.url+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	octets, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if err := json.Unmarshal(octets, &errResp); err == nil && errResp.Message != "" {
+			return fmt.Errorf("schema registry request %s failed: %s (%d)", path, errResp.Message, errResp.ErrorCode)
+		}
+		return fmt.Errorf("schema registry request %s failed: %s", path, resp.Status)
+	}
+
+	if err := json.Unmarshal(octets, result); err != nil {
+		return fmt.Errorf("decoding schema registry response of %s failed: %v", path, err)
+	}
+	return nil
+}
+
+// Encode prefixes the payload with the header of the Confluent wire format.
+func Encode(id int, payload []byte) []byte {
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	buf[0] = magicByte
+	binary.BigEndian.PutUint32(buf[1:headerSize], uint32(id))
+	return append(buf, payload...)
+}
+
+// Decode splits a message of the Confluent wire format into the schema ID
+// and the payload.
+func Decode(buf []byte) (int, []byte, error) {
+	if len(buf) < headerSize {
+		return 0, nil, errors.New("message too short for the schema registry wire format")
+	}
+	if buf[0] != magicByte {
+		return 0, nil, fmt.Errorf("invalid magic byte %d of the schema registry wire format", buf[0])
+	}
+	return int(binary.BigEndian.Uint32(buf[1:headerSize])), buf[headerSize:], nil
+}
@@ -0,0 +1,149 @@
+package schemaregistry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	id, payload, err := Decode([]byte{0, 0, 0, 1, 2, 42})
+	require.NoError(t, err)
+	require.Equal(t, 258, id)
+	require.Equal(t, []byte{42}, payload)
+
+	_, _, err = Decode([]byte{0, 0, 0})
+	require.Error(t, err)
+
+	_, _, err = Decode([]byte{1, 0, 0, 0, 1, 42})
+	require.Error(t, err)
+}
+
+func TestEncode(t *testing.T) {
+	buf := Encode(258, []byte{42})
+	require.Equal(t, []byte{0, 0, 0, 1, 2, 42}, buf)
+
+	id, payload, err := Decode(buf)
+	require.NoError(t, err)
+	require.Equal(t, 258, id)
+	require.Equal(t, []byte{42}, payload)
+}
+
+func TestClientRegister(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/vnd.schemaregistry.v1+json", r.Header.Get("Content-Type"))
+
+		var schema Schema
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&schema))
+		switch r.URL.Path {
+		case "/subjects/telegraf.cpu/versions":
+			require.Equal(t, Schema{Schema: `"string"`}, schema)
+			w.Write([]byte(`{"id": 5}`))
+		default:
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error_code": 409, "message": "Schema being registered is incompatible with an earlier schema"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL)
+	require.NoError(t, err)
+
+	id, err := client.Register("telegraf.cpu", &Schema{Schema: `"string"`})
+	require.NoError(t, err)
+	require.Equal(t, 5, id)
+
+	_, err = client.Register("telegraf.mem", &Schema{Schema: `"string"`})
+	require.Error(t, err)
+}
+
+func TestClientSchemaCache(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/schemas/ids/1":
+			w.Write([]byte(`{"schema": "\"string\""}`))
+		case "/subjects/other/versions/2":
+			w.Write([]byte(`{"subject": "other", "version": 2, "id": 3, "schemaType": "PROTOBUF", "schema": "syntax = \"proto3\";"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error_code": 40403, "message": "Schema not found"}`))
+		}
+	}))
+	defer ts.Close()
+
+	client, err := NewClient(ts.URL + "/")
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		schema, err := client.SchemaByID(1)
+		require.NoError(t, err)
+		require.Equal(t, &Schema{Type: "AVRO", Schema: `"string"`}, schema)
+	}
+	require.Equal(t, 1, requests)
+
+	schema, err := client.SchemaBySubject("other", 2)
+	require.NoError(t, err)
+	require.Equal(t, "PROTOBUF", schema.Type)
+
+	_, err = client.SchemaByID(2)
+	require.EqualError(t, err, "schema registry request /schemas/ids/2 failed: Schema not found (40403)")
+}
+
+func TestNewClientInvalidURL(t *testing.T) {
+	_, err := NewClient("localhost:8081")
+	require.Error(t, err)
+}
+
+func TestRecordMetric(t *testing.T) {
+	config := &RecordConfig{
+		MetricName:       "kafka",
+		MeasurementField: "type",
+		Tags:             []string{"host", "labels_region"},
+		Timestamp:        "time",
+		TimestampFormat:  "unix_ms",
+		DefaultTags:      map[string]string{"source": "test"},
+	}
+
+	m, err := config.Metric(map[string]interface{}{
+		"type":   "cpu",
+		"host":   "a",
+		"time":   int64(1622745000123),
+		"labels": map[string]interface{}{"region": "eu"},
+		"usage":  map[string]interface{}{"user": 4.5, "system": nil},
+		"cores":  []interface{}{int64(1), int64(2)},
+		"seen":   time.Unix(1, 0),
+	})
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "labels_region": "eu", "source": "test"},
+		map[string]interface{}{"usage_user": 4.5, "cores_0": int64(1), "cores_1": int64(2), "seen": int64(1e9)},
+		time.Unix(0, 1622745000123*int64(time.Millisecond)),
+	)
+	testutil.RequireMetricEqual(t, expected, m)
+}
+
+func TestRecordMetricTimestamp(t *testing.T) {
+	config := &RecordConfig{MetricName: "kafka", Timestamp: "time", FieldSeparator: "."}
+
+	tm := time.Unix(1622745000, 0)
+	m, err := config.Metric(map[string]interface{}{
+		"time":  tm,
+		"usage": map[string]interface{}{"user": 4.5},
+	})
+	require.NoError(t, err)
+	require.Equal(t, tm, m.Time())
+	require.Equal(t, map[string]interface{}{"usage.user": 4.5}, m.Fields())
+
+	_, err = config.Metric(map[string]interface{}{"value": 1})
+	require.Error(t, err)
+}
@@ -6,10 +6,15 @@ import (
 	"github.com/influxdata/telegraf"
 )
 
+// TemplateMetric exposes a metric to Go templates.
 type TemplateMetric struct {
 	metric telegraf.Metric
 }
 
+func NewTemplateMetric(metric telegraf.Metric) *TemplateMetric {
+	return &TemplateMetric{metric: metric}
+}
+
 func (m *TemplateMetric) Name() string {
 	return m.metric.Name()
 }
@@ -27,3 +32,13 @@ func (m *TemplateMetric) Field(key string) interface{} {
 func (m *TemplateMetric) Time() time.Time {
 	return m.metric.Time()
 }
+
+// Tags returns the tags of the metric, ranged over by key order.
+func (m *TemplateMetric) Tags() map[string]string {
+	return m.metric.Tags()
+}
+
+// Fields returns the fields of the metric, ranged over by key order.
+func (m *TemplateMetric) Fields() map[string]interface{} {
+	return m.metric.Fields()
+}
@@ -64,8 +64,8 @@ var sampleConfig = `
   ## HTTP entity-body to send with POST/PUT requests.
   # body = ""
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
   ## HTTP Proxy support
@@ -178,8 +178,8 @@ func (h *HTTP) gatherURL(
 		request.Header.Set("Authorization", bearer)
 	}
 
-	if h.ContentEncoding == "gzip" {
-		request.Header.Set("Content-Encoding", "gzip")
+	if h.ContentEncoding != "" && h.ContentEncoding != "identity" {
+		request.Header.Set("Content-Encoding", h.ContentEncoding)
 	}
 
 	for k, v := range h.Headers {
@@ -236,15 +236,7 @@ func (h *HTTP) gatherURL(
 }
 
 func makeRequestBodyReader(contentEncoding, body string) (io.ReadCloser, error) {
-	var reader io.Reader = strings.NewReader(body)
-	if contentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reader)
-		if err != nil {
-			return nil, err
-		}
-		return rc, nil
-	}
-	return ioutil.NopCloser(reader), nil
+	return internal.CompressWithEncoding(contentEncoding, strings.NewReader(body))
 }
 
 func init() {
@@ -9,6 +9,7 @@ import (
 	"net/url"
 	"testing"
 
+	"github.com/influxdata/telegraf/internal"
 	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
 	oauth "github.com/influxdata/telegraf/plugins/common/oauth"
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
@@ -236,6 +237,26 @@ func TestBodyAndContentEncoding(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 			},
 		},
+		{
+			name: "zstd encoding",
+			plugin: &plugin.HTTP{
+				URLs:            []string{url},
+				Method:          "GET",
+				Body:            "test",
+				ContentEncoding: "zstd",
+			},
+			queryHandlerFunc: func(t *testing.T, w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, r.Header.Get("Content-Encoding"), "zstd")
+
+				zr, err := internal.NewZstdReader(r.Body)
+				require.NoError(t, err)
+				defer zr.Close()
+				body, err := ioutil.ReadAll(zr)
+				require.NoError(t, err)
+				require.Equal(t, []byte("test"), body)
+				w.WriteHeader(http.StatusOK)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1,9 +1,9 @@
 package http_listener_v2
 
 import (
-	"compress/gzip"
 	"crypto/subtle"
 	"crypto/tls"
+	"errors"
 	"io/ioutil"
 	"net"
 	"net/http"
@@ -12,9 +12,9 @@ import (
 	"sync"
 	"time"
 
-	"github.com/golang/snappy"
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
@@ -74,7 +74,8 @@ const sampleConfig = `
   ## maximum duration before timing out write of the response
   # write_timeout = "10s"
 
-  ## Maximum allowed http request body size in bytes.
+  ## Maximum allowed http request body size in bytes, also applied
+  ## to the decompressed body.
   ## 0 means to use the default of 524,288,00 bytes (500 mebibytes)
   # max_body_size = "500MB"
 
@@ -258,59 +259,36 @@ func (h *HTTPListenerV2) serveWrite(res http.ResponseWriter, req *http.Request)
 }
 
 func (h *HTTPListenerV2) collectBody(res http.ResponseWriter, req *http.Request) ([]byte, bool) {
-	encoding := req.Header.Get("Content-Encoding")
-
-	switch encoding {
-	case "gzip":
-		r, err := gzip.NewReader(req.Body)
-		if err != nil {
-			h.Log.Debug(err.Error())
-			if err := badRequest(res); err != nil {
-				h.Log.Debugf("error in bad-request: %v", err)
-			}
-			return nil, false
-		}
-		defer r.Close()
-		maxReader := http.MaxBytesReader(res, r, int64(h.MaxBodySize))
-		bytes, err := ioutil.ReadAll(maxReader)
-		if err != nil {
-			if err := tooLarge(res); err != nil {
-				h.Log.Debugf("error in too-large: %v", err)
-			}
-			return nil, false
-		}
-		return bytes, true
-	case "snappy":
-		defer req.Body.Close()
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			h.Log.Debug(err.Error())
-			if err := badRequest(res); err != nil {
-				h.Log.Debugf("error in bad-request: %v", err)
-			}
-			return nil, false
-		}
-		// snappy block format is only supported by decode/encode not snappy reader/writer
-		bytes, err = snappy.Decode(nil, bytes)
-		if err != nil {
-			h.Log.Debug(err.Error())
-			if err := badRequest(res); err != nil {
-				h.Log.Debugf("error in bad-request: %v", err)
-			}
-			return nil, false
-		}
-		return bytes, true
-	default:
-		defer req.Body.Close()
-		bytes, err := ioutil.ReadAll(req.Body)
-		if err != nil {
-			h.Log.Debug(err.Error())
-			if err := badRequest(res); err != nil {
-				h.Log.Debugf("error in bad-request: %v", err)
-			}
-			return nil, false
+	defer req.Body.Close()
+
+	encoding := internal.RequestContentEncoding(req.Header.Get("Content-Encoding"))
+	r, err := internal.NewLimitedStreamContentDecoder(encoding, req.Body, int64(h.MaxBodySize))
+	if err != nil {
+		h.rejectBody(res, err)
+		return nil, false
+	}
+	defer r.Close()
+
+	bytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		h.rejectBody(res, err)
+		return nil, false
+	}
+	return bytes, true
+}
+
+// rejectBody answers a request whose body cannot be read, either because it
+// is too large once decompressed or because it is invalid.
+func (h *HTTPListenerV2) rejectBody(res http.ResponseWriter, err error) {
+	h.Log.Debug(err.Error())
+	if errors.Is(err, internal.ErrDecompressedTooLarge) {
+		if err := tooLarge(res); err != nil {
+			h.Log.Debugf("error in too-large: %v", err)
 		}
-		return bytes, true
+		return
+	}
+	if err := badRequest(res); err != nil {
+		h.Log.Debugf("error in bad-request: %v", err)
 	}
 }
 
@@ -9,12 +9,14 @@ import (
 	"net/url"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/golang/snappy"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -328,6 +330,95 @@ func TestWriteHTTPGzippedData(t *testing.T) {
 	}
 }
 
+// test that writing zstd compressed data works
+func TestWriteZstdData(t *testing.T) {
+	listener := newTestHTTPListenerV2()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	encoder, err := internal.NewContentEncoder("zstd")
+	require.NoError(t, err)
+	data, err := encoder.Encode([]byte(testMsgs))
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBuffer(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "zstd")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	hostTags := []string{"server02", "server03",
+		"server04", "server05", "server06"}
+	acc.Wait(len(hostTags))
+	for _, hostTag := range hostTags {
+		acc.AssertContainsTaggedFields(t, "cpu_load_short",
+			map[string]interface{}{"value": float64(12)},
+			map[string]string{"host": hostTag},
+		)
+	}
+}
+
+// test that bodies of unknown encodings are read as is
+func TestWriteUnknownContentEncoding(t *testing.T) {
+	listener := newTestHTTPListenerV2()
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, listener.Start(acc))
+	defer listener.Stop()
+
+	req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBufferString(testMsg))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "unknown")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	require.EqualValues(t, 204, resp.StatusCode)
+
+	acc.Wait(1)
+	acc.AssertContainsTaggedFields(t, "cpu_load_short",
+		map[string]interface{}{"value": float64(12)},
+		map[string]string{"host": "server01"},
+	)
+}
+
+// test that the size of the decompressed data is limited
+func TestWriteDecompressedTooLarge(t *testing.T) {
+	for _, encoding := range []string{"gzip", "zstd", "snappy"} {
+		t.Run(encoding, func(t *testing.T) {
+			listener := newTestHTTPListenerV2()
+			listener.MaxBodySize = config.Size(4096)
+
+			acc := &testutil.Accumulator{}
+			require.NoError(t, listener.Start(acc))
+			defer listener.Stop()
+
+			encoder, err := internal.NewContentEncoder(encoding)
+			require.NoError(t, err)
+			data, err := encoder.Encode([]byte(strings.Repeat(testMsgs, 100)))
+			require.NoError(t, err)
+			require.Less(t, len(data), 4096)
+
+			req, err := http.NewRequest("POST", createURL(listener, "http", "/write", ""), bytes.NewBuffer(data))
+			require.NoError(t, err)
+			req.Header.Set("Content-Encoding", encoding)
+
+			client := &http.Client{}
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			require.NoError(t, resp.Body.Close())
+			require.EqualValues(t, 413, resp.StatusCode)
+		})
+	}
+}
+
 // test that writing snappy data works
 func TestWriteHTTPSnappyData(t *testing.T) {
 	listener := newTestHTTPListenerV2()
@@ -1,10 +1,10 @@
 package influxdb_listener
 
 import (
-	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -33,7 +33,7 @@ type InfluxDBListener struct {
 	ReadTimeout        config.Duration `toml:"read_timeout"`
 	WriteTimeout       config.Duration `toml:"write_timeout"`
 	MaxBodySize        config.Size     `toml:"max_body_size"`
-	MaxLineSize        config.Size     `toml:"max_line_size"` // deprecated in 1.14; ignored
+	MaxLineSize        config.Size     `toml:"max_line_size" deprecated:"1.14.0;parser now handles lines of unlimited length and option is ignored"`
 	BasicUsername      string          `toml:"basic_username"`
 	BasicPassword      string          `toml:"basic_password"`
 	DatabaseTag        string          `toml:"database_tag"`
@@ -70,7 +70,8 @@ const sampleConfig = `
   ## maximum duration before timing out write of the response
   write_timeout = "10s"
 
-  ## Maximum allowed HTTP request body size in bytes.
+  ## Maximum allowed HTTP request body size in bytes, also applied
+  ## to the decompressed body.
   ## 0 means to use the default of 32MiB.
   max_body_size = "32MiB"
 
@@ -270,21 +271,24 @@ func (h *InfluxDBListener) handleWrite() http.HandlerFunc {
 		db := req.URL.Query().Get("db")
 		rp := req.URL.Query().Get("rp")
 
-		body := req.Body
-		body = http.MaxBytesReader(res, body, int64(h.MaxBodySize))
-		// Handle gzip request bodies
-		if req.Header.Get("Content-Encoding") == "gzip" {
-			var err error
-			body, err = gzip.NewReader(body)
-			if err != nil {
-				h.Log.Debugf("Error decompressing request body: %v", err.Error())
-				if err := badRequest(res, err.Error()); err != nil {
-					h.Log.Debugf("error in bad-request: %v", err)
+		body := http.MaxBytesReader(res, req.Body, int64(h.MaxBodySize))
+		// Handle compressed request bodies, limiting their decompressed size
+		encoding := internal.RequestContentEncoding(req.Header.Get("Content-Encoding"))
+		body, err := internal.NewLimitedStreamContentDecoder(encoding, body, int64(h.MaxBodySize))
+		if err != nil {
+			h.Log.Debugf("Error decompressing request body: %v", err.Error())
+			if errors.Is(err, internal.ErrDecompressedTooLarge) {
+				if err := tooLarge(res); err != nil {
+					h.Log.Debugf("error in too-large: %v", err)
 				}
 				return
 			}
-			defer body.Close()
+			if err := badRequest(res, err.Error()); err != nil {
+				h.Log.Debugf("error in bad-request: %v", err)
+			}
+			return
 		}
+		defer body.Close()
 
 		parser := influx.NewStreamParser(body)
 		parser.SetTimeFunc(h.timeFunc)
@@ -296,7 +300,6 @@ func (h *InfluxDBListener) handleWrite() http.HandlerFunc {
 		}
 
 		var m telegraf.Metric
-		var err error
 		var parseErrorCount int
 		var lastPos int
 		var firstParseErrorStr string
@@ -338,6 +341,13 @@ func (h *InfluxDBListener) handleWrite() http.HandlerFunc {
 
 			h.acc.AddMetric(m)
 		}
+		if errors.Is(err, internal.ErrDecompressedTooLarge) {
+			h.Log.Debugf("Error parsing the request body: %v", err.Error())
+			if err := tooLarge(res); err != nil {
+				h.Log.Debugf("error in too-large: %v", err)
+			}
+			return
+		}
 		if err != influx.EOF {
 			h.Log.Debugf("Error parsing the request body: %v", err.Error())
 			if err := badRequest(res, err.Error()); err != nil {
@@ -1,10 +1,10 @@
 package influxdb_v2_listener
 
 import (
-	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -71,7 +71,8 @@ const sampleConfig = `
   ## (Double check the port. Could be 9999 if using OSS Beta)
   service_address = ":8086"
 
-  ## Maximum allowed HTTP request body size in bytes.
+  ## Maximum allowed HTTP request body size in bytes, also applied
+  ## to the decompressed body.
   ## 0 means to use the default of 32MiB.
   # max_body_size = "32MiB"
 
@@ -237,26 +238,35 @@ func (h *InfluxDBV2Listener) handleWrite() http.HandlerFunc {
 
 		bucket := req.URL.Query().Get("bucket")
 
-		body := req.Body
-		body = http.MaxBytesReader(res, body, int64(h.MaxBodySize))
-		// Handle gzip request bodies
-		if req.Header.Get("Content-Encoding") == "gzip" {
-			var err error
-			body, err = gzip.NewReader(body)
-			if err != nil {
-				h.Log.Debugf("Error decompressing request body: %v", err.Error())
-				if err := badRequest(res, Invalid, err.Error()); err != nil {
-					h.Log.Debugf("error in bad-request: %v", err)
+		body := http.MaxBytesReader(res, req.Body, int64(h.MaxBodySize))
+		// Handle compressed request bodies, limiting their decompressed size
+		encoding := internal.RequestContentEncoding(req.Header.Get("Content-Encoding"))
+		body, err := internal.NewLimitedStreamContentDecoder(encoding, body, int64(h.MaxBodySize))
+		if err != nil {
+			h.Log.Debugf("Error decompressing request body: %v", err.Error())
+			if errors.Is(err, internal.ErrDecompressedTooLarge) {
+				if err := tooLarge(res, int64(h.MaxBodySize)); err != nil {
+					h.Log.Debugf("error in too-large: %v", err)
 				}
 				return
 			}
-			defer body.Close()
+			if err := badRequest(res, Invalid, err.Error()); err != nil {
+				h.Log.Debugf("error in bad-request: %v", err)
+			}
+			return
 		}
+		defer body.Close()
 
 		var readErr error
 		var bytes []byte
-		//body = http.MaxBytesReader(res, req.Body, 1000000) //p.MaxBodySize.Size)
 		bytes, readErr = ioutil.ReadAll(body)
+		if errors.Is(readErr, internal.ErrDecompressedTooLarge) {
+			h.Log.Debugf("Error parsing the request body: %v", readErr.Error())
+			if err := tooLarge(res, int64(h.MaxBodySize)); err != nil {
+				h.Log.Debugf("error in too-large: %v", err)
+			}
+			return
+		}
 		if readErr != nil {
 			h.Log.Debugf("Error parsing the request body: %v", readErr.Error())
 			if err := badRequest(res, InternalError, readErr.Error()); err != nil {
@@ -275,7 +285,6 @@ func (h *InfluxDBV2Listener) handleWrite() http.HandlerFunc {
 		}
 
 		var metrics []telegraf.Metric
-		var err error
 
 		metrics, err = parser.Parse(bytes)
 
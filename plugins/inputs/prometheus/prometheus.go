@@ -25,6 +25,10 @@ import (
 
 const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
 
+// acceptHeaderOpenMetrics also accepts the OpenMetrics format, which only the
+// parser of metric_version 2 supports.
+const acceptHeaderOpenMetrics = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,application/openmetrics-text;version=0.0.1;q=0.5,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
+
 type Prometheus struct {
 	// An array of urls to scrape metrics from.
 	URLs []string `toml:"urls"`
@@ -53,6 +57,8 @@ type Prometheus struct {
 
 	MetricVersion int `toml:"metric_version"`
 
+	EnableOpenMetrics bool `toml:"enable_openmetrics"`
+
 	URLTag string `toml:"url_tag"`
 
 	tls.ClientConfig
@@ -92,6 +98,10 @@ var sampleConfig = `
   ##            metric_version = 2; recommended version
   # metric_version = 1
 
+  ## Ask for the OpenMetrics format, preferring it over the text format, with
+  ## metric_version = 2.
+  # enable_openmetrics = false
+
   ## Url tag name (tag containing scrapped url. optional, default is "url")
   # url_tag = "url"
 
@@ -279,6 +289,9 @@ func (p *Prometheus) Gather(acc telegraf.Accumulator) error {
 			"User-Agent": internal.ProductToken(),
 			"Accept":     acceptHeader,
 		}
+		if p.MetricVersion == 2 && p.EnableOpenMetrics {
+			p.headers["Accept"] = acceptHeaderOpenMetrics
+		}
 	}
 
 	var wg sync.WaitGroup
@@ -242,6 +242,58 @@ func TestPrometheusGeneratesGaugeMetricsV2(t *testing.T) {
 	assert.True(t, acc.HasTimestamp("prometheus", time.Unix(1490802350, 0)))
 }
 
+func TestPrometheusAcceptHeader(t *testing.T) {
+	tests := []struct {
+		name              string
+		metricVersion     int
+		enableOpenMetrics bool
+		expected          string
+	}{
+		{
+			name:     "metric version 1",
+			expected: acceptHeader,
+		},
+		{
+			name:              "metric version 1 with openmetrics",
+			enableOpenMetrics: true,
+			expected:          acceptHeader,
+		},
+		{
+			name:          "metric version 2",
+			metricVersion: 2,
+			expected:      acceptHeader,
+		},
+		{
+			name:              "metric version 2 with openmetrics",
+			metricVersion:     2,
+			enableOpenMetrics: true,
+			expected:          acceptHeaderOpenMetrics,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var accept string
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept")
+				_, err := fmt.Fprint(w, sampleGaugeTextFormat)
+				require.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			p := &Prometheus{
+				URLs:              []string{ts.URL},
+				URLTag:            "url",
+				MetricVersion:     tt.metricVersion,
+				EnableOpenMetrics: tt.enableOpenMetrics,
+			}
+
+			var acc testutil.Accumulator
+			require.NoError(t, acc.GatherError(p.Gather))
+			require.Equal(t, tt.expected, accept)
+		})
+	}
+}
+
 func TestUnsupportedFieldSelector(t *testing.T) {
 	fieldSelectorString := "spec.containerName=container"
 	prom := &Prometheus{Log: testutil.Logger{}, KubernetesFieldSelector: fieldSelectorString}
@@ -0,0 +1,162 @@
+package prometheus_remote_write
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/influxdata/telegraf"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of the metadata in the remote write protocol.
+const (
+	writeRequestMetadata = 3
+
+	metadataType             = 1
+	metadataMetricFamilyName = 2
+	metadataHelp             = 4
+	metadataUnit             = 5
+)
+
+// metricTypes are the names of the metric types of the metadata, by value.
+var metricTypes = []string{"unknown", "counter", "gauge", "histogram", "gaugehistogram", "summary", "info", "stateset"}
+
+// suffixes are the suffixes of the series names of a metric family.
+var suffixes = []string{"_total", "_created", "_bucket", "_count", "_sum", "_gcount", "_gsum", "_info"}
+
+// metadata is the metadata of a metric family.
+type metadata struct {
+	family string
+	mtype  string
+	help   string
+	unit   string
+}
+
+// decodeMetadata decodes the metadata of the metric families from the
+// fields of a write request.
+func decodeMetadata(buf []byte) ([]metadata, error) {
+	var families []metadata
+	err := decodeFields(buf, func(num protowire.Number, typ protowire.Type, value []byte) error {
+		if num != writeRequestMetadata || typ != protowire.BytesType {
+			return nil
+		}
+
+		var md metadata
+		err := decodeFields(value, func(num protowire.Number, typ protowire.Type, value []byte) error {
+			switch {
+			case num == metadataType && typ == protowire.VarintType:
+				v, n := protowire.ConsumeVarint(value)
+				if n < 0 {
+					return protowire.ParseError(n)
+				}
+				if v < uint64(len(metricTypes)) {
+					md.mtype = metricTypes[v]
+				}
+			case num == metadataMetricFamilyName && typ == protowire.BytesType:
+				md.family = string(value)
+			case num == metadataHelp && typ == protowire.BytesType:
+				md.help = string(value)
+			case num == metadataUnit && typ == protowire.BytesType:
+				md.unit = string(value)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if md.family == "" {
+			return fmt.Errorf("metadata without metric family name")
+		}
+		families = append(families, md)
+		return nil
+	})
+	return families, err
+}
+
+// decodeFields calls the function with the number, wire type and value of
+// each field of a protobuf message.  The value of length-delimited fields is
+// their content, the one of the other fields their encoding.
+func decodeFields(buf []byte, f func(protowire.Number, protowire.Type, []byte) error) error {
+	for len(buf) > 0 {
+		num, typ, n := protowire.ConsumeTag(buf)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+
+		var value []byte
+		if typ == protowire.BytesType {
+			value, n = protowire.ConsumeBytes(buf)
+		} else {
+			n = protowire.ConsumeFieldValue(num, typ, buf)
+			if n >= 0 {
+				value = buf[:n]
+			}
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		buf = buf[n:]
+
+		if err := f(num, typ, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateMetadata keeps the latest metadata of the metric families.
+func (p *PrometheusRemoteWrite) updateMetadata(families []metadata) {
+	if len(families) == 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, md := range families {
+		p.metadata[md.family] = md
+	}
+}
+
+// lookupMetadata returns the metadata of the family of a series, whose name
+// may have a suffix of the family type.  The caller must hold the lock.
+func (p *PrometheusRemoteWrite) lookupMetadata(name string) metadata {
+	if md, ok := p.metadata[name]; ok {
+		return md
+	}
+	for _, suffix := range suffixes {
+		if !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if md, ok := p.metadata[strings.TrimSuffix(name, suffix)]; ok {
+			return md
+		}
+	}
+	return metadata{}
+}
+
+func (md metadata) tag(name string) string {
+	switch name {
+	case "type":
+		return md.mtype
+	case "unit":
+		return md.unit
+	case "help":
+		return md.help
+	}
+	return ""
+}
+
+func (md metadata) valueType() telegraf.ValueType {
+	switch md.mtype {
+	case "counter":
+		return telegraf.Counter
+	case "gauge", "gaugehistogram", "info", "stateset":
+		return telegraf.Gauge
+	case "histogram":
+		return telegraf.Histogram
+	case "summary":
+		return telegraf.Summary
+	}
+	return telegraf.Untyped
+}
@@ -0,0 +1,404 @@
+package prometheus_remote_write
+
+import (
+	"context"
+	"crypto/subtle"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// defaultMaxBodySize is the default maximum size of the decompressed
+	// request body, in bytes.  Larger requests get an HTTP 413 error.
+	defaultMaxBodySize = 32 * 1024 * 1024
+
+	defaultMaxUndeliveredRequests = 100
+	defaultTimeout                = 10 * time.Second
+
+	measurement = "prometheus_remote_write"
+)
+
+type PrometheusRemoteWrite struct {
+	ServiceAddress         string          `toml:"service_address"`
+	Path                   string          `toml:"path"`
+	ReadTimeout            config.Duration `toml:"read_timeout"`
+	WriteTimeout           config.Duration `toml:"write_timeout"`
+	MaxBodySize            config.Size     `toml:"max_body_size"`
+	MaxUndeliveredRequests int             `toml:"max_undelivered_requests"`
+	BasicUsername          string          `toml:"basic_username"`
+	BasicPassword          string          `toml:"basic_password"`
+	MetadataTags           []string        `toml:"metadata_tags"`
+	tlsint.ServerConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	port     int
+	listener net.Listener
+	server   *http.Server
+	acc      telegraf.TrackingAccumulator
+	sem      chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	metadata map[string]metadata
+
+	requestsReceived  selfstat.Stat
+	samplesReceived   selfstat.Stat
+	requestsRejected  selfstat.Stat
+	requestsThrottled selfstat.Stat
+}
+
+const sampleConfig = `
+  ## Address and port to host the remote write receiver on
+  service_address = ":9201"
+
+  ## Path of the remote write endpoint.
+  # path = "/api/v1/write"
+
+  ## Maximum duration before timing out read of the request
+  # read_timeout = "10s"
+  ## Maximum duration before timing out write of the response, including
+  ## the time spent waiting for the outputs to catch up.
+  # write_timeout = "10s"
+
+  ## Maximum allowed size of the decompressed request body.
+  # max_body_size = "32MiB"
+
+  ## Maximum number of requests whose metrics have not been written by the
+  ## outputs yet.  When reached, the requests wait for the outputs to catch
+  ## up until write_timeout, and are then answered with a 503 status code, so
+  ## that the senders retry them later.
+  # max_undelivered_requests = 100
+
+  ## Metadata of the metric families to add as tags, among "type", "unit"
+  ## and "help".  The metadata is sent by Prometheus in separate requests,
+  ## so the first metrics of a family may not have it.
+  # metadata_tags = []
+
+  ## Set one or more allowed client CA certificate file names to
+  ## enable mutually authenticated TLS connections
+  # tls_allowed_cacerts = ["/etc/telegraf/clientca.pem"]
+
+  ## Add service certificate and key
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+
+  ## Optional username and password to accept for HTTP basic authentication.
+  ## You probably want to make sure you have TLS configured above for this.
+  # basic_username = "foobar"
+  # basic_password = "barfoo"
+`
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Receive metrics with the Prometheus remote write protocol"
+}
+
+func (p *PrometheusRemoteWrite) Gather(_ telegraf.Accumulator) error {
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Init() error {
+	for _, tag := range p.MetadataTags {
+		switch tag {
+		case "type", "unit", "help":
+		default:
+			return fmt.Errorf("invalid metadata tag %q", tag)
+		}
+	}
+	if p.MaxUndeliveredRequests < 1 {
+		return fmt.Errorf("max_undelivered_requests must be at least 1")
+	}
+
+	if p.MaxBodySize == 0 {
+		p.MaxBodySize = config.Size(defaultMaxBodySize)
+	}
+	if p.ReadTimeout == 0 {
+		p.ReadTimeout = config.Duration(defaultTimeout)
+	}
+	if p.WriteTimeout == 0 {
+		p.WriteTimeout = config.Duration(defaultTimeout)
+	}
+
+	tags := map[string]string{"address": p.ServiceAddress}
+	p.requestsReceived = selfstat.Register("prometheus_remote_write", "requests_received", tags)
+	p.samplesReceived = selfstat.Register("prometheus_remote_write", "samples_received", tags)
+	p.requestsRejected = selfstat.Register("prometheus_remote_write", "requests_rejected", tags)
+	p.requestsThrottled = selfstat.Register("prometheus_remote_write", "requests_throttled", tags)
+
+	p.metadata = make(map[string]metadata)
+	return nil
+}
+
+// Start starts the remote write receiver.
+func (p *PrometheusRemoteWrite) Start(acc telegraf.Accumulator) error {
+	tlsConf, err := p.ServerConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	// The timeout handler cancels the requests waiting for the outputs to
+	// catch up, and answers them with a 503 status code.
+	p.server = &http.Server{
+		Addr:        p.ServiceAddress,
+		Handler:     http.TimeoutHandler(p, time.Duration(p.WriteTimeout), "timed out waiting for the outputs"),
+		ReadTimeout: time.Duration(p.ReadTimeout),
+		TLSConfig:   tlsConf,
+	}
+
+	var listener net.Listener
+	if tlsConf != nil {
+		listener, err = tls.Listen("tcp", p.ServiceAddress, tlsConf)
+	} else {
+		listener, err = net.Listen("tcp", p.ServiceAddress)
+	}
+	if err != nil {
+		return err
+	}
+	p.listener = listener
+	p.port = listener.Addr().(*net.TCPAddr).Port
+
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	p.acc = acc.WithTracking(p.MaxUndeliveredRequests)
+	p.sem = make(chan struct{}, p.MaxUndeliveredRequests)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.receiveDelivered()
+	}()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		if err := p.server.Serve(p.listener); err != nil && err != http.ErrServerClosed {
+			p.Log.Errorf("Serve failed: %v", err)
+		}
+	}()
+
+	p.Log.Infof("Listening on %s", listener.Addr().String())
+
+	return nil
+}
+
+// Stop cleans up all resources
+func (p *PrometheusRemoteWrite) Stop() {
+	p.cancel()
+	if err := p.server.Shutdown(context.Background()); err != nil {
+		p.Log.Errorf("Shutting down server failed: %v", err)
+	}
+	p.wg.Wait()
+}
+
+func (p *PrometheusRemoteWrite) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.URL.Path != p.Path {
+		http.NotFound(res, req)
+		return
+	}
+	if !p.authenticated(req) {
+		http.Error(res, "Unauthorized.", http.StatusUnauthorized)
+		return
+	}
+	p.requestsReceived.Incr(1)
+
+	if status, err := p.serveWrite(req); err != nil {
+		p.Log.Debugf("Rejecting request from %s: %v", req.RemoteAddr, err)
+		if status == http.StatusServiceUnavailable {
+			p.requestsThrottled.Incr(1)
+		} else {
+			p.requestsRejected.Incr(1)
+		}
+		if status == http.StatusMethodNotAllowed {
+			res.Header().Set("Allow", http.MethodPost)
+		}
+		http.Error(res, err.Error(), status)
+		return
+	}
+	res.WriteHeader(http.StatusNoContent)
+}
+
+// serveWrite handles a write request, returning the status code of the
+// error if any.
+func (p *PrometheusRemoteWrite) serveWrite(req *http.Request) (int, error) {
+	if req.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method)
+	}
+	if encoding := req.Header.Get("Content-Encoding"); encoding != "snappy" {
+		return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		mediatype, params, err := mime.ParseMediaType(contentType)
+		if err != nil || mediatype != "application/x-protobuf" {
+			return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %q", contentType)
+		}
+		if proto, ok := params["proto"]; ok && proto != "prometheus.WriteRequest" {
+			return http.StatusUnsupportedMediaType, fmt.Errorf("unsupported protobuf message %q", proto)
+		}
+	}
+
+	// The snappy block format cannot be streamed, so the compressed body is
+	// limited to the largest encoding of the maximum decompressed size.
+	maxCompressed := int64(snappy.MaxEncodedLen(int(p.MaxBodySize)))
+	if req.ContentLength > maxCompressed {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request body too large")
+	}
+	compressed, err := ioutil.ReadAll(io.LimitReader(req.Body, maxCompressed+1))
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if int64(len(compressed)) > maxCompressed {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request body too large")
+	}
+
+	size, err := snappy.DecodedLen(compressed)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("decoding snappy failed: %v", err)
+	}
+	if size > int(p.MaxBodySize) {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("decompressed body of %d bytes too large", size)
+	}
+	data, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("decoding snappy failed: %v", err)
+	}
+
+	var wr prompb.WriteRequest
+	if err := proto.Unmarshal(data, &wr); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("unmarshaling write request failed: %v", err)
+	}
+
+	// The metadata is unknown to the write request of the vendored protobuf
+	// definitions, and kept in its unrecognized fields.
+	families, err := decodeMetadata(wr.XXX_unrecognized)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("decoding metadata failed: %v", err)
+	}
+	p.updateMetadata(families)
+
+	metrics, err := p.convert(wr.Timeseries, time.Now())
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	if len(metrics) == 0 {
+		return http.StatusNoContent, nil
+	}
+
+	// Wait for the outputs to write the metrics of previous requests, so
+	// that the senders retry when the outputs fall behind.
+	select {
+	case p.sem <- struct{}{}:
+	case <-req.Context().Done():
+		return http.StatusServiceUnavailable, fmt.Errorf("timed out waiting for the outputs")
+	case <-p.ctx.Done():
+		return http.StatusServiceUnavailable, fmt.Errorf("shutting down")
+	}
+
+	p.acc.AddTrackingMetricGroup(metrics)
+	p.samplesReceived.Incr(int64(len(metrics)))
+	return http.StatusNoContent, nil
+}
+
+// convert converts the samples of the series to metrics named after the
+// protocol, with a field named after the series.  NaN values, such as the
+// staleness markers, are skipped.
+func (p *PrometheusRemoteWrite) convert(series []prompb.TimeSeries, now time.Time) ([]telegraf.Metric, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var metrics []telegraf.Metric
+	for _, ts := range series {
+		tags := make(map[string]string, len(ts.Labels))
+		for _, l := range ts.Labels {
+			tags[l.Name] = l.Value
+		}
+
+		name := tags[model.MetricNameLabel]
+		if name == "" {
+			return nil, fmt.Errorf("metric name %q not found in tag-set or empty", model.MetricNameLabel)
+		}
+		delete(tags, model.MetricNameLabel)
+
+		md := p.lookupMetadata(name)
+		for _, tag := range p.MetadataTags {
+			if _, ok := tags[tag]; ok {
+				continue
+			}
+			if value := md.tag(tag); value != "" {
+				tags[tag] = value
+			}
+		}
+
+		for _, s := range ts.Samples {
+			if math.IsNaN(s.Value) {
+				continue
+			}
+			t := now
+			if s.Timestamp > 0 {
+				t = time.Unix(0, s.Timestamp*int64(time.Millisecond))
+			}
+			fields := map[string]interface{}{name: s.Value}
+			metrics = append(metrics, metric.New(measurement, tags, fields, t, md.valueType()))
+		}
+	}
+	return metrics, nil
+}
+
+// receiveDelivered frees a slot for each request whose metrics have been
+// written or dropped by the outputs.
+func (p *PrometheusRemoteWrite) receiveDelivered() {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case info := <-p.acc.Delivered():
+			<-p.sem
+			if !info.Delivered() {
+				p.Log.Debug("Metrics of a request failed to process")
+			}
+		}
+	}
+}
+
+func (p *PrometheusRemoteWrite) authenticated(req *http.Request) bool {
+	if p.BasicUsername == "" && p.BasicPassword == "" {
+		return true
+	}
+	username, password, ok := req.BasicAuth()
+	return ok &&
+		subtle.ConstantTimeCompare([]byte(username), []byte(p.BasicUsername)) == 1 &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(p.BasicPassword)) == 1
+}
+
+func init() {
+	inputs.Add("prometheus_remote_write", func() telegraf.Input {
+		return &PrometheusRemoteWrite{
+			ServiceAddress:         ":9201",
+			Path:                   "/api/v1/write",
+			MaxUndeliveredRequests: defaultMaxUndeliveredRequests,
+		}
+	})
+}
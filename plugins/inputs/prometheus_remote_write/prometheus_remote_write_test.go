@@ -0,0 +1,302 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func newTestListener(t *testing.T) *PrometheusRemoteWrite {
+	p := &PrometheusRemoteWrite{
+		ServiceAddress:         "localhost:0",
+		Path:                   "/api/v1/write",
+		MaxUndeliveredRequests: defaultMaxUndeliveredRequests,
+		Log:                    testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	return p
+}
+
+func (p *PrometheusRemoteWrite) url() string {
+	return "http://localhost:" + strconv.Itoa(p.port) + p.Path
+}
+
+// encode encodes a write request with the metadata of the families.
+func encode(t *testing.T, series []prompb.TimeSeries, families []metadata) []byte {
+	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: series})
+	require.NoError(t, err)
+
+	for _, md := range families {
+		var b []byte
+		for v, name := range metricTypes {
+			if name == md.mtype {
+				b = protowire.AppendTag(b, metadataType, protowire.VarintType)
+				b = protowire.AppendVarint(b, uint64(v))
+			}
+		}
+		b = protowire.AppendTag(b, metadataMetricFamilyName, protowire.BytesType)
+		b = protowire.AppendString(b, md.family)
+		b = protowire.AppendTag(b, metadataHelp, protowire.BytesType)
+		b = protowire.AppendString(b, md.help)
+		b = protowire.AppendTag(b, metadataUnit, protowire.BytesType)
+		b = protowire.AppendString(b, md.unit)
+
+		data = protowire.AppendTag(data, writeRequestMetadata, protowire.BytesType)
+		data = protowire.AppendBytes(data, b)
+	}
+	return snappy.Encode(nil, data)
+}
+
+func post(t *testing.T, url string, body []byte) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestWrite(t *testing.T) {
+	p := newTestListener(t)
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	series := []prompb.TimeSeries{
+		{
+			Labels: []prompb.Label{{Name: "__name__", Value: "go_gc_duration_seconds"}, {Name: "quantile", Value: "0.99"}},
+			Samples: []prompb.Sample{
+				{Value: 4.63, Timestamp: 1614889298859},
+				{Value: math.NaN(), Timestamp: 1614889299859},
+			},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "job", Value: "node"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: 1614889298859}},
+		},
+	}
+	resp := post(t, p.url(), encode(t, series, nil))
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus_remote_write",
+			map[string]string{"quantile": "0.99"},
+			map[string]interface{}{"go_gc_duration_seconds": 4.63},
+			time.Unix(0, 1614889298859*int64(time.Millisecond))),
+		testutil.MustMetric("prometheus_remote_write",
+			map[string]string{"job": "node"},
+			map[string]interface{}{"up": 1.0},
+			time.Unix(0, 1614889298859*int64(time.Millisecond))),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWriteMetadata(t *testing.T) {
+	p := newTestListener(t)
+	p.MetadataTags = []string{"type", "unit"}
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	// Prometheus sends the metadata in requests without series
+	families := []metadata{
+		{family: "http_requests", mtype: "counter", help: "Number of requests.", unit: "requests"},
+		{family: "request_duration_seconds", mtype: "histogram", help: "Duration.", unit: "seconds"},
+	}
+	resp := post(t, p.url(), encode(t, nil, families))
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Empty(t, acc.GetTelegrafMetrics())
+
+	ts := time.Unix(1622745000, 0)
+	series := []prompb.TimeSeries{
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "http_requests_total"}, {Name: "unit", Value: "calls"}},
+			Samples: []prompb.Sample{{Value: 1027, Timestamp: ts.UnixNano() / 1e6}},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "request_duration_seconds_bucket"}, {Name: "le", Value: "0.5"}},
+			Samples: []prompb.Sample{{Value: 8, Timestamp: ts.UnixNano() / 1e6}},
+		},
+		{
+			Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+			Samples: []prompb.Sample{{Value: 1, Timestamp: ts.UnixNano() / 1e6}},
+		},
+	}
+	resp = post(t, p.url(), encode(t, series, nil))
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus_remote_write",
+			map[string]string{"type": "counter", "unit": "calls"},
+			map[string]interface{}{"http_requests_total": 1027.0},
+			ts, telegraf.Counter),
+		testutil.MustMetric("prometheus_remote_write",
+			map[string]string{"type": "histogram", "unit": "seconds", "le": "0.5"},
+			map[string]interface{}{"request_duration_seconds_bucket": 8.0},
+			ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus_remote_write",
+			map[string]string{},
+			map[string]interface{}{"up": 1.0},
+			ts),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestWriteStatusCodes(t *testing.T) {
+	p := newTestListener(t)
+	p.MaxBodySize = config.Size(1024)
+	p.BasicUsername = "user"
+	p.BasicPassword = "secret"
+	acc := &testutil.Accumulator{}
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	valid := encode(t, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1}},
+	}}, nil)
+	unnamed := encode(t, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "job", Value: "node"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1}},
+	}}, nil)
+	large := encode(t, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "padding", Value: string(make([]byte, 2048))}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1}},
+	}}, nil)
+
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		encoding string
+		ctype    string
+		auth     bool
+		body     []byte
+		status   int
+	}{
+		{name: "valid", body: valid, status: http.StatusNoContent},
+		{name: "versioned content type", ctype: "application/x-protobuf;proto=prometheus.WriteRequest", body: valid, status: http.StatusNoContent},
+		{name: "unauthorized", auth: false, body: valid, status: http.StatusUnauthorized},
+		{name: "not found", path: "/write", body: valid, status: http.StatusNotFound},
+		{name: "method", method: http.MethodPut, body: valid, status: http.StatusMethodNotAllowed},
+		{name: "gzip", encoding: "gzip", body: valid, status: http.StatusUnsupportedMediaType},
+		{name: "json", ctype: "application/json", body: valid, status: http.StatusUnsupportedMediaType},
+		{name: "remote write 2.0", ctype: "application/x-protobuf;proto=io.prometheus.write.v2.Request", body: valid, status: http.StatusUnsupportedMediaType},
+		{name: "not snappy", body: []byte("up 1"), status: http.StatusBadRequest},
+		{name: "not protobuf", body: snappy.Encode(nil, []byte("up 1")), status: http.StatusBadRequest},
+		{name: "no metric name", body: unnamed, status: http.StatusBadRequest},
+		{name: "too large", body: large, status: http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.method == "" {
+				tt.method = http.MethodPost
+			}
+			if tt.path == "" {
+				tt.path = p.Path
+			}
+			if tt.encoding == "" {
+				tt.encoding = "snappy"
+			}
+			if tt.ctype == "" {
+				tt.ctype = "application/x-protobuf"
+			}
+			if tt.status != http.StatusUnauthorized {
+				tt.auth = true
+			}
+
+			req, err := http.NewRequest(tt.method, "http://localhost:"+strconv.Itoa(p.port)+tt.path, bytes.NewReader(tt.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Encoding", tt.encoding)
+			req.Header.Set("Content-Type", tt.ctype)
+			if tt.auth {
+				req.SetBasicAuth("user", "secret")
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, tt.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestWriteBackpressure(t *testing.T) {
+	p := newTestListener(t)
+	p.MaxUndeliveredRequests = 1
+	p.WriteTimeout = config.Duration(200 * time.Millisecond)
+
+	dst := make(chan telegraf.Metric, 10)
+	acc := agent.NewAccumulator(&testMetricMaker{}, dst)
+	require.NoError(t, p.Start(acc))
+	defer p.Stop()
+
+	body := encode(t, []prompb.TimeSeries{{
+		Labels:  []prompb.Label{{Name: "__name__", Value: "up"}},
+		Samples: []prompb.Sample{{Value: 1, Timestamp: 1}},
+	}}, nil)
+
+	resp := post(t, p.url(), body)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// The outputs have not written the metrics of the first request yet
+	resp = post(t, p.url(), body)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	m := <-dst
+	m.Accept()
+
+	require.Eventually(t, func() bool {
+		return post(t, p.url(), body).StatusCode == http.StatusNoContent
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestDecodeMetadataInvalid(t *testing.T) {
+	_, err := decodeMetadata([]byte{0x1a, 0x05, 0x08})
+	require.Error(t, err)
+
+	var b []byte
+	b = protowire.AppendTag(b, writeRequestMetadata, protowire.BytesType)
+	b = protowire.AppendBytes(b, protowire.AppendVarint(protowire.AppendTag(nil, metadataType, protowire.VarintType), 1))
+	_, err = decodeMetadata(b)
+	require.Error(t, err)
+}
+
+func TestInitInvalidMetadataTag(t *testing.T) {
+	p := &PrometheusRemoteWrite{MaxUndeliveredRequests: 1, MetadataTags: []string{"family"}}
+	require.Error(t, p.Init())
+}
+
+type testMetricMaker struct{}
+
+func (tm *testMetricMaker) Name() string {
+	return "TestPlugin"
+}
+
+func (tm *testMetricMaker) LogName() string {
+	return tm.Name()
+}
+
+func (tm *testMetricMaker) MakeMetric(metric telegraf.Metric) telegraf.Metric {
+	return metric
+}
+
+func (tm *testMetricMaker) Log() telegraf.Logger {
+	return models.NewLogger("test", "test", "")
+}
@@ -2,9 +2,11 @@ package socket_listener
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/tls"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"strconv"
@@ -15,11 +17,19 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/framing"
 	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
+// defaultMaxFrameSize is the default maximum size of a length-prefixed frame.
+const defaultMaxFrameSize = 16 * 1024 * 1024
+
+// defaultDecompressionRatio bounds the decompressed size of a frame by
+// default, as a multiple of the maximum frame size.
+const defaultDecompressionRatio = 4
+
 type setReadBufferer interface {
 	SetReadBuffer(bytes int) error
 }
@@ -119,11 +129,21 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	defer ssl.removeConnection(c)
 	defer c.Close()
 
+	if ssl.Framing == framing.LengthPrefixed {
+		ssl.readFrames(c)
+		return
+	}
+
 	decoder, err := internal.NewStreamContentDecoder(ssl.ContentEncoding, c)
 	if err != nil {
 		ssl.Log.Error("Read error: %v", err)
 		return
 	}
+	if closer, ok := decoder.(io.Closer); ok {
+		// Ignore the returned error as we cannot do anything about it anyway
+		//nolint:errcheck,revive
+		defer closer.Close()
+	}
 
 	scnr := bufio.NewScanner(decoder)
 	for {
@@ -159,6 +179,63 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	}
 }
 
+// readFrames reads length-prefixed frames, each decoded and parsed as a
+// whole, and acknowledges them if needed.
+func (ssl *streamSocketListener) readFrames(c net.Conn) {
+	r := bufio.NewReader(c)
+	for {
+		if ssl.ReadTimeout != nil && *ssl.ReadTimeout > 0 {
+			if err := c.SetReadDeadline(time.Now().Add(time.Duration(*ssl.ReadTimeout))); err != nil {
+				ssl.Log.Errorf("setting read deadline failed: %v", err)
+				return
+			}
+		}
+
+		frame, err := framing.ReadFrame(r, int(ssl.MaxFrameSize))
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				ssl.Log.Debugf("Timeout in plugin: %s", err.Error())
+			} else if err != io.EOF && !strings.HasSuffix(err.Error(), ": use of closed network connection") {
+				ssl.Log.Errorf("Unable to read frame: %s", err.Error())
+			}
+			return
+		}
+
+		answer := framing.Ack
+		metrics, err := ssl.parseFrame(frame)
+		if err != nil {
+			ssl.Log.Errorf("Unable to parse incoming frame: %s", err.Error())
+			answer = framing.Nak
+		}
+		for _, m := range metrics {
+			ssl.AddMetric(m)
+		}
+
+		if ssl.Acknowledge {
+			if _, err := c.Write([]byte{answer}); err != nil {
+				ssl.Log.Errorf("Unable to acknowledge frame: %s", err.Error())
+				return
+			}
+		}
+	}
+}
+
+// parseFrame decodes the payload of a frame, up to the maximum decompressed
+// size, and parses it.
+func (ssl *streamSocketListener) parseFrame(frame []byte) ([]telegraf.Metric, error) {
+	r, err := internal.NewLimitedStreamContentDecoder(ssl.ContentEncoding, bytes.NewReader(frame), int64(ssl.MaxDecompressedSize))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return ssl.Parse(body)
+}
+
 type packetSocketListener struct {
 	net.PacketConn
 	*SocketListener
@@ -194,13 +271,17 @@ func (psl *packetSocketListener) listen() {
 }
 
 type SocketListener struct {
-	ServiceAddress  string           `toml:"service_address"`
-	MaxConnections  int              `toml:"max_connections"`
-	ReadBufferSize  config.Size      `toml:"read_buffer_size"`
-	ReadTimeout     *config.Duration `toml:"read_timeout"`
-	KeepAlivePeriod *config.Duration `toml:"keep_alive_period"`
-	SocketMode      string           `toml:"socket_mode"`
-	ContentEncoding string           `toml:"content_encoding"`
+	ServiceAddress      string           `toml:"service_address"`
+	MaxConnections      int              `toml:"max_connections"`
+	ReadBufferSize      config.Size      `toml:"read_buffer_size"`
+	ReadTimeout         *config.Duration `toml:"read_timeout"`
+	KeepAlivePeriod     *config.Duration `toml:"keep_alive_period"`
+	SocketMode          string           `toml:"socket_mode"`
+	ContentEncoding     string           `toml:"content_encoding"`
+	Framing             string           `toml:"framing"`
+	MaxFrameSize        config.Size      `toml:"max_frame_size"`
+	MaxDecompressedSize config.Size      `toml:"max_decompressed_size"`
+	Acknowledge         bool             `toml:"acknowledge"`
 	tlsint.ServerConfig
 
 	wg sync.WaitGroup
@@ -272,9 +353,25 @@ func (sl *SocketListener) SampleConfig() string {
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_INPUT.md
   # data_format = "influx"
 
-  ## Content encoding for message payloads, can be set to "gzip" to or
-  ## "identity" to apply no encoding.
+  ## Content encoding for message payloads, can be set to "gzip", "zstd" or
+  ## to "identity" to apply no encoding.
   # content_encoding = "identity"
+
+  ## Framing of the messages on stream sockets (e.g. TCP), can be set to
+  ## "length_prefixed" to read frames prefixed with their length, each
+  ## parsed as a whole, or to "none" to read a plain stream of lines.
+  # framing = "none"
+
+  ## Maximum size of a frame, larger frames close the connection.
+  # max_frame_size = "16MiB"
+
+  ## Maximum size of the payload of a frame once decompressed, larger frames
+  ## are rejected.  Defaults to four times max_frame_size.
+  # max_decompressed_size = "64MiB"
+
+  ## Acknowledge each frame once its metrics are added, or reject it if it
+  ## cannot be parsed.  Requires the "length_prefixed" framing.
+  # acknowledge = false
 `
 }
 
@@ -296,6 +393,25 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 	protocol := spl[0]
 	addr := spl[1]
 
+	if err := framing.Validate(sl.Framing); err != nil {
+		return err
+	}
+	framed := sl.Framing == framing.LengthPrefixed
+	if sl.Acknowledge && !framed {
+		return fmt.Errorf("acknowledge requires the %q framing", framing.LengthPrefixed)
+	}
+	if sl.MaxFrameSize == 0 {
+		sl.MaxFrameSize = config.Size(defaultMaxFrameSize)
+	}
+	if sl.MaxDecompressedSize == 0 {
+		sl.MaxDecompressedSize = defaultDecompressionRatio * sl.MaxFrameSize
+	}
+	if framed {
+		if _, err := internal.NewContentDecoder(sl.ContentEncoding); err != nil {
+			return err
+		}
+	}
+
 	if protocol == "unix" || protocol == "unixpacket" || protocol == "unixgram" {
 		// no good way of testing for "file does not exist".
 		// Instead just ignore error and blow up when we try to listen, which will
@@ -350,6 +466,10 @@ func (sl *SocketListener) Start(acc telegraf.Accumulator) error {
 			ssl.listen()
 		}()
 	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
+		if framed {
+			return fmt.Errorf("framing is not supported on %s sockets", protocol)
+		}
+
 		decoder, err := internal.NewContentDecoder(sl.ContentEncoding)
 		if err != nil {
 			return err
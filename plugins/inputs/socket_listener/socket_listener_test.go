@@ -15,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/framing"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
@@ -208,6 +209,104 @@ func TestSocketListenerDecode_tcp(t *testing.T) {
 	testSocketListener(t, sl, client)
 }
 
+func TestSocketListenerDecodeZstd_tcp(t *testing.T) {
+	defer testEmptyLog(t)()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.ContentEncoding = "zstd"
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	testSocketListener(t, sl, client)
+}
+
+func TestSocketListenerFraming_tcp(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.ContentEncoding = "gzip"
+	sl.Framing = framing.LengthPrefixed
+	sl.MaxFrameSize = config.Size(1024)
+	sl.MaxDecompressedSize = config.Size(4096)
+	sl.Acknowledge = true
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+	defer client.Close()
+
+	encoder, err := internal.NewContentEncoder("gzip")
+	require.NoError(t, err)
+	answer := make([]byte, 1)
+
+	// Each frame is parsed as a whole, and acknowledged once added
+	payload, err := encoder.Encode([]byte("test,foo=bar v=1i 123456789\ntest,foo=baz v=2i 123456790"))
+	require.NoError(t, err)
+	require.NoError(t, framing.WriteFrame(client, payload))
+	_, err = io.ReadFull(client, answer)
+	require.NoError(t, err)
+	require.Equal(t, framing.Ack, answer[0])
+	require.Equal(t, 2, len(acc.GetTelegrafMetrics()))
+
+	// Invalid frames are rejected
+	payload, err = encoder.Encode([]byte("not line protocol"))
+	require.NoError(t, err)
+	require.NoError(t, framing.WriteFrame(client, payload))
+	_, err = io.ReadFull(client, answer)
+	require.NoError(t, err)
+	require.Equal(t, framing.Nak, answer[0])
+
+	require.NoError(t, framing.WriteFrame(client, []byte("not gzip")))
+	_, err = io.ReadFull(client, answer)
+	require.NoError(t, err)
+	require.Equal(t, framing.Nak, answer[0])
+
+	// Frames too large once decompressed are rejected
+	payload, err = encoder.Encode(bytes.Repeat([]byte("test,foo=bar v=1i 123456789\n"), 1000))
+	require.NoError(t, err)
+	require.Less(t, len(payload), 1024)
+	require.NoError(t, framing.WriteFrame(client, payload))
+	_, err = io.ReadFull(client, answer)
+	require.NoError(t, err)
+	require.Equal(t, framing.Nak, answer[0])
+
+	// Frames too large close the connection
+	require.NoError(t, framing.WriteFrame(client, make([]byte, 2048)))
+	_, err = io.ReadFull(client, answer)
+	require.Error(t, err)
+	require.Equal(t, 2, len(acc.GetTelegrafMetrics()))
+}
+
+func TestSocketListenerFraming_invalid(t *testing.T) {
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "udp://127.0.0.1:0"
+	sl.Framing = framing.LengthPrefixed
+	require.Error(t, sl.Start(&testutil.Accumulator{}))
+
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Framing = framing.None
+	sl.Acknowledge = true
+	require.Error(t, sl.Start(&testutil.Accumulator{}))
+
+	sl.Framing = framing.LengthPrefixed
+	sl.Acknowledge = false
+	sl.ContentEncoding = "deflate"
+	require.Error(t, sl.Start(&testutil.Accumulator{}))
+}
+
 func TestSocketListenerDecode_udp(t *testing.T) {
 	defer testEmptyLog(t)()
 
@@ -232,7 +331,7 @@ func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
 	mstr12 := []byte("test,foo=bar v=1i 123456789\ntest,foo=baz v=2i 123456790\n")
 	mstr3 := []byte("test,foo=zab v=3i 123456791\n")
 
-	if sl.ContentEncoding == "gzip" {
+	if sl.ContentEncoding != "" {
 		encoder, err := internal.NewContentEncoder(sl.ContentEncoding)
 		require.NoError(t, err)
 		mstr12, err = encoder.Encode(mstr12)
@@ -0,0 +1,178 @@
+package starlark
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// maxBodySize limits the size of the bodies read by the http and file
+// modules.
+const maxBodySize = 32 * 1024 * 1024
+
+// httpModule builds a module for sending requests to the allowed hosts.
+func (s *Starlark) httpModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "http",
+		Members: starlark.StringDict{
+			"get": starlark.NewBuiltin("http.get", s.httpGet),
+		},
+	}
+}
+
+// http.get(url, headers={}) sends a GET request and returns a struct with the
+// status_code, headers and body of the response.
+func (s *Starlark) httpGet(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rawURL string
+	var headers *starlark.Dict
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "url", &rawURL, "headers?", &headers); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%s: unsupported scheme %q", b.Name(), u.Scheme)
+	}
+	if err := s.checkHost(u); err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if headers != nil {
+		for _, item := range headers.Items() {
+			k, ok := starlark.AsString(item[0])
+			if !ok {
+				return nil, fmt.Errorf("%s: header name must be of type 'str'", b.Name())
+			}
+			v, ok := starlark.AsString(item[1])
+			if !ok {
+				return nil, fmt.Errorf("%s: header value must be of type 'str'", b.Name())
+			}
+			req.Header.Set(k, v)
+		}
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	body, err := readAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+
+	respHeaders := starlark.NewDict(len(resp.Header))
+	for k := range resp.Header {
+		if err := respHeaders.SetKey(starlark.String(k), starlark.String(resp.Header.Get(k))); err != nil {
+			return nil, err
+		}
+	}
+
+	return starlarkstruct.FromStringDict(starlarkstruct.Default, starlark.StringDict{
+		"status_code": starlark.MakeInt(resp.StatusCode),
+		"headers":     respHeaders,
+		"body":        starlark.String(body),
+	}), nil
+}
+
+// checkHost fails if the host of the URL is not allowed.
+func (s *Starlark) checkHost(u *url.URL) error {
+	for _, host := range s.AllowedHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not allowed", u.Host)
+}
+
+// fileModule builds a module for reading the allowed files.
+func (s *Starlark) fileModule() *starlarkstruct.Module {
+	return &starlarkstruct.Module{
+		Name: "file",
+		Members: starlark.StringDict{
+			"read": starlark.NewBuiltin("file.read", s.fileRead),
+		},
+	}
+}
+
+// file.read(path) returns the content of the file.
+func (s *Starlark) fileRead(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var path string
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &path); err != nil {
+		return nil, err
+	}
+
+	path, err := s.checkPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	defer f.Close()
+
+	data, err := readAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	return starlark.String(data), nil
+}
+
+// checkPath resolves the path and fails if it is not one of the allowed paths
+// or within one of the allowed directories.
+func (s *Starlark) checkPath(path string) (string, error) {
+	resolved, err := filepath.Abs(path)
+	if err == nil {
+		resolved, err = filepath.EvalSymlinks(resolved)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	for _, allowed := range s.AllowedPaths {
+		allowed, err := filepath.Abs(allowed)
+		if err == nil {
+			allowed, err = filepath.EvalSymlinks(allowed)
+		}
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(allowed, resolved)
+		if err != nil {
+			continue
+		}
+		if rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("path %q is not allowed", path)
+}
+
+func readAll(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxBodySize {
+		return nil, fmt.Errorf("size exceeds %d bytes", maxBodySize)
+	}
+	return data, nil
+}
@@ -0,0 +1,165 @@
+package starlark
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/influxdata/telegraf"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"go.starlark.net/starlark"
+)
+
+const (
+	description  = "Gather metrics using a Starlark script"
+	sampleConfig = `
+  ## The Starlark source can be set as a string in this configuration file, or
+  ## by referencing a file containing the script.  Only one source or script
+  ## should be set at once.
+  ##
+  ## Source of the Starlark script.
+  source = '''
+load("http.star", "http")
+load("json.star", "json")
+
+def gather():
+  resp = http.get("http://localhost:8080/status")
+  status = json.decode(resp.body)
+  m = Metric("status")
+  m.fields["requests"] = status["requests"]
+  return m
+'''
+
+  ## File containing a Starlark script.
+  # script = "/usr/local/bin/myscript.star"
+
+  ## The constants of the Starlark script.
+  # [inputs.starlark.constants]
+  #   max_size = 10
+  #   threshold = 0.75
+  #   default_name = "Julia"
+  #   debug_mode = true
+
+  ## Hosts the script is allowed to send requests to with the http module,
+  ## as "host" or "host:port".  Requests to any other host fail.
+  allowed_hosts = ["localhost:8080"]
+
+  ## Files and directories the script is allowed to read with the file
+  ## module.  Reading any other file fails.
+  # allowed_paths = ["/var/lib/myapp"]
+
+  ## HTTP request timeout.
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+)
+
+type Starlark struct {
+	common.StarlarkCommon
+
+	AllowedHosts []string `toml:"allowed_hosts"`
+	AllowedPaths []string `toml:"allowed_paths"`
+	httpconfig.HTTPClientConfig
+
+	client *http.Client
+}
+
+func (s *Starlark) Init() error {
+	client, err := s.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return s.checkHost(req.URL)
+	}
+	s.client = client
+
+	// Provide the modules of the input in addition to the common ones.
+	s.StarlarkLoadFunc = s.loadFunc
+
+	// Execute source
+	err = s.StarlarkCommon.Init()
+	if err != nil {
+		return err
+	}
+
+	// The source should define a gather function.
+	return s.AddFunction("gather")
+}
+
+func (s *Starlark) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Starlark) Description() string {
+	return description
+}
+
+func (s *Starlark) Gather(acc telegraf.Accumulator) error {
+	rv, err := s.Call("gather")
+	if err != nil {
+		s.LogError(err)
+		return err
+	}
+
+	switch rv := rv.(type) {
+	case *starlark.List:
+		iter := rv.Iterate()
+		defer iter.Done()
+		var v starlark.Value
+		for iter.Next(&v) {
+			switch v := v.(type) {
+			case *common.Metric:
+				addMetric(acc, v)
+			default:
+				acc.AddError(fmt.Errorf("invalid type returned in list: %s", v.Type()))
+			}
+		}
+	case *common.Metric:
+		addMetric(acc, rv)
+	case starlark.NoneType:
+		// Nothing gathered
+	default:
+		return fmt.Errorf("invalid type returned: %T", rv)
+	}
+	return nil
+}
+
+// addMetric adds a copy of the metric, as the script might keep the metric
+// in its state and modify it on the next gather.
+func addMetric(acc telegraf.Accumulator, m *common.Metric) {
+	acc.AddMetric(m.Unwrap().Copy())
+}
+
+func (s *Starlark) loadFunc(module string, logger telegraf.Logger) (starlark.StringDict, error) {
+	switch module {
+	case "http.star":
+		return starlark.StringDict{
+			"http": s.httpModule(),
+		}, nil
+	case "file.star":
+		return starlark.StringDict{
+			"file": s.fileModule(),
+		}, nil
+	default:
+		return common.LoadFunc(module, logger)
+	}
+}
+
+func init() {
+	inputs.Add("starlark", func() telegraf.Input {
+		return &Starlark{}
+	})
+}
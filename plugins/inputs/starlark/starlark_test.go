@@ -0,0 +1,305 @@
+package starlark
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/influxdata/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func newStarlark(source string) *Starlark {
+	return &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source: source,
+			Log:    testutil.Logger{},
+		},
+	}
+}
+
+func TestInitError(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{
+			name:   "no source",
+			source: "",
+		},
+		{
+			name: "gather must be defined",
+			source: `
+def apply(metric):
+  return metric
+`,
+		},
+		{
+			name: "gather must not take arguments",
+			source: `
+def gather(metric):
+  return metric
+`,
+		},
+		{
+			name: "unknown module",
+			source: `
+load("os.star", "os")
+
+def gather():
+  return None
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, newStarlark(tt.source).Init())
+		})
+	}
+}
+
+func TestGather(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected []telegraf.Metric
+		errors   int
+	}{
+		{
+			name: "single metric",
+			source: `
+def gather():
+  m = Metric("cpu")
+  m.fields["value"] = 42
+  m.time = 0
+  return m
+`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0)),
+			},
+		},
+		{
+			name: "list of metrics",
+			source: `
+def gather():
+  metrics = []
+  for i in range(2):
+    m = Metric("cpu")
+    m.tags["cpu"] = "cpu%d" % i
+    m.fields["value"] = i
+    m.time = 0
+    metrics.append(m)
+  return metrics
+`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"}, map[string]interface{}{"value": 0}, time.Unix(0, 0)),
+				testutil.MustMetric("cpu", map[string]string{"cpu": "cpu1"}, map[string]interface{}{"value": 1}, time.Unix(0, 0)),
+			},
+		},
+		{
+			name: "none",
+			source: `
+def gather():
+  return None
+`,
+		},
+		{
+			name: "invalid type in list",
+			source: `
+def gather():
+  m = Metric("cpu")
+  m.fields["value"] = 42
+  m.time = 0
+  return [m, 42]
+`,
+			expected: []telegraf.Metric{
+				testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 42}, time.Unix(0, 0)),
+			},
+			errors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newStarlark(tt.source)
+			require.NoError(t, plugin.Init())
+
+			var acc testutil.Accumulator
+			require.NoError(t, plugin.Gather(&acc))
+			testutil.RequireMetricsEqual(t, tt.expected, acc.GetTelegrafMetrics())
+			require.Len(t, acc.Errors, tt.errors)
+		})
+	}
+}
+
+func TestGatherError(t *testing.T) {
+	plugin := newStarlark(`
+def gather():
+  fail("gather failed")
+`)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	err := plugin.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "gather failed")
+
+	plugin = newStarlark(`
+def gather():
+  return 42
+`)
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Gather(&acc))
+}
+
+func TestGatherState(t *testing.T) {
+	plugin := newStarlark(`
+state = {"count": 0}
+
+def gather():
+  state["count"] += 1
+  m = Metric("gathers")
+  m.fields["count"] = state["count"]
+  m.time = 0
+  return m
+`)
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	require.NoError(t, plugin.Gather(&acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("gathers", map[string]string{}, map[string]interface{}{"count": 1}, time.Unix(0, 0)),
+		testutil.MustMetric("gathers", map[string]string{}, map[string]interface{}{"count": 2}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/queue/a":
+			w.Write([]byte(`{"length": 3, "capacity": 10}`))
+		case "/queue/b":
+			w.Write([]byte(`{"length": 7, "capacity": 10}`))
+		case "/redirect":
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+	u, err := url.Parse(ts.URL)
+	require.NoError(t, err)
+
+	plugin := &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Script:    "testdata/http_json.star",
+			Constants: map[string]interface{}{"base_url": ts.URL},
+			Log:       testutil.Logger{},
+		},
+		AllowedHosts: []string{u.Host},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	expected := []telegraf.Metric{
+		testutil.MustMetric("queue", map[string]string{"name": "a"}, map[string]interface{}{"usage": 0.3}, time.Unix(0, 0)),
+		testutil.MustMetric("queue", map[string]string{"name": "b"}, map[string]interface{}{"usage": 0.7}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	// Hosts not in the allow list are rejected
+	plugin.AllowedHosts = []string{"example.com"}
+	err = plugin.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not allowed")
+
+	// Redirects to hosts not in the allow list are rejected
+	plugin = &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Source: `
+load("http.star", "http")
+
+def gather():
+  http.get(base_url + "/redirect")
+`,
+			Constants: map[string]interface{}{"base_url": ts.URL},
+			Log:       testutil.Logger{},
+		},
+		AllowedHosts: []string{u.Hostname()},
+	}
+	require.NoError(t, plugin.Init())
+	err = plugin.Gather(&acc)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `host "example.com" is not allowed`)
+}
+
+func TestSampleConfig(t *testing.T) {
+	plugin := &Starlark{}
+	require.NoError(t, toml.Unmarshal([]byte(plugin.SampleConfig()), plugin))
+	plugin.Log = testutil.Logger{}
+	require.NoError(t, plugin.Init())
+
+	// The host the sample script requests is allowed
+	require.Equal(t, []string{"localhost:8080"}, plugin.AllowedHosts)
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "starlark")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	allowed := filepath.Join(dir, "allowed")
+	require.NoError(t, os.Mkdir(allowed, 0755))
+	path := filepath.Join(allowed, "input.txt")
+	require.NoError(t, ioutil.WriteFile(path, []byte("a\nb\nc\n"), 0644))
+	other := filepath.Join(dir, "other.txt")
+	require.NoError(t, ioutil.WriteFile(other, []byte("secret"), 0644))
+
+	plugin := &Starlark{
+		StarlarkCommon: common.StarlarkCommon{
+			Script:    "testdata/file_lines.star",
+			Constants: map[string]interface{}{"path": path},
+			Log:       testutil.Logger{},
+		},
+		AllowedPaths: []string{allowed},
+	}
+	require.NoError(t, plugin.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, plugin.Gather(&acc))
+	expected := []telegraf.Metric{
+		testutil.MustMetric("lines", map[string]string{"file": "input.txt"}, map[string]interface{}{"count": 3}, time.Unix(0, 0)),
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics(), testutil.IgnoreTime())
+
+	rejected := []string{other, filepath.Join(allowed, "..", "other.txt"), dir}
+	link := filepath.Join(allowed, "link.txt")
+	if err := os.Symlink(other, link); err == nil {
+		rejected = append(rejected, link)
+	}
+	for _, path := range rejected {
+		plugin := &Starlark{
+			StarlarkCommon: common.StarlarkCommon{
+				Script:    "testdata/file_lines.star",
+				Constants: map[string]interface{}{"path": path},
+				Log:       testutil.Logger{},
+			},
+			AllowedPaths: []string{allowed},
+		}
+		require.NoError(t, plugin.Init())
+		err := plugin.Gather(&acc)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "is not allowed")
+	}
+}
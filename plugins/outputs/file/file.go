@@ -24,6 +24,14 @@ type File struct {
 	writer     io.Writer
 	closers    []io.Closer
 	serializer serializers.Serializer
+
+	// Writers of stateful serializers, which use a serializer per file.
+	statefulWriters []statefulWriter
+}
+
+type statefulWriter struct {
+	writer     io.Writer
+	serializer serializers.StatefulSerializer
 }
 
 var sampleConfig = `
@@ -65,9 +73,17 @@ func (f *File) Connect() error {
 		f.Files = []string{"stdout"}
 	}
 
+	stateful, isStateful := f.serializer.(serializers.StatefulSerializer)
+
 	for _, file := range f.Files {
+		var writer io.Writer
+		var serializer serializers.StatefulSerializer
+		if isStateful {
+			serializer = stateful.Copy()
+		}
+
 		if file == "stdout" {
-			writers = append(writers, os.Stdout)
+			writer = os.Stdout
 		} else {
 			of, err := rotate.NewFileWriter(
 				file, time.Duration(f.RotationInterval), int64(f.RotationMaxSize), f.RotationMaxArchives)
@@ -75,9 +91,26 @@ func (f *File) Connect() error {
 				return err
 			}
 
-			writers = append(writers, of)
+			if isStateful {
+				// Continue an existing file without repeating its CSV header,
+				// and start each new file with a cleared state to write it
+				// again.
+				if info, err := os.Stat(file); err == nil && info.Size() > 0 {
+					serializer.Resume()
+				}
+				if fw, ok := of.(*rotate.FileWriter); ok {
+					fw.OnRotate(serializer.Reset)
+				}
+			}
+
+			writer = of
 			f.closers = append(f.closers, of)
 		}
+
+		if isStateful {
+			f.statefulWriters = append(f.statefulWriters, statefulWriter{writer: writer, serializer: serializer})
+		}
+		writers = append(writers, writer)
 	}
 	f.writer = io.MultiWriter(writers...)
 	return nil
@@ -103,26 +136,40 @@ func (f *File) Description() string {
 }
 
 func (f *File) Write(metrics []telegraf.Metric) error {
+	if len(f.statefulWriters) == 0 {
+		return f.write(f.writer, f.serializer, metrics)
+	}
+
+	var writeErr error
+	for _, w := range f.statefulWriters {
+		if err := f.write(w.writer, w.serializer, metrics); err != nil {
+			writeErr = err
+		}
+	}
+	return writeErr
+}
+
+func (f *File) write(writer io.Writer, serializer serializers.Serializer, metrics []telegraf.Metric) error {
 	var writeErr error
 
 	if f.UseBatchFormat {
-		octets, err := f.serializer.SerializeBatch(metrics)
+		octets, err := serializer.SerializeBatch(metrics)
 		if err != nil {
 			f.Log.Errorf("Could not serialize metric: %v", err)
 		}
 
-		_, err = f.writer.Write(octets)
+		_, err = writer.Write(octets)
 		if err != nil {
 			f.Log.Errorf("Error writing to file: %v", err)
 		}
 	} else {
 		for _, metric := range metrics {
-			b, err := f.serializer.Serialize(metric)
+			b, err := serializer.Serialize(metric)
 			if err != nil {
 				f.Log.Debugf("Could not serialize metric: %v", err)
 			}
 
-			_, err = f.writer.Write(b)
+			_, err = writer.Write(b)
 			if err != nil {
 				writeErr = fmt.Errorf("failed to write message: %v", err)
 			}
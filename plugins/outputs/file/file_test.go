@@ -5,10 +5,15 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
@@ -180,6 +185,64 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileRotationStatefulSerializer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, err := serializers.NewSerializer(&serializers.Config{DataFormat: "csv", CSVHeader: true})
+	require.NoError(t, err)
+	f := File{
+		Files:               []string{filepath.Join(dir, "first.csv"), filepath.Join(dir, "second.csv")},
+		RotationMaxSize:     config.Size(30),
+		RotationMaxArchives: -1,
+		serializer:          s,
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	// The second write fills the files and rotates them, so the third one
+	// starts new files with their own header.
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, f.Write([]telegraf.Metric{m}))
+	}
+
+	for _, name := range []string{"first", "second"} {
+		archives, err := filepath.Glob(filepath.Join(dir, name+".*.csv"))
+		require.NoError(t, err)
+		require.Len(t, archives, 1)
+		validateFile(archives[0], "timestamp,name,value\n0,cpu,1\n0,cpu,1\n", t)
+		validateFile(filepath.Join(dir, name+".csv"), "timestamp,name,value\n0,cpu,1\n", t)
+	}
+}
+
+func TestFileStatefulSerializerExistingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing.csv")
+	require.NoError(t, ioutil.WriteFile(existing, []byte("timestamp,name,value\n0,cpu,1\n"), 0644))
+	empty := filepath.Join(dir, "empty.csv")
+	require.NoError(t, ioutil.WriteFile(empty, nil, 0644))
+
+	s, err := serializers.NewSerializer(&serializers.Config{DataFormat: "csv", CSVHeader: true})
+	require.NoError(t, err)
+	f := File{
+		Files:      []string{existing, empty},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+	defer f.Close()
+
+	m := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 2}, time.Unix(1, 0))
+	require.NoError(t, f.Write([]telegraf.Metric{m}))
+
+	validateFile(existing, "timestamp,name,value\n0,cpu,1\n1,cpu,2\n", t)
+	validateFile(empty, "timestamp,name,value\n1,cpu,2\n", t)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
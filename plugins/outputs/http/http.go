@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
@@ -54,8 +53,8 @@ var sampleConfig = `
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   # data_format = "influx"
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
   ## Additional HTTP headers
@@ -93,6 +92,10 @@ func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
 }
 
 func (h *HTTP) Connect() error {
+	if err := internal.ValidateContentEncoding(h.ContentEncoding); err != nil {
+		return err
+	}
+
 	if h.Method == "" {
 		h.Method = http.MethodPost
 	}
@@ -134,17 +137,11 @@ func (h *HTTP) Write(metrics []telegraf.Metric) error {
 }
 
 func (h *HTTP) write(reqBody []byte) error {
-	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
-
-	var err error
-	if h.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reqBodyBuffer)
-		if err != nil {
-			return err
-		}
-		defer rc.Close()
-		reqBodyBuffer = rc
+	reqBodyBuffer, err := internal.CompressWithEncoding(h.ContentEncoding, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return err
 	}
+	defer reqBodyBuffer.Close()
 
 	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
 	if err != nil {
@@ -157,8 +154,8 @@ func (h *HTTP) write(reqBody []byte) error {
 
 	req.Header.Set("User-Agent", internal.ProductToken())
 	req.Header.Set("Content-Type", defaultContentType)
-	if h.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if h.ContentEncoding != "" && h.ContentEncoding != "identity" {
+		req.Header.Set("Content-Encoding", h.ContentEncoding)
 	}
 	for k, v := range h.Headers {
 		if strings.ToLower(k) == "host" {
@@ -1,7 +1,6 @@
 package http
 
 import (
-	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -230,7 +229,16 @@ func TestContentType(t *testing.T) {
 	}
 }
 
-func TestContentEncodingGzip(t *testing.T) {
+func TestInvalidContentEncoding(t *testing.T) {
+	plugin := &HTTP{
+		URL:             "http://localhost:8080",
+		ContentEncoding: "deflate",
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.Error(t, plugin.Connect())
+}
+
+func TestContentEncoding(t *testing.T) {
 	ts := httptest.NewServer(http.NotFoundHandler())
 	defer ts.Close()
 
@@ -258,6 +266,22 @@ func TestContentEncodingGzip(t *testing.T) {
 			},
 			expected: "gzip",
 		},
+		{
+			name: "zstd content_encoding",
+			plugin: &HTTP{
+				URL:             u.String(),
+				ContentEncoding: "zstd",
+			},
+			expected: "zstd",
+		},
+		{
+			name: "snappy content_encoding",
+			plugin: &HTTP{
+				URL:             u.String(),
+				ContentEncoding: "snappy",
+			},
+			expected: "snappy",
+		},
 	}
 
 	for _, tt := range tests {
@@ -265,12 +289,8 @@ func TestContentEncodingGzip(t *testing.T) {
 			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				require.Equal(t, tt.expected, r.Header.Get("Content-Encoding"))
 
-				body := r.Body
-				var err error
-				if r.Header.Get("Content-Encoding") == "gzip" {
-					body, err = gzip.NewReader(r.Body)
-					require.NoError(t, err)
-				}
+				body, err := internal.NewLimitedStreamContentDecoder(r.Header.Get("Content-Encoding"), r.Body, 1024)
+				require.NoError(t, err)
 
 				payload, err := ioutil.ReadAll(body)
 				require.NoError(t, err)
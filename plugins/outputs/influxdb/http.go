@@ -468,8 +468,8 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	c.addHeaders(req)
 
-	if c.config.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if c.config.ContentEncoding != "" && c.config.ContentEncoding != "identity" {
+		req.Header.Set("Content-Encoding", c.config.ContentEncoding)
 	}
 
 	return req, nil
@@ -480,16 +480,7 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 func (c *httpClient) requestBodyReader(metrics []telegraf.Metric) (io.ReadCloser, error) {
 	reader := influx.NewReader(metrics, c.config.Serializer)
 
-	if c.config.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reader)
-		if err != nil {
-			return nil, err
-		}
-
-		return rc, nil
-	}
-
-	return ioutil.NopCloser(reader), nil
+	return internal.CompressWithEncoding(c.config.ContentEncoding, reader)
 }
 
 func (c *httpClient) addHeaders(req *http.Request) {
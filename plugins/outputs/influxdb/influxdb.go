@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
@@ -129,8 +130,8 @@ var sampleConfig = `
   ## Additional HTTP headers
   # http_headers = {"X-Special-Header" = "Special-Value"}
 
-  ## HTTP Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## HTTP Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "gzip"
 
   ## When true, Telegraf will output unsigned integers as unsigned values,
@@ -141,6 +142,10 @@ var sampleConfig = `
 `
 
 func (i *InfluxDB) Connect() error {
+	if err := internal.ValidateContentEncoding(i.ContentEncoding); err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 
 	urls := make([]string, 0, len(i.URLs))
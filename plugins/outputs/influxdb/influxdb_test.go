@@ -112,6 +112,18 @@ func TestConnectUDPConfig(t *testing.T) {
 	require.NotNil(t, actual.Serializer)
 }
 
+func TestConnectInvalidContentEncoding(t *testing.T) {
+	output := influxdb.InfluxDB{
+		URLs:            []string{"http://localhost:8086"},
+		ContentEncoding: "deflate",
+		CreateHTTPClientF: func(config *influxdb.HTTPConfig) (influxdb.Client, error) {
+			return &MockClient{}, nil
+		},
+	}
+	output.Log = testutil.Logger{}
+	require.Error(t, output.Connect())
+}
+
 func TestConnectHTTPConfig(t *testing.T) {
 	var actual *influxdb.HTTPConfig
 
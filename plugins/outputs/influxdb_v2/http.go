@@ -7,7 +7,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"log"
 	"math"
 	"net"
@@ -337,8 +336,8 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
 	c.addHeaders(req)
 
-	if c.ContentEncoding == "gzip" {
-		req.Header.Set("Content-Encoding", "gzip")
+	if c.ContentEncoding != "" && c.ContentEncoding != "identity" {
+		req.Header.Set("Content-Encoding", c.ContentEncoding)
 	}
 
 	return req, nil
@@ -349,16 +348,7 @@ func (c *httpClient) makeWriteRequest(url string, body io.Reader) (*http.Request
 func (c *httpClient) requestBodyReader(metrics []telegraf.Metric) (io.ReadCloser, error) {
 	reader := influx.NewReader(metrics, c.serializer)
 
-	if c.ContentEncoding == "gzip" {
-		rc, err := internal.CompressWithGzip(reader)
-		if err != nil {
-			return nil, err
-		}
-
-		return rc, nil
-	}
-
-	return ioutil.NopCloser(reader), nil
+	return internal.CompressWithEncoding(c.ContentEncoding, reader)
 }
 
 func (c *httpClient) addHeaders(req *http.Request) {
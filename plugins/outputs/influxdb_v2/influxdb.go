@@ -10,6 +10,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
@@ -58,8 +59,8 @@ var sampleConfig = `
   ## HTTP User-Agent
   # user_agent = "telegraf"
 
-  ## Content-Encoding for write request body, can be set to "gzip" to
-  ## compress body or "identity" to apply no encoding.
+  ## Content-Encoding for write request body, can be set to "gzip",
+  ## "zstd" or "snappy" to compress body or "identity" to apply no encoding.
   # content_encoding = "gzip"
 
   ## Enable or disable uint support for writing uints influxdb 2.0.
@@ -101,6 +102,10 @@ type InfluxDB struct {
 }
 
 func (i *InfluxDB) Connect() error {
+	if err := internal.ValidateContentEncoding(i.ContentEncoding); err != nil {
+		return err
+	}
+
 	if len(i.URLs) == 0 {
 		i.URLs = append(i.URLs, defaultURL)
 	}
@@ -32,6 +32,13 @@ func TestConnect(t *testing.T) {
 				},
 			},
 		},
+		{
+			err: true,
+			out: influxdb.InfluxDB{
+				URLs:            []string{"http://localhost:1234"},
+				ContentEncoding: "deflate",
+			},
+		},
 		{
 			err: true,
 			out: influxdb.InfluxDB{
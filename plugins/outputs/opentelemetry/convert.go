@@ -0,0 +1,440 @@
+package opentelemetry
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	otlpcollectormetrics "github.com/influxdata/influxdb-observability/otlp/collector/metrics/v1"
+	otlpcommon "github.com/influxdata/influxdb-observability/otlp/common/v1"
+	otlpmetrics "github.com/influxdata/influxdb-observability/otlp/metrics/v1"
+	otlpresource "github.com/influxdata/influxdb-observability/otlp/resource/v1"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+const (
+	// Measurement of metrics in the prometheus metric_version 2 format
+	prometheusMeasurement = "prometheus"
+
+	bucketTag    = "le"
+	quantileTag  = "quantile"
+	bucketSuffix = "_bucket"
+	sumSuffix    = "_sum"
+	countSuffix  = "_count"
+)
+
+// converter converts telegraf metrics to an OTLP export request.
+//
+// Histograms and summaries are recognized in the shapes produced by the
+// prometheus parser, with one field per bucket or quantile (metric_version 1)
+// or one metric per bucket or quantile tagged with "le" or "quantile"
+// (metric_version 2), and by the cumulative histogram aggregator.  All other
+// numeric fields are converted to gauges, or to sums if the metric is a
+// counter.
+type converter struct {
+	resourceTags map[string]bool
+	attributes   []*otlpcommon.KeyValue
+	log          telegraf.Logger
+
+	resources     map[string]*otlpmetrics.InstrumentationLibraryMetrics
+	request       *otlpcollectormetrics.ExportMetricsServiceRequest
+	metrics       map[string]*otlpmetrics.Metric
+	distributions map[string]*distribution
+	order         []*distribution
+}
+
+// distribution collects the parts of a histogram or summary data point.
+type distribution struct {
+	library     *otlpmetrics.InstrumentationLibraryMetrics
+	resourceKey string
+	name        string
+	labels      []*otlpcommon.StringKeyValue
+	time        uint64
+	summary     bool
+
+	// cumulative bucket counts by upper bound, or values by quantile
+	values   map[float64]float64
+	count    float64
+	hasCount bool
+	sum      float64
+}
+
+func newConverter(resourceTags []string, attributes map[string]string, log telegraf.Logger) *converter {
+	c := &converter{
+		resourceTags: make(map[string]bool, len(resourceTags)),
+		log:          log,
+	}
+	for _, tag := range resourceTags {
+		c.resourceTags[tag] = true
+	}
+	keys := make([]string, 0, len(attributes))
+	for k := range attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		c.attributes = append(c.attributes, stringAttribute(k, attributes[k]))
+	}
+	return c
+}
+
+// convert returns the export request for the metrics.
+func (c *converter) convert(metrics []telegraf.Metric) *otlpcollectormetrics.ExportMetricsServiceRequest {
+	c.request = &otlpcollectormetrics.ExportMetricsServiceRequest{}
+	c.resources = make(map[string]*otlpmetrics.InstrumentationLibraryMetrics)
+	c.metrics = make(map[string]*otlpmetrics.Metric)
+	c.distributions = make(map[string]*distribution)
+	c.order = nil
+
+	for _, m := range metrics {
+		c.add(m)
+	}
+	for _, d := range c.order {
+		c.addDistribution(d)
+	}
+
+	request := c.request
+	c.request = nil
+	c.resources = nil
+	c.metrics = nil
+	c.distributions = nil
+	c.order = nil
+	return request
+}
+
+func (c *converter) add(m telegraf.Metric) {
+	library, resourceKey := c.library(m)
+
+	var labels []*otlpcommon.StringKeyValue
+	var bound, quantile string
+	var hasBound, hasQuantile, hasLowerBound bool
+	for _, tag := range m.TagList() {
+		switch {
+		case c.resourceTags[tag.Key]:
+		case tag.Key == bucketTag:
+			bound, hasBound = tag.Value, true
+		case tag.Key == quantileTag && m.Type() == telegraf.Summary:
+			quantile, hasQuantile = tag.Value, true
+		default:
+			if tag.Key == "gt" {
+				hasLowerBound = true
+			}
+			labels = append(labels, &otlpcommon.StringKeyValue{Key: tag.Key, Value: tag.Value})
+		}
+	}
+	ts := uint64(m.Time().UnixNano())
+
+	// Histograms and summaries with one field per bucket or quantile
+	if (m.Type() == telegraf.Histogram || m.Type() == telegraf.Summary) && !hasBound && !hasQuantile {
+		if _, ok := m.GetField("count"); ok {
+			d := c.distribution(library, resourceKey, m.Name(), labels, ts, m.Type() == telegraf.Summary)
+			for _, field := range m.FieldList() {
+				value, ok := toFloat(field.Value)
+				if !ok {
+					continue
+				}
+				switch field.Key {
+				case "count":
+					d.count, d.hasCount = value, true
+				case "sum":
+					d.sum = value
+				default:
+					if key, ok := parseBound(field.Key); ok {
+						d.values[key] = value
+					}
+				}
+			}
+			return
+		}
+	}
+
+	for _, field := range m.FieldList() {
+		name := metricName(m.Name(), field.Key)
+		switch {
+		case hasBound && !hasLowerBound && strings.HasSuffix(field.Key, bucketSuffix):
+			key, ok := parseBound(bound)
+			value, isNumber := toFloat(field.Value)
+			if !ok || !isNumber {
+				continue
+			}
+			name = strings.TrimSuffix(name, bucketSuffix)
+			d := c.distribution(library, resourceKey, name, labels, ts, false)
+			d.values[key] = value
+		case hasQuantile:
+			key, ok := parseBound(quantile)
+			value, isNumber := toFloat(field.Value)
+			if !ok || !isNumber {
+				continue
+			}
+			d := c.distribution(library, resourceKey, name, labels, ts, true)
+			d.values[key] = value
+		case (m.Type() == telegraf.Histogram || m.Type() == telegraf.Summary) && !hasBound &&
+			(strings.HasSuffix(field.Key, sumSuffix) || strings.HasSuffix(field.Key, countSuffix)):
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			summary := m.Type() == telegraf.Summary
+			if strings.HasSuffix(field.Key, sumSuffix) {
+				d := c.distribution(library, resourceKey, strings.TrimSuffix(name, sumSuffix), labels, ts, summary)
+				d.sum = value
+			} else {
+				d := c.distribution(library, resourceKey, strings.TrimSuffix(name, countSuffix), labels, ts, summary)
+				d.count, d.hasCount = value, true
+			}
+		default:
+			if hasBound {
+				// Buckets that are not cumulative are kept as separate series
+				labels = append(labels[:len(labels):len(labels)], &otlpcommon.StringKeyValue{Key: bucketTag, Value: bound})
+				hasBound = false
+			}
+			c.addNumber(library, resourceKey, name, m.Type(), labels, ts, field.Value)
+		}
+	}
+}
+
+// library returns the metrics of the resource described by the resource tags
+// of the metric.
+func (c *converter) library(m telegraf.Metric) (*otlpmetrics.InstrumentationLibraryMetrics, string) {
+	attributes := append([]*otlpcommon.KeyValue{}, c.attributes...)
+	var key strings.Builder
+	for _, tag := range m.TagList() {
+		if c.resourceTags[tag.Key] {
+			attributes = append(attributes, stringAttribute(tag.Key, tag.Value))
+			key.WriteString(tag.Key + "=" + tag.Value + "\n")
+		}
+	}
+
+	if library, ok := c.resources[key.String()]; ok {
+		return library, key.String()
+	}
+
+	library := &otlpmetrics.InstrumentationLibraryMetrics{
+		InstrumentationLibrary: &otlpcommon.InstrumentationLibrary{
+			Name:    "telegraf",
+			Version: internal.Version(),
+		},
+	}
+	c.request.ResourceMetrics = append(c.request.ResourceMetrics, &otlpmetrics.ResourceMetrics{
+		Resource:                      &otlpresource.Resource{Attributes: attributes},
+		InstrumentationLibraryMetrics: []*otlpmetrics.InstrumentationLibraryMetrics{library},
+	})
+	c.resources[key.String()] = library
+	return library, key.String()
+}
+
+func (c *converter) distribution(library *otlpmetrics.InstrumentationLibraryMetrics, resourceKey, name string, labels []*otlpcommon.StringKeyValue, ts uint64, summary bool) *distribution {
+	key := resourceKey + "\n" + name + "\n" + labelsKey(labels) + "\n" + strconv.FormatUint(ts, 10) + "\n" + strconv.FormatBool(summary)
+	if d, ok := c.distributions[key]; ok {
+		return d
+	}
+	d := &distribution{
+		library:     library,
+		resourceKey: resourceKey,
+		name:        name,
+		labels:      labels,
+		time:        ts,
+		summary:     summary,
+		values:      make(map[float64]float64),
+	}
+	c.distributions[key] = d
+	c.order = append(c.order, d)
+	return d
+}
+
+// metric returns the metric with the name and kind of data of the resource,
+// adding it if needed.
+func (c *converter) metric(library *otlpmetrics.InstrumentationLibraryMetrics, resourceKey, name, kind string, data func() *otlpmetrics.Metric) *otlpmetrics.Metric {
+	key := resourceKey + "\n" + name + "\n" + kind
+	if m, ok := c.metrics[key]; ok {
+		return m
+	}
+	m := data()
+	m.Name = name
+	library.Metrics = append(library.Metrics, m)
+	c.metrics[key] = m
+	return m
+}
+
+// addNumber adds a gauge, or a sum for counters, data point.
+func (c *converter) addNumber(library *otlpmetrics.InstrumentationLibraryMetrics, resourceKey, name string, vt telegraf.ValueType, labels []*otlpcommon.StringKeyValue, ts uint64, value interface{}) {
+	var intValue int64
+	var isInt bool
+	switch v := value.(type) {
+	case int64:
+		intValue, isInt = v, true
+	case uint64:
+		if v > math.MaxInt64 {
+			v = math.MaxInt64
+		}
+		intValue, isInt = int64(v), true
+	case bool:
+		if v {
+			intValue = 1
+		}
+		isInt = true
+	case float64:
+	default:
+		c.log.Debugf("Skipping field %q of unsupported type %T", name, value)
+		return
+	}
+
+	counter := vt == telegraf.Counter
+	switch {
+	case isInt && counter:
+		m := c.metric(library, resourceKey, name, "intsum", func() *otlpmetrics.Metric {
+			return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_IntSum{IntSum: &otlpmetrics.IntSum{
+				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}}}
+		})
+		sum := m.GetIntSum()
+		sum.DataPoints = append(sum.DataPoints, &otlpmetrics.IntDataPoint{Labels: labels, TimeUnixNano: ts, Value: intValue})
+	case isInt:
+		m := c.metric(library, resourceKey, name, "intgauge", func() *otlpmetrics.Metric {
+			return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_IntGauge{IntGauge: &otlpmetrics.IntGauge{}}}
+		})
+		gauge := m.GetIntGauge()
+		gauge.DataPoints = append(gauge.DataPoints, &otlpmetrics.IntDataPoint{Labels: labels, TimeUnixNano: ts, Value: intValue})
+	case counter:
+		m := c.metric(library, resourceKey, name, "doublesum", func() *otlpmetrics.Metric {
+			return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_DoubleSum{DoubleSum: &otlpmetrics.DoubleSum{
+				AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+				IsMonotonic:            true,
+			}}}
+		})
+		sum := m.GetDoubleSum()
+		sum.DataPoints = append(sum.DataPoints, &otlpmetrics.DoubleDataPoint{Labels: labels, TimeUnixNano: ts, Value: value.(float64)})
+	default:
+		m := c.metric(library, resourceKey, name, "doublegauge", func() *otlpmetrics.Metric {
+			return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_DoubleGauge{DoubleGauge: &otlpmetrics.DoubleGauge{}}}
+		})
+		gauge := m.GetDoubleGauge()
+		gauge.DataPoints = append(gauge.DataPoints, &otlpmetrics.DoubleDataPoint{Labels: labels, TimeUnixNano: ts, Value: value.(float64)})
+	}
+}
+
+// addDistribution adds the histogram or summary data point.
+func (c *converter) addDistribution(d *distribution) {
+	keys := make([]float64, 0, len(d.values))
+	for k := range d.values {
+		keys = append(keys, k)
+	}
+	sort.Float64s(keys)
+
+	if d.summary {
+		dp := &otlpmetrics.DoubleSummaryDataPoint{
+			Labels:       d.labels,
+			TimeUnixNano: d.time,
+			Count:        toCount(d.count),
+			Sum:          d.sum,
+		}
+		for _, q := range keys {
+			dp.QuantileValues = append(dp.QuantileValues, &otlpmetrics.DoubleSummaryDataPoint_ValueAtQuantile{
+				Quantile: q,
+				Value:    d.values[q],
+			})
+		}
+		m := c.metric(d.library, d.resourceKey, d.name, "summary", func() *otlpmetrics.Metric {
+			return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_DoubleSummary{DoubleSummary: &otlpmetrics.DoubleSummary{}}}
+		})
+		summary := m.GetDoubleSummary()
+		summary.DataPoints = append(summary.DataPoints, dp)
+		return
+	}
+
+	// Telegraf buckets are cumulative while OTLP buckets are not
+	dp := &otlpmetrics.DoubleHistogramDataPoint{
+		Labels:       d.labels,
+		TimeUnixNano: d.time,
+		Sum:          d.sum,
+	}
+	var previous float64
+	for _, bound := range keys {
+		if math.IsInf(bound, 1) {
+			continue
+		}
+		dp.ExplicitBounds = append(dp.ExplicitBounds, bound)
+		dp.BucketCounts = append(dp.BucketCounts, toCount(d.values[bound]-previous))
+		previous = d.values[bound]
+	}
+	total := previous
+	if inf, ok := d.values[math.Inf(1)]; ok {
+		total = inf
+	}
+	if d.hasCount {
+		total = d.count
+	}
+	dp.BucketCounts = append(dp.BucketCounts, toCount(total-previous))
+	dp.Count = toCount(total)
+
+	m := c.metric(d.library, d.resourceKey, d.name, "histogram", func() *otlpmetrics.Metric {
+		return &otlpmetrics.Metric{Data: &otlpmetrics.Metric_DoubleHistogram{DoubleHistogram: &otlpmetrics.DoubleHistogram{
+			AggregationTemporality: otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE,
+		}}}
+	})
+	histogram := m.GetDoubleHistogram()
+	histogram.DataPoints = append(histogram.DataPoints, dp)
+}
+
+// metricName returns the name of the metric of a field, following the naming
+// of the prometheus parser and serializer.
+func metricName(measurement, field string) string {
+	switch {
+	case measurement == prometheusMeasurement:
+		return field
+	case field == "value" || field == "gauge" || field == "counter":
+		return measurement
+	default:
+		return measurement + "_" + field
+	}
+}
+
+func labelsKey(labels []*otlpcommon.StringKeyValue) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.Key + "=" + l.Value + "\n")
+	}
+	return b.String()
+}
+
+func stringAttribute(key, value string) *otlpcommon.KeyValue {
+	return &otlpcommon.KeyValue{
+		Key:   key,
+		Value: &otlpcommon.AnyValue{Value: &otlpcommon.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+// parseBound parses a bucket bound or quantile.
+func parseBound(s string) (float64, bool) {
+	switch strings.ToLower(s) {
+	case "+inf", "inf":
+		return math.Inf(1), true
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return v, true
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func toCount(v float64) uint64 {
+	if v < 0 || math.IsNaN(v) {
+		return 0
+	}
+	return uint64(math.Round(v))
+}
@@ -0,0 +1,267 @@
+package opentelemetry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	otlpcollectormetrics "github.com/influxdata/influxdb-observability/otlp/collector/metrics/v1"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultGRPCAddress = "localhost:4317"
+	defaultHTTPAddress = "http://localhost:4318/v1/metrics"
+	defaultTimeout     = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## Protocol used to export the metrics, "grpc" or "http" (protobuf over
+  ## HTTP).
+  # protocol = "grpc"
+
+  ## Address of the OpenTelemetry Collector, defaults to "localhost:4317" for
+  ## gRPC and "http://localhost:4318/v1/metrics" for HTTP.
+  # service_address = "localhost:4317"
+
+  ## Timeout of an export request.
+  # timeout = "5s"
+
+  ## Compression of the export requests, "gzip" or "none".
+  # compression = "gzip"
+
+  ## Tags of the metrics used as resource attributes instead of labels.
+  # resource_tags = ["host"]
+
+  ## Additional resource attributes.
+  # [outputs.opentelemetry.attributes]
+  #   "service.name" = "telegraf"
+
+  ## Additional gRPC metadata or HTTP headers.
+  # [outputs.opentelemetry.headers]
+  #   key1 = "value1"
+
+  ## Optional TLS Config.  gRPC uses TLS if any option is set, HTTP if the
+  ## service address is an https URL.
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type OpenTelemetry struct {
+	Protocol       string            `toml:"protocol"`
+	ServiceAddress string            `toml:"service_address"`
+	Timeout        config.Duration   `toml:"timeout"`
+	Compression    string            `toml:"compression"`
+	ResourceTags   []string          `toml:"resource_tags"`
+	Attributes     map[string]string `toml:"attributes"`
+	Headers        map[string]string `toml:"headers"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	converter *converter
+	exporter  exporter
+}
+
+// exporter sends export requests to the collector.
+type exporter interface {
+	export(ctx context.Context, req *otlpcollectormetrics.ExportMetricsServiceRequest) error
+	close() error
+}
+
+func (o *OpenTelemetry) SampleConfig() string {
+	return sampleConfig
+}
+
+func (o *OpenTelemetry) Description() string {
+	return "Send OpenTelemetry metrics over gRPC or HTTP"
+}
+
+func (o *OpenTelemetry) Init() error {
+	switch o.Protocol {
+	case "", "grpc":
+		o.Protocol = "grpc"
+		if o.ServiceAddress == "" {
+			o.ServiceAddress = defaultGRPCAddress
+		}
+	case "http":
+		if o.ServiceAddress == "" {
+			o.ServiceAddress = defaultHTTPAddress
+		}
+	default:
+		return fmt.Errorf("invalid protocol %q", o.Protocol)
+	}
+
+	switch o.Compression {
+	case "", "gzip", "none":
+	default:
+		return fmt.Errorf("invalid compression %q", o.Compression)
+	}
+
+	if o.Timeout <= 0 {
+		o.Timeout = config.Duration(defaultTimeout)
+	}
+
+	o.converter = newConverter(o.ResourceTags, o.Attributes, o.Log)
+	return nil
+}
+
+func (o *OpenTelemetry) Connect() error {
+	tlsConfig, err := o.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	if o.Protocol == "http" {
+		o.exporter = &httpExporter{
+			url:      o.ServiceAddress,
+			headers:  o.Headers,
+			compress: o.Compression != "none",
+			client: &http.Client{
+				Transport: &http.Transport{
+					Proxy:           http.ProxyFromEnvironment,
+					TLSClientConfig: tlsConfig,
+				},
+			},
+		}
+		return nil
+	}
+
+	var opts []grpc.DialOption
+	if tlsConfig != nil {
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		opts = append(opts, grpc.WithInsecure())
+	}
+	conn, err := grpc.Dial(o.ServiceAddress, opts...)
+	if err != nil {
+		return err
+	}
+	o.exporter = &grpcExporter{
+		conn:     conn,
+		client:   otlpcollectormetrics.NewMetricsServiceClient(conn),
+		headers:  o.Headers,
+		compress: o.Compression != "none",
+	}
+	return nil
+}
+
+func (o *OpenTelemetry) Close() error {
+	if o.exporter == nil {
+		return nil
+	}
+	err := o.exporter.close()
+	o.exporter = nil
+	return err
+}
+
+func (o *OpenTelemetry) Write(metrics []telegraf.Metric) error {
+	req := o.converter.convert(metrics)
+	if len(req.ResourceMetrics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(o.Timeout))
+	defer cancel()
+	return o.exporter.export(ctx, req)
+}
+
+type grpcExporter struct {
+	conn     *grpc.ClientConn
+	client   otlpcollectormetrics.MetricsServiceClient
+	headers  map[string]string
+	compress bool
+}
+
+func (e *grpcExporter) export(ctx context.Context, req *otlpcollectormetrics.ExportMetricsServiceRequest) error {
+	if len(e.headers) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, metadata.New(e.headers))
+	}
+	var opts []grpc.CallOption
+	if e.compress {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+	_, err := e.client.Export(ctx, req, opts...)
+	return err
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	url      string
+	headers  map[string]string
+	compress bool
+	client   *http.Client
+}
+
+func (e *httpExporter) export(ctx context.Context, req *otlpcollectormetrics.ExportMetricsServiceRequest) error {
+	body, err := proto.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if e.compress {
+		encoder, err := internal.NewContentEncoder("gzip")
+		if err != nil {
+			return err
+		}
+		if body, err = encoder.Encode(body); err != nil {
+			return err
+		}
+	}
+
+	httpReq, err := http.NewRequest("POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	httpReq.Header.Set("Content-Type", "application/x-protobuf")
+	httpReq.Header.Set("User-Agent", internal.ProductToken())
+	if e.compress {
+		httpReq.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range e.headers {
+		httpReq.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("export failed with status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	return err
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+func init() {
+	outputs.Add("opentelemetry", func() telegraf.Output {
+		return &OpenTelemetry{}
+	})
+}
@@ -0,0 +1,340 @@
+package opentelemetry
+
+import (
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	otlpcollectormetrics "github.com/influxdata/influxdb-observability/otlp/collector/metrics/v1"
+	otlpcommon "github.com/influxdata/influxdb-observability/otlp/common/v1"
+	otlpmetrics "github.com/influxdata/influxdb-observability/otlp/metrics/v1"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type metricsServer struct {
+	otlpcollectormetrics.UnimplementedMetricsServiceServer
+
+	mu       sync.Mutex
+	requests []*otlpcollectormetrics.ExportMetricsServiceRequest
+	metadata []metadata.MD
+}
+
+func (s *metricsServer) Export(ctx context.Context, req *otlpcollectormetrics.ExportMetricsServiceRequest) (*otlpcollectormetrics.ExportMetricsServiceResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	s.metadata = append(s.metadata, md)
+	return &otlpcollectormetrics.ExportMetricsServiceResponse{}, nil
+}
+
+func TestWriteGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	ms := &metricsServer{}
+	otlpcollectormetrics.RegisterMetricsServiceServer(server, ms)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	plugin := &OpenTelemetry{
+		ServiceAddress: listener.Addr().String(),
+		ResourceTags:   []string{"host"},
+		Attributes:     map[string]string{"service.name": "telegraf"},
+		Headers:        map[string]string{"x-token": "secret"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 90.5},
+			time.Unix(0, 1000),
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	require.Len(t, ms.requests, 1)
+	require.Equal(t, []string{"secret"}, ms.metadata[0].Get("x-token"))
+
+	rm := ms.requests[0].ResourceMetrics
+	require.Len(t, rm, 1)
+	require.Equal(t, []*otlpcommon.KeyValue{
+		stringAttribute("service.name", "telegraf"),
+		stringAttribute("host", "a"),
+	}, rm[0].Resource.Attributes)
+	m := rm[0].InstrumentationLibraryMetrics[0].Metrics
+	require.Len(t, m, 1)
+	require.Equal(t, "cpu_usage_idle", m[0].Name)
+	dp := m[0].GetDoubleGauge().DataPoints
+	require.Len(t, dp, 1)
+	require.Equal(t, 90.5, dp[0].Value)
+	require.Equal(t, uint64(1000), dp[0].TimeUnixNano)
+	require.Equal(t, "cpu", dp[0].Labels[0].Key)
+}
+
+func TestWriteHTTP(t *testing.T) {
+	var received *otlpcollectormetrics.ExportMetricsServiceRequest
+	var headers http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = gz
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = &otlpcollectormetrics.ExportMetricsServiceRequest{}
+		if err := proto.Unmarshal(data, received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		Protocol:       "http",
+		ServiceAddress: ts.URL + "/v1/metrics",
+		Headers:        map[string]string{"X-Token": "secret"},
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("requests",
+			map[string]string{},
+			map[string]interface{}{"counter": int64(42)},
+			time.Unix(0, 0),
+			telegraf.Counter,
+		),
+	}
+	require.NoError(t, plugin.Write(metrics))
+
+	require.Equal(t, "gzip", headers.Get("Content-Encoding"))
+	require.Equal(t, "application/x-protobuf", headers.Get("Content-Type"))
+	require.Equal(t, "secret", headers.Get("X-Token"))
+	m := received.ResourceMetrics[0].InstrumentationLibraryMetrics[0].Metrics
+	require.Equal(t, "requests", m[0].Name)
+	sum := m[0].GetIntSum()
+	require.True(t, sum.IsMonotonic)
+	require.Equal(t, otlpmetrics.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.AggregationTemporality)
+	require.Equal(t, int64(42), sum.DataPoints[0].Value)
+}
+
+func TestWriteHTTPError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "too large", http.StatusRequestEntityTooLarge)
+	}))
+	defer ts.Close()
+
+	plugin := &OpenTelemetry{
+		Protocol:       "http",
+		ServiceAddress: ts.URL,
+		Compression:    "none",
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Connect())
+	defer plugin.Close()
+
+	err := plugin.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "too large")
+}
+
+func TestInitError(t *testing.T) {
+	require.Error(t, (&OpenTelemetry{Protocol: "udp"}).Init())
+	require.Error(t, (&OpenTelemetry{Compression: "zstd"}).Init())
+}
+
+func convertMetrics(t *testing.T, metrics []telegraf.Metric) []*otlpmetrics.Metric {
+	t.Helper()
+	c := newConverter(nil, nil, testutil.Logger{})
+	req := c.convert(metrics)
+	require.Len(t, req.ResourceMetrics, 1)
+	return req.ResourceMetrics[0].InstrumentationLibraryMetrics[0].Metrics
+}
+
+func TestConvertHistogramV1(t *testing.T) {
+	m := convertMetrics(t, []telegraf.Metric{
+		testutil.MustMetric("http_request_duration_seconds",
+			map[string]string{"method": "GET"},
+			map[string]interface{}{
+				"0.1":   10.0,
+				"0.5":   15.0,
+				"1":     18.0,
+				"+Inf":  20.0,
+				"count": 20.0,
+				"sum":   7.5,
+			},
+			time.Unix(0, 0),
+			telegraf.Histogram,
+		),
+	})
+	require.Len(t, m, 1)
+	require.Equal(t, "http_request_duration_seconds", m[0].Name)
+	dp := m[0].GetDoubleHistogram().DataPoints
+	require.Len(t, dp, 1)
+	require.Equal(t, []float64{0.1, 0.5, 1}, dp[0].ExplicitBounds)
+	require.Equal(t, []uint64{10, 5, 3, 2}, dp[0].BucketCounts)
+	require.Equal(t, uint64(20), dp[0].Count)
+	require.Equal(t, 7.5, dp[0].Sum)
+	require.Equal(t, []*otlpcommon.StringKeyValue{{Key: "method", Value: "GET"}}, dp[0].Labels)
+}
+
+func TestConvertHistogramV2(t *testing.T) {
+	now := time.Unix(0, 0)
+	var metrics []telegraf.Metric
+	for _, bucket := range []struct {
+		le    string
+		count float64
+	}{{"0.1", 10}, {"0.5", 15}, {"+Inf", 20}} {
+		metrics = append(metrics, testutil.MustMetric("prometheus",
+			map[string]string{"method": "GET", "le": bucket.le},
+			map[string]interface{}{"http_request_duration_seconds_bucket": bucket.count},
+			now, telegraf.Histogram))
+	}
+	metrics = append(metrics, testutil.MustMetric("prometheus",
+		map[string]string{"method": "GET"},
+		map[string]interface{}{
+			"http_request_duration_seconds_sum":   7.5,
+			"http_request_duration_seconds_count": 20.0,
+		},
+		now, telegraf.Histogram))
+
+	m := convertMetrics(t, metrics)
+	require.Len(t, m, 1)
+	require.Equal(t, "http_request_duration_seconds", m[0].Name)
+	dp := m[0].GetDoubleHistogram().DataPoints
+	require.Len(t, dp, 1)
+	require.Equal(t, []float64{0.1, 0.5}, dp[0].ExplicitBounds)
+	require.Equal(t, []uint64{10, 5, 5}, dp[0].BucketCounts)
+	require.Equal(t, uint64(20), dp[0].Count)
+	require.Equal(t, 7.5, dp[0].Sum)
+}
+
+func TestConvertHistogramAggregator(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := convertMetrics(t, []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0", "le": "50"},
+			map[string]interface{}{"usage_idle_bucket": int64(2)}, now),
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0", "le": "100"},
+			map[string]interface{}{"usage_idle_bucket": int64(5)}, now),
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0", "le": "+Inf"},
+			map[string]interface{}{"usage_idle_bucket": int64(5)}, now),
+	})
+	require.Len(t, m, 1)
+	require.Equal(t, "cpu_usage_idle", m[0].Name)
+	dp := m[0].GetDoubleHistogram().DataPoints
+	require.Len(t, dp, 1)
+	require.Equal(t, []float64{50, 100}, dp[0].ExplicitBounds)
+	require.Equal(t, []uint64{2, 3, 0}, dp[0].BucketCounts)
+	require.Equal(t, uint64(5), dp[0].Count)
+}
+
+func TestConvertSummary(t *testing.T) {
+	now := time.Unix(0, 0)
+	v1 := convertMetrics(t, []telegraf.Metric{
+		testutil.MustMetric("rpc_duration_seconds", map[string]string{},
+			map[string]interface{}{"0.5": 0.2, "0.99": 0.9, "count": 100.0, "sum": 25.0},
+			now, telegraf.Summary),
+	})
+	v2 := convertMetrics(t, []telegraf.Metric{
+		testutil.MustMetric("prometheus", map[string]string{"quantile": "0.5"},
+			map[string]interface{}{"rpc_duration_seconds": 0.2}, now, telegraf.Summary),
+		testutil.MustMetric("prometheus", map[string]string{"quantile": "0.99"},
+			map[string]interface{}{"rpc_duration_seconds": 0.9}, now, telegraf.Summary),
+		testutil.MustMetric("prometheus", map[string]string{},
+			map[string]interface{}{"rpc_duration_seconds_count": 100.0, "rpc_duration_seconds_sum": 25.0},
+			now, telegraf.Summary),
+	})
+
+	for _, m := range [][]*otlpmetrics.Metric{v1, v2} {
+		require.Len(t, m, 1)
+		require.Equal(t, "rpc_duration_seconds", m[0].Name)
+		dp := m[0].GetDoubleSummary().DataPoints
+		require.Len(t, dp, 1)
+		require.Equal(t, uint64(100), dp[0].Count)
+		require.Equal(t, 25.0, dp[0].Sum)
+		require.Len(t, dp[0].QuantileValues, 2)
+		require.Equal(t, 0.5, dp[0].QuantileValues[0].Quantile)
+		require.Equal(t, 0.2, dp[0].QuantileValues[0].Value)
+		require.Equal(t, 0.99, dp[0].QuantileValues[1].Quantile)
+		require.Equal(t, 0.9, dp[0].QuantileValues[1].Value)
+	}
+}
+
+func TestConvertFields(t *testing.T) {
+	now := time.Unix(0, 0)
+	m := convertMetrics(t, []telegraf.Metric{
+		testutil.MustMetric("system",
+			map[string]string{},
+			map[string]interface{}{
+				"load1":   1.5,
+				"n_cpus":  int64(4),
+				"uptime":  uint64(100),
+				"up":      true,
+				"version": "1.0",
+			},
+			now,
+		),
+		testutil.MustMetric("system",
+			map[string]string{},
+			map[string]interface{}{"load1": 2.5},
+			now.Add(time.Second),
+		),
+	})
+
+	byName := make(map[string]*otlpmetrics.Metric)
+	for _, metric := range m {
+		byName[metric.Name] = metric
+	}
+	require.Len(t, byName, 4)
+	require.Len(t, byName["system_load1"].GetDoubleGauge().DataPoints, 2)
+	require.Equal(t, int64(4), byName["system_n_cpus"].GetIntGauge().DataPoints[0].Value)
+	require.Equal(t, int64(100), byName["system_uptime"].GetIntGauge().DataPoints[0].Value)
+	require.Equal(t, int64(1), byName["system_up"].GetIntGauge().DataPoints[0].Value)
+}
+
+func TestConvertResources(t *testing.T) {
+	c := newConverter([]string{"host"}, nil, testutil.Logger{})
+	req := c.convert([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3.0}, time.Unix(1, 0)),
+	})
+	require.Len(t, req.ResourceMetrics, 2)
+	require.Equal(t, []*otlpcommon.KeyValue{stringAttribute("host", "a")}, req.ResourceMetrics[0].Resource.Attributes)
+	require.Len(t, req.ResourceMetrics[0].InstrumentationLibraryMetrics[0].Metrics[0].GetDoubleGauge().DataPoints, 2)
+	require.Equal(t, []*otlpcommon.KeyValue{stringAttribute("host", "b")}, req.ResourceMetrics[1].Resource.Attributes)
+	require.Empty(t, req.ResourceMetrics[1].InstrumentationLibraryMetrics[0].Metrics[0].GetDoubleGauge().DataPoints[0].Labels)
+}
@@ -71,6 +71,11 @@ var sampleConfig = `
 
   ## Export metric collection time.
   # export_timestamp = false
+
+  ## Serve the OpenMetrics format to the clients asking for it, such as
+  ## Prometheus, including the exemplars of counters and histogram buckets
+  ## with metric_version = 2.
+  # enable_openmetrics = false
 `
 
 type Collector interface {
@@ -90,6 +95,7 @@ type PrometheusClient struct {
 	CollectorsExclude  []string        `toml:"collectors_exclude"`
 	StringAsLabel      bool            `toml:"string_as_label"`
 	ExportTimestamp    bool            `toml:"export_timestamp"`
+	EnableOpenMetrics  bool            `toml:"enable_openmetrics"`
 	tlsint.ServerConfig
 
 	Log telegraf.Logger `toml:"-"`
@@ -158,7 +164,10 @@ func (p *PrometheusClient) Init() error {
 
 	authHandler := internal.AuthHandler(p.BasicUsername, p.BasicPassword, "prometheus", onAuthError)
 	rangeHandler := internal.IPRangeHandler(ipRange, onError)
-	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: p.EnableOpenMetrics,
+	})
 
 	mux := http.NewServeMux()
 	if p.Path == "" {
@@ -461,3 +461,75 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestRoundTripOpenMetrics(t *testing.T) {
+	data := []byte(`# HELP http_requests Telegraf collected metric
+# TYPE http_requests counter
+http_requests_total{code="200"} 1027.0 # {trace_id="abc123"} 1.0 1.6227450005e+09
+# HELP request_duration_seconds Telegraf collected metric
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 8 # {trace_id="def456"} 0.067
+request_duration_seconds_bucket{le="+Inf"} 12
+request_duration_seconds_sum 5.5
+request_duration_seconds_count 12
+# EOF
+`)
+
+	accepts := make(chan string, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case accepts <- r.Header.Get("Accept"):
+		default:
+		}
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=0.0.1; charset=utf-8")
+		if _, err := w.Write(data); err != nil {
+			t.Errorf("writing response failed: %v", err)
+		}
+	}))
+	defer ts.Close()
+
+	input := &inputs.Prometheus{
+		URLs:              []string{ts.URL},
+		URLTag:            "",
+		MetricVersion:     2,
+		EnableOpenMetrics: true,
+	}
+	var acc testutil.Accumulator
+	require.NoError(t, input.Start(&acc))
+	require.NoError(t, input.Gather(&acc))
+	input.Stop()
+	require.Contains(t, <-accepts, "application/openmetrics-text")
+
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		Path:              defaultPath,
+		MetricVersion:     2,
+		EnableOpenMetrics: true,
+		Log:               testutil.Logger{Name: "outputs.prometheus_client"},
+		CollectorsExclude: []string{"gocollector", "process"},
+	}
+	require.NoError(t, output.Init())
+	require.NoError(t, output.Connect())
+	defer func() {
+		require.NoError(t, output.Close())
+	}()
+	require.NoError(t, output.Write(acc.GetTelegrafMetrics()))
+
+	req, err := http.NewRequest("GET", output.URL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1,text/plain;version=0.0.4;q=0.5")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Contains(t, resp.Header.Get("Content-Type"), "application/openmetrics-text")
+
+	actual, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(actual))
+
+	// The classic text format is still served by default
+	resp, err = http.Get(output.URL())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Contains(t, resp.Header.Get("Content-Type"), "text/plain")
+}
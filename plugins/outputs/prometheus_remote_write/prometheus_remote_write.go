@@ -0,0 +1,521 @@
+package prometheus_remote_write
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	httpconfig "github.com/influxdata/telegraf/plugins/common/http"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	defaultURL               = "http://localhost:9090/api/v1/write"
+	defaultMinShards         = 1
+	defaultMaxShards         = 10
+	defaultMaxSamplesPerSend = 500
+	defaultMaxRetries        = 3
+	defaultMinBackoff        = 30 * time.Millisecond
+	defaultMaxBackoff        = 5 * time.Second
+
+	remoteWriteVersion = "0.1.0"
+
+	// maxErrorBody is the length of the response body kept in errors.
+	maxErrorBody = 256
+)
+
+var sampleConfig = `
+  ## URL of the remote write endpoint.
+  url = "http://localhost:9090/api/v1/write"
+
+  ## Timeout of a write request.
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## OAuth2 Client Credentials Grant
+  # client_id = "clientid"
+  # client_secret = "secret"
+  # token_url = "https://indentityprovider/oauth2/v1/token"
+  # scopes = ["urn:opc:idm:__myscopes__"]
+
+  ## Additional HTTP headers
+  # [outputs.prometheus_remote_write.headers]
+  #   X-Scope-OrgID = "telegraf"
+
+  ## Range of the number of shards sending the write requests in parallel.
+  ## The series are spread over the shards, which are scaled to the number
+  ## of samples of the batch.
+  # min_shards = 1
+  # max_shards = 10
+
+  ## Maximum number of samples per write request.
+  # max_samples_per_send = 500
+
+  ## Number of retries of a write request failing with a 5xx or 429 status
+  ## code, or a network error.  The requests failing with other 4xx status
+  ## codes are dropped.
+  # max_retries = 3
+
+  ## Range of the backoff between retries, which doubles on each retry.  The
+  ## Retry-After header of the responses is used instead when set, up to
+  ## max_backoff.
+  # min_backoff = "30ms"
+  # max_backoff = "5s"
+
+  ## Send string metrics as Prometheus labels.
+  # string_as_label = false
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Idle (keep-alive) connection timeout.
+  ## Maximum amount of time before idle connection is closed.
+  ## Zero means no limit.
+  # idle_conn_timeout = 0
+`
+
+type PrometheusRemoteWrite struct {
+	URL               string            `toml:"url"`
+	Username          string            `toml:"username"`
+	Password          string            `toml:"password"`
+	Headers           map[string]string `toml:"headers"`
+	MinShards         int               `toml:"min_shards"`
+	MaxShards         int               `toml:"max_shards"`
+	MaxSamplesPerSend int               `toml:"max_samples_per_send"`
+	MaxRetries        int               `toml:"max_retries"`
+	MinBackoff        config.Duration   `toml:"min_backoff"`
+	MaxBackoff        config.Duration   `toml:"max_backoff"`
+	StringAsLabel     bool              `toml:"string_as_label"`
+	httpconfig.HTTPClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client     *http.Client
+	serializer *serializer.Serializer
+
+	// sent holds the samples delivered by a write that failed on other
+	// shards, so that they are skipped when the batch is written again.
+	sent map[sampleKey]bool
+
+	// ctx is canceled on Close, stopping the requests and retries in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// sampleKey identifies a sample by its series and timestamp.
+type sampleKey struct {
+	series    serializer.MetricKey
+	timestamp int64
+}
+
+// recoverableError is an error of a write request worth retrying, after
+// the delay asked by the server if any.
+type recoverableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *recoverableError) Error() string {
+	return e.err.Error()
+}
+
+func (p *PrometheusRemoteWrite) Description() string {
+	return "Write metrics with the Prometheus remote write protocol"
+}
+
+func (p *PrometheusRemoteWrite) SampleConfig() string {
+	return sampleConfig
+}
+
+func (p *PrometheusRemoteWrite) Init() error {
+	if p.URL == "" {
+		return errors.New("url must be set")
+	}
+	if p.MinShards < 1 {
+		return errors.New("min_shards must be at least 1")
+	}
+	if p.MaxShards < p.MinShards {
+		return errors.New("max_shards must be greater than or equal to min_shards")
+	}
+	if p.MaxSamplesPerSend < 1 {
+		return errors.New("max_samples_per_send must be at least 1")
+	}
+	if p.MaxRetries < 0 {
+		return errors.New("max_retries must not be negative")
+	}
+	if p.MinBackoff <= 0 || p.MaxBackoff < p.MinBackoff {
+		return errors.New("min_backoff must be positive and max_backoff greater than or equal to it")
+	}
+
+	format := serializer.FormatConfig{}
+	if p.StringAsLabel {
+		format.StringHandling = serializer.StringAsLabel
+	}
+	s, err := serializer.NewSerializer(format)
+	if err != nil {
+		return err
+	}
+	p.serializer = s
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Connect() error {
+	client, err := p.HTTPClientConfig.CreateClient(context.Background())
+	if err != nil {
+		return err
+	}
+	p.client = client
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return nil
+}
+
+func (p *PrometheusRemoteWrite) Close() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	return nil
+}
+
+// Write sends the samples of the metrics, spreading the series over the
+// shards.  The requests of a shard are sent in order, so that the samples of
+// a series are written in order.
+//
+// When a shard fails, the write fails and the batch is written again later.
+// The samples already delivered by the other shards are remembered until the
+// next successful write, and skipped instead of being sent twice.
+func (p *PrometheusRemoteWrite) Write(metrics []telegraf.Metric) error {
+	series, samples, err := p.timeSeries(metrics)
+	if err != nil {
+		return err
+	}
+	if p.sent != nil {
+		series, samples = p.unsent(series)
+	}
+	if samples == 0 {
+		p.sent = nil
+		return nil
+	}
+
+	shards := p.shards(samples)
+	queues := make([][]prompb.TimeSeries, shards)
+	for _, ts := range series {
+		i := shardOf(serializer.MakeMetricKey(ts.Labels), shards)
+		queues[i] = append(queues[i], ts)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, shards)
+	delivered := make([][]prompb.TimeSeries, shards)
+	for i, queue := range queues {
+		if len(queue) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, queue []prompb.TimeSeries) {
+			defer wg.Done()
+			delivered[i], errs[i] = p.sendShard(queue)
+		}(i, queue)
+	}
+	wg.Wait()
+
+	var failed error
+	for _, err := range errs {
+		if err != nil {
+			failed = err
+			break
+		}
+	}
+	if failed == nil {
+		p.sent = nil
+		return nil
+	}
+
+	if p.sent == nil {
+		p.sent = make(map[sampleKey]bool)
+	}
+	for _, shard := range delivered {
+		for _, ts := range shard {
+			key := serializer.MakeMetricKey(ts.Labels)
+			for _, sample := range ts.Samples {
+				p.sent[sampleKey{series: key, timestamp: sample.Timestamp}] = true
+			}
+		}
+	}
+	return failed
+}
+
+// unsent returns the series without the samples delivered by a previous
+// write, along with the number of samples left.
+func (p *PrometheusRemoteWrite) unsent(series []prompb.TimeSeries) ([]prompb.TimeSeries, int) {
+	left := make([]prompb.TimeSeries, 0, len(series))
+	count := 0
+	for _, ts := range series {
+		key := serializer.MakeMetricKey(ts.Labels)
+		samples := make([]prompb.Sample, 0, len(ts.Samples))
+		for _, sample := range ts.Samples {
+			if !p.sent[sampleKey{series: key, timestamp: sample.Timestamp}] {
+				samples = append(samples, sample)
+			}
+		}
+		if len(samples) > 0 {
+			left = append(left, prompb.TimeSeries{Labels: ts.Labels, Samples: samples})
+			count += len(samples)
+		}
+	}
+	return left, count
+}
+
+// timeSeries converts the metrics to time series with all their samples in
+// order, along with the number of samples.  The metrics are converted by
+// timestamp, as the serializer keeps a single sample per series.
+func (p *PrometheusRemoteWrite) timeSeries(metrics []telegraf.Metric) ([]prompb.TimeSeries, int, error) {
+	byTime := make(map[int64][]telegraf.Metric)
+	times := make([]int64, 0)
+	for _, m := range metrics {
+		t := m.Time().UnixNano()
+		if _, ok := byTime[t]; !ok {
+			times = append(times, t)
+		}
+		byTime[t] = append(byTime[t], m)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	var series []prompb.TimeSeries
+	index := make(map[serializer.MetricKey]int)
+	samples := 0
+	for _, t := range times {
+		tss, err := p.serializer.TimeSeries(byTime[t])
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, ts := range tss {
+			// Remote write receivers expect the labels sorted by name
+			sort.Slice(ts.Labels, func(i, j int) bool { return ts.Labels[i].Name < ts.Labels[j].Name })
+
+			key := serializer.MakeMetricKey(ts.Labels)
+			i, ok := index[key]
+			if !ok {
+				index[key] = len(series)
+				series = append(series, ts)
+				samples += len(ts.Samples)
+				continue
+			}
+
+			for _, sample := range ts.Samples {
+				existing := series[i].Samples
+				if last := len(existing) - 1; existing[last].Timestamp == sample.Timestamp {
+					existing[last] = sample
+					continue
+				}
+				series[i].Samples = append(existing, sample)
+				samples++
+			}
+		}
+	}
+	return series, samples, nil
+}
+
+// shards returns the number of shards needed to send the samples in one
+// request per shard, within the range of shards.
+func (p *PrometheusRemoteWrite) shards(samples int) int {
+	n := (samples + p.MaxSamplesPerSend - 1) / p.MaxSamplesPerSend
+	if n < p.MinShards {
+		return p.MinShards
+	}
+	if n > p.MaxShards {
+		return p.MaxShards
+	}
+	return n
+}
+
+// shardOf returns the shard of a series.  The key is mixed first, as the FNV
+// hashes of label sets differing only in their last value are too alike to be
+// spread by a modulo, for example all falling into the same of three shards.
+func shardOf(key serializer.MetricKey, shards int) int {
+	k := uint64(key)
+	k ^= k >> 33
+	k *= 0xff51afd7ed558ccd
+	k ^= k >> 33
+	k *= 0xc4ceb9fe1a85ec53
+	k ^= k >> 33
+	return int(k % uint64(shards))
+}
+
+// sendShard sends the series of a shard in requests of at most
+// max_samples_per_send samples, splitting the samples of a series over
+// several requests if needed.  It returns the series of the requests that
+// are done with, delivered or dropped, before any error.
+func (p *PrometheusRemoteWrite) sendShard(series []prompb.TimeSeries) ([]prompb.TimeSeries, error) {
+	var delivered []prompb.TimeSeries
+	var request []prompb.TimeSeries
+	count := 0
+	for _, ts := range series {
+		samples := ts.Samples
+		for len(samples) > 0 {
+			n := p.MaxSamplesPerSend - count
+			if n > len(samples) {
+				n = len(samples)
+			}
+			request = append(request, prompb.TimeSeries{Labels: ts.Labels, Samples: samples[:n]})
+			samples = samples[n:]
+			count += n
+
+			if count == p.MaxSamplesPerSend {
+				if err := p.send(request, count); err != nil {
+					return delivered, err
+				}
+				delivered = append(delivered, request...)
+				request = nil
+				count = 0
+			}
+		}
+	}
+
+	if count > 0 {
+		if err := p.send(request, count); err != nil {
+			return delivered, err
+		}
+		delivered = append(delivered, request...)
+	}
+	return delivered, nil
+}
+
+// send sends a write request, retrying on recoverable errors.  The request
+// is dropped on other errors, as the server would reject it again.
+func (p *PrometheusRemoteWrite) send(series []prompb.TimeSeries, samples int) error {
+	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: series})
+	if err != nil {
+		return fmt.Errorf("unable to marshal protobuf: %v", err)
+	}
+	body := snappy.Encode(nil, data)
+
+	backoff := time.Duration(p.MinBackoff)
+	for retry := 0; ; retry++ {
+		err := p.post(body)
+		if err == nil {
+			return nil
+		}
+
+		var recoverable *recoverableError
+		if !errors.As(err, &recoverable) {
+			p.Log.Errorf("Dropping %d samples: %v", samples, err)
+			return nil
+		}
+		if retry >= p.MaxRetries {
+			return fmt.Errorf("sending %d samples failed after %d retries: %v", samples, retry, err)
+		}
+
+		wait := backoff
+		if recoverable.retryAfter > 0 {
+			wait = recoverable.retryAfter
+			if wait > time.Duration(p.MaxBackoff) {
+				wait = time.Duration(p.MaxBackoff)
+			}
+		}
+		p.Log.Debugf("Retrying in %s: %v", wait, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-p.ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("sending %d samples canceled by closing the output: %v", samples, err)
+		}
+
+		backoff *= 2
+		if backoff > time.Duration(p.MaxBackoff) {
+			backoff = time.Duration(p.MaxBackoff)
+		}
+	}
+}
+
+func (p *PrometheusRemoteWrite) post(body []byte) error {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodPost, p.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if p.Username != "" || p.Password != "" {
+		req.SetBasicAuth(p.Username, p.Password)
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", remoteWriteVersion)
+	for k, v := range p.Headers {
+		if strings.ToLower(k) == "host" {
+			req.Host = v
+		}
+		req.Header.Set(k, v)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return &recoverableError{err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		_, err = io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err = fmt.Errorf("server returned status %q: %s", resp.Status, bytes.TrimSpace(msg))
+	if resp.StatusCode/100 == 5 || resp.StatusCode == http.StatusTooManyRequests {
+		return &recoverableError{err: err, retryAfter: retryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+	return err
+}
+
+// retryAfter parses the delay of a Retry-After header, given in seconds or
+// as a date.
+func retryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(header); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+func init() {
+	outputs.Add("prometheus_remote_write", func() telegraf.Output {
+		return &PrometheusRemoteWrite{
+			URL:               defaultURL,
+			MinShards:         defaultMinShards,
+			MaxShards:         defaultMaxShards,
+			MaxSamplesPerSend: defaultMaxSamplesPerSend,
+			MaxRetries:        defaultMaxRetries,
+			MinBackoff:        config.Duration(defaultMinBackoff),
+			MaxBackoff:        config.Duration(defaultMaxBackoff),
+		}
+	})
+}
@@ -0,0 +1,381 @@
+package prometheus_remote_write
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	serializer "github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+// receiver is a remote write endpoint recording the samples of each series
+// in the order they are received.
+type receiver struct {
+	*httptest.Server
+	sync.Mutex
+	requests int
+	samples  map[string][]prompb.Sample
+	labels   map[string][]prompb.Label
+	status   func(n int) (int, string)
+	// reject fails the requests it returns true for with a 503 status code
+	reject func(wr *prompb.WriteRequest) bool
+}
+
+func newReceiver(t *testing.T, status func(n int) (int, string)) *receiver {
+	r := &receiver{
+		samples: make(map[string][]prompb.Sample),
+		labels:  make(map[string][]prompb.Label),
+		status:  status,
+	}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		require.Equal(t, "snappy", req.Header.Get("Content-Encoding"))
+		require.Equal(t, "application/x-protobuf", req.Header.Get("Content-Type"))
+		require.Equal(t, "0.1.0", req.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+		compressed, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		data, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		var wr prompb.WriteRequest
+		require.NoError(t, proto.Unmarshal(data, &wr))
+
+		r.Lock()
+		r.requests++
+		code, retryAfter := http.StatusNoContent, ""
+		if r.status != nil {
+			code, retryAfter = r.status(r.requests)
+		}
+		if r.reject != nil && r.reject(&wr) {
+			code = http.StatusServiceUnavailable
+		}
+		if code/100 == 2 {
+			for _, ts := range wr.Timeseries {
+				key := labelsKey(ts.Labels)
+				r.labels[key] = ts.Labels
+				r.samples[key] = append(r.samples[key], ts.Samples...)
+			}
+		}
+		r.Unlock()
+
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		w.WriteHeader(code)
+	}))
+	return r
+}
+
+func labelsKey(labels []prompb.Label) string {
+	key := ""
+	for _, l := range labels {
+		key += l.Name + "=" + l.Value + ","
+	}
+	return key
+}
+
+func newOutput(t *testing.T, url string) *PrometheusRemoteWrite {
+	p := &PrometheusRemoteWrite{
+		URL:               url,
+		MinShards:         defaultMinShards,
+		MaxShards:         defaultMaxShards,
+		MaxSamplesPerSend: defaultMaxSamplesPerSend,
+		MaxRetries:        defaultMaxRetries,
+		MinBackoff:        config.Duration(time.Millisecond),
+		MaxBackoff:        config.Duration(10 * time.Millisecond),
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, p.Init())
+	require.NoError(t, p.Connect())
+	return p
+}
+
+func TestWrite(t *testing.T) {
+	r := newReceiver(t, nil)
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "b", "cpu": "0"},
+			map[string]interface{}{"time_idle": 42.0}, time.Unix(2, 0)),
+		testutil.MustMetric("cpu", map[string]string{"host": "b", "cpu": "0"},
+			map[string]interface{}{"time_idle": 40.0}, time.Unix(1, 0)),
+		testutil.MustMetric("mem", map[string]string{},
+			map[string]interface{}{"used": int64(3), "state": "ok"}, time.Unix(1, 0)),
+	}
+	require.NoError(t, p.Write(metrics))
+	require.Equal(t, 1, r.requests)
+
+	cpu := labelsKey([]prompb.Label{{Name: "__name__", Value: "cpu_time_idle"}, {Name: "cpu", Value: "0"}, {Name: "host", Value: "b"}})
+	require.Equal(t, []prompb.Sample{{Value: 40, Timestamp: 1000}, {Value: 42, Timestamp: 2000}}, r.samples[cpu])
+
+	mem := labelsKey([]prompb.Label{{Name: "__name__", Value: "mem_used"}})
+	require.Equal(t, []prompb.Sample{{Value: 3, Timestamp: 1000}}, r.samples[mem])
+	require.Len(t, r.samples, 2)
+}
+
+func TestWriteHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		username, password, ok := req.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "user", username)
+		require.Equal(t, "secret", password)
+		require.Equal(t, "tenant", req.Header.Get("X-Scope-OrgID"))
+		require.Contains(t, req.Header.Get("User-Agent"), "Telegraf")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	p := newOutput(t, ts.URL)
+	p.Username = "user"
+	p.Password = "secret"
+	p.Headers = map[string]string{"X-Scope-OrgID": "tenant"}
+
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.NoError(t, p.Write([]telegraf.Metric{m}))
+}
+
+func TestWriteSharding(t *testing.T) {
+	r := newReceiver(t, nil)
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	p.MaxShards = 3
+	p.MaxSamplesPerSend = 2
+
+	// 8 series of 5 samples each, sent by 3 shards in requests of 2 samples
+	var metrics []telegraf.Metric
+	for i := int64(0); i < 5; i++ {
+		for _, host := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			metrics = append(metrics, testutil.MustMetric("cpu", map[string]string{"host": host},
+				map[string]interface{}{"value": float64(i)}, time.Unix(i, 0)))
+		}
+	}
+	require.NoError(t, p.Write(metrics))
+	require.Len(t, r.samples, 8)
+	require.True(t, r.requests >= 20)
+
+	// The samples of each series are received in order
+	for key, samples := range r.samples {
+		require.Len(t, samples, 5, key)
+		for i, s := range samples {
+			require.Equal(t, float64(i), s.Value, key)
+			require.Equal(t, int64(i)*1000, s.Timestamp, key)
+		}
+	}
+}
+
+func TestWriteRetry(t *testing.T) {
+	r := newReceiver(t, func(n int) (int, string) {
+		switch n {
+		case 1:
+			return http.StatusInternalServerError, ""
+		case 2:
+			return http.StatusTooManyRequests, ""
+		}
+		return http.StatusNoContent, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.NoError(t, p.Write([]telegraf.Metric{m}))
+	require.Equal(t, 3, r.requests)
+	require.Len(t, r.samples, 1)
+}
+
+func TestWriteRetryAfter(t *testing.T) {
+	r := newReceiver(t, func(n int) (int, string) {
+		if n == 1 {
+			return http.StatusTooManyRequests, "1"
+		}
+		return http.StatusNoContent, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	p.MaxBackoff = config.Duration(2 * time.Second)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	start := time.Now()
+	require.NoError(t, p.Write([]telegraf.Metric{m}))
+	require.True(t, time.Since(start) >= time.Second)
+	require.Equal(t, 2, r.requests)
+}
+
+func TestWriteRetryAfterCapped(t *testing.T) {
+	r := newReceiver(t, func(n int) (int, string) {
+		if n == 1 {
+			return http.StatusTooManyRequests, "3600"
+		}
+		return http.StatusNoContent, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	start := time.Now()
+	require.NoError(t, p.Write([]telegraf.Metric{m}))
+	require.True(t, time.Since(start) < time.Minute)
+	require.Equal(t, 2, r.requests)
+}
+
+func TestWriteRetryCanceledOnClose(t *testing.T) {
+	r := newReceiver(t, func(int) (int, string) {
+		return http.StatusServiceUnavailable, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	p.MinBackoff = config.Duration(time.Hour)
+	p.MaxBackoff = config.Duration(time.Hour)
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+
+	errs := make(chan error)
+	go func() {
+		errs <- p.Write([]telegraf.Metric{m})
+	}()
+	require.Eventually(t, func() bool {
+		r.Lock()
+		defer r.Unlock()
+		return r.requests == 1
+	}, time.Second, time.Millisecond)
+	require.NoError(t, p.Close())
+
+	select {
+	case err := <-errs:
+		require.Error(t, err)
+	case <-time.After(10 * time.Second):
+		require.Fail(t, "write not canceled on close")
+	}
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	r := newReceiver(t, func(int) (int, string) {
+		return http.StatusServiceUnavailable, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	p.MaxRetries = 2
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	require.Error(t, p.Write([]telegraf.Metric{m}))
+	require.Equal(t, 3, r.requests)
+}
+
+func TestWriteFailedShardOnly(t *testing.T) {
+	r := newReceiver(t, nil)
+	defer r.Close()
+	r.reject = func(wr *prompb.WriteRequest) bool {
+		for _, ts := range wr.Timeseries {
+			for _, l := range ts.Labels {
+				if l.Name == "host" && l.Value == "a" {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	p := newOutput(t, r.URL)
+	p.MaxShards = 3
+	p.MaxSamplesPerSend = 1
+	p.MaxRetries = 0
+
+	var metrics []telegraf.Metric
+	for _, host := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		metrics = append(metrics, testutil.MustMetric("cpu", map[string]string{"host": host},
+			map[string]interface{}{"value": 1.0}, time.Unix(0, 0)))
+	}
+
+	// The shard of host a fails while the others deliver their samples
+	require.Error(t, p.Write(metrics))
+	r.Lock()
+	delivered := len(r.samples)
+	r.Unlock()
+	require.NotZero(t, delivered)
+	require.Less(t, delivered, 8)
+
+	// Writing the batch again only sends the samples not yet delivered
+	r.Lock()
+	r.reject = nil
+	r.requests = 0
+	r.Unlock()
+	require.NoError(t, p.Write(metrics))
+	require.Equal(t, 8-delivered, r.requests)
+	require.Len(t, r.samples, 8)
+	for key, samples := range r.samples {
+		require.Len(t, samples, 1, key)
+	}
+
+	// Once written, the batch is sent in full again
+	r.requests = 0
+	require.NoError(t, p.Write(metrics))
+	require.Equal(t, 8, r.requests)
+}
+
+func TestWriteDropOnClientError(t *testing.T) {
+	r := newReceiver(t, func(int) (int, string) {
+		return http.StatusBadRequest, ""
+	})
+	defer r.Close()
+
+	p := newOutput(t, r.URL)
+	p.MaxSamplesPerSend = 1
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 2.0}, time.Unix(1, 0)),
+	}
+	require.NoError(t, p.Write(metrics))
+	require.Equal(t, 2, r.requests)
+	require.Empty(t, r.samples)
+}
+
+func TestShards(t *testing.T) {
+	p := &PrometheusRemoteWrite{MinShards: 2, MaxShards: 5, MaxSamplesPerSend: 10}
+	require.Equal(t, 2, p.shards(1))
+	require.Equal(t, 3, p.shards(21))
+	require.Equal(t, 5, p.shards(1000))
+}
+
+func TestShardOf(t *testing.T) {
+	used := make(map[int]bool)
+	for _, host := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		key := serializer.MakeMetricKey([]prompb.Label{{Name: "__name__", Value: "cpu_value"}, {Name: "host", Value: host}})
+		used[shardOf(key, 3)] = true
+	}
+	require.Len(t, used, 3)
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2021, 6, 3, 18, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Duration(0), retryAfter("", now))
+	require.Equal(t, 2*time.Second, retryAfter("2", now))
+	require.Equal(t, 30*time.Second, retryAfter("Thu, 03 Jun 2021 18:00:30 GMT", now))
+	require.Equal(t, time.Duration(0), retryAfter("Thu, 03 Jun 2021 17:00:00 GMT", now))
+	require.Equal(t, time.Duration(0), retryAfter("soon", now))
+}
+
+func TestInit(t *testing.T) {
+	p := &PrometheusRemoteWrite{
+		URL:               defaultURL,
+		MinShards:         2,
+		MaxShards:         1,
+		MaxSamplesPerSend: defaultMaxSamplesPerSend,
+		MinBackoff:        config.Duration(defaultMinBackoff),
+		MaxBackoff:        config.Duration(defaultMaxBackoff),
+	}
+	require.Error(t, p.Init())
+
+	p.MaxShards = 2
+	require.NoError(t, p.Init())
+}
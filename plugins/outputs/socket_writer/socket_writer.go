@@ -3,6 +3,7 @@ package socket_writer
 import (
 	"crypto/tls"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"strings"
@@ -11,13 +12,19 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/framing"
 	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
+const defaultAckTimeout = 5 * time.Second
+
 type SocketWriter struct {
-	ContentEncoding string `toml:"content_encoding"`
+	ContentEncoding string          `toml:"content_encoding"`
+	Framing         string          `toml:"framing"`
+	Acknowledge     bool            `toml:"acknowledge"`
+	AckTimeout      config.Duration `toml:"ack_timeout"`
 	Address         string
 	KeepAlivePeriod *config.Duration
 	tlsint.ClientConfig
@@ -60,11 +67,23 @@ func (sw *SocketWriter) SampleConfig() string {
   ## Defaults to the OS configuration.
   # keep_alive_period = "5m"
 
-  ## Content encoding for packet-based connections (i.e. UDP, unixgram).
-  ## Can be set to "gzip" or to "identity" to apply no encoding.
-  ##
+  ## Content encoding of the messages, can be set to "gzip", "zstd" or to
+  ## "identity" to apply no encoding.  Without framing, each metric is
+  ## encoded on its own.
   # content_encoding = "identity"
 
+  ## Framing of the messages on stream sockets (e.g. TCP), can be set to
+  ## "length_prefixed" to send each batch of metrics as a frame prefixed with
+  ## its length, or to "none" to write the metrics as a plain stream.
+  # framing = "none"
+
+  ## Wait for the listener to acknowledge each frame, so that a batch is
+  ## only written once the listener confirms it.  Batches not acknowledged
+  ## within ack_timeout are written again later.  Requires the
+  ## "length_prefixed" framing, and a listener acknowledging the frames.
+  # acknowledge = false
+  # ack_timeout = "5s"
+
   ## Data format to generate.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -98,6 +117,13 @@ func (sw *SocketWriter) Connect() error {
 		return err
 	}
 
+	if err := sw.checkFraming(spl[0]); err != nil {
+		// Ignore the returned error as we cannot do anything about it anyway
+		//nolint:errcheck,revive
+		c.Close()
+		return err
+	}
+
 	if err := sw.setKeepAlive(c); err != nil {
 		log.Printf("unable to configure keep alive (%s): %s", sw.Address, err)
 	}
@@ -111,6 +137,26 @@ func (sw *SocketWriter) Connect() error {
 	return nil
 }
 
+// checkFraming checks that the framing and acknowledgement are only used on
+// stream sockets, where the listener can answer.
+func (sw *SocketWriter) checkFraming(network string) error {
+	if err := framing.Validate(sw.Framing); err != nil {
+		return err
+	}
+	framed := sw.Framing == framing.LengthPrefixed
+	if sw.Acknowledge && !framed {
+		return fmt.Errorf("acknowledge requires the %q framing", framing.LengthPrefixed)
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6", "ip", "ip4", "ip6", "unixgram":
+		if framed {
+			return fmt.Errorf("framing is not supported on %s sockets", network)
+		}
+	}
+	return nil
+}
+
 func (sw *SocketWriter) setKeepAlive(c net.Conn) error {
 	if sw.KeepAlivePeriod == nil {
 		return nil
@@ -139,6 +185,10 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 		}
 	}
 
+	if sw.Framing == framing.LengthPrefixed {
+		return sw.writeFrame(metrics)
+	}
+
 	for _, m := range metrics {
 		bs, err := sw.Serialize(m)
 		if err != nil {
@@ -167,6 +217,60 @@ func (sw *SocketWriter) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// writeFrame writes the metrics as a single frame, and waits for the
+// listener to acknowledge it if needed.
+func (sw *SocketWriter) writeFrame(metrics []telegraf.Metric) error {
+	bs, err := sw.SerializeBatch(metrics)
+	if err != nil {
+		log.Printf("E! [outputs.socket_writer] Could not serialize metrics, dropping them: %v", err)
+		return nil
+	}
+	if len(bs) == 0 {
+		return nil
+	}
+
+	bs, err = sw.encoder.Encode(bs)
+	if err != nil {
+		log.Printf("E! [outputs.socket_writer] Could not encode metrics, dropping them: %v", err)
+		return nil
+	}
+
+	if err := framing.WriteFrame(sw.Conn, bs); err != nil {
+		sw.Close()
+		return fmt.Errorf("closing connection: %v", err)
+	}
+	if !sw.Acknowledge {
+		return nil
+	}
+
+	// The connection is closed when the answer is missing, as a late answer
+	// would be taken for the one of the next frame.
+	answer := make([]byte, 1)
+	timeout := time.Duration(sw.AckTimeout)
+	if timeout <= 0 {
+		timeout = defaultAckTimeout
+	}
+	if err := sw.Conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		sw.Close()
+		return fmt.Errorf("closing connection: %v", err)
+	}
+	if _, err := io.ReadFull(sw.Conn, answer); err != nil {
+		sw.Close()
+		return fmt.Errorf("closing connection, frame not acknowledged: %v", err)
+	}
+
+	switch answer[0] {
+	case framing.Ack:
+		return nil
+	case framing.Nak:
+		log.Printf("E! [outputs.socket_writer] Listener rejected %d metrics, dropping them", len(metrics))
+		return nil
+	default:
+		sw.Close()
+		return fmt.Errorf("closing connection, unexpected acknowledgement %#x", answer[0])
+	}
+}
+
 // Close closes the connection. Noop if already closed.
 func (sw *SocketWriter) Close() error {
 	if sw.Conn == nil {
@@ -181,6 +285,7 @@ func newSocketWriter() *SocketWriter {
 	s, _ := serializers.NewInfluxSerializer()
 	return &SocketWriter{
 		Serializer: s,
+		AckTimeout: config.Duration(defaultAckTimeout),
 	}
 }
 
@@ -9,8 +9,12 @@ import (
 	"runtime"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/framing"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -214,3 +218,92 @@ func TestSocketWriter_udp_gzip(t *testing.T) {
 
 	testSocketWriterPacket(t, sw, listener)
 }
+
+func TestSocketWriter_tcp_framing(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+	sw.ContentEncoding = "zstd"
+	sw.Framing = framing.LengthPrefixed
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "test"), testutil.TestMetric(2, "test")}
+	expected, err := sw.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	require.NoError(t, sw.Write(metrics))
+
+	frame, err := framing.ReadFrame(lconn, 1024)
+	require.NoError(t, err)
+	decoder, err := internal.NewContentDecoder("zstd")
+	require.NoError(t, err)
+	actual, err := decoder.Decode(frame)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), string(actual))
+}
+
+func TestSocketWriter_tcp_acknowledge(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	sw := newSocketWriter()
+	sw.Address = "tcp://" + listener.Addr().String()
+	sw.Framing = framing.LengthPrefixed
+	sw.Acknowledge = true
+	sw.AckTimeout = config.Duration(100 * time.Millisecond)
+
+	err = sw.Connect()
+	require.NoError(t, err)
+
+	lconn, err := listener.Accept()
+	require.NoError(t, err)
+
+	// The listener answers each frame in turn
+	answers := make(chan byte, 3)
+	go func() {
+		for answer := range answers {
+			if _, err := framing.ReadFrame(lconn, 1024); err != nil {
+				return
+			}
+			if answer != 0 {
+				lconn.Write([]byte{answer})
+			}
+		}
+	}()
+	metrics := []telegraf.Metric{testutil.TestMetric(1, "test")}
+
+	answers <- framing.Ack
+	require.NoError(t, sw.Write(metrics))
+
+	// Rejected frames are dropped
+	answers <- framing.Nak
+	require.NoError(t, sw.Write(metrics))
+
+	// Frames without answer are written again later
+	answers <- 0
+	require.Error(t, sw.Write(metrics))
+	require.Nil(t, sw.Conn)
+	close(answers)
+}
+
+func TestSocketWriter_framing_invalid(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	sw := newSocketWriter()
+	sw.Address = "udp://" + listener.LocalAddr().String()
+	sw.Framing = framing.LengthPrefixed
+	require.Error(t, sw.Connect())
+
+	sw.Framing = framing.None
+	sw.Acknowledge = true
+	require.Error(t, sw.Connect())
+}
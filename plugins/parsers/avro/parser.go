@@ -0,0 +1,120 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/linkedin/goavro/v2"
+)
+
+type Config struct {
+	// SchemaRegistry is the URL of the schema registry.
+	SchemaRegistry string
+	Record         schemaregistry.RecordConfig
+}
+
+type codec struct {
+	codec  *goavro.Codec
+	schema *schema
+}
+
+// Parser parses Avro messages of the Confluent wire format, fetching their
+// schema from a schema registry.
+type Parser struct {
+	record   schemaregistry.RecordConfig
+	registry *schemaregistry.Client
+
+	sync.Mutex
+	codecs map[int]*codec
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.SchemaRegistry == "" {
+		return nil, errors.New("schema registry URL must be set")
+	}
+
+	registry, err := schemaregistry.NewClient(config.SchemaRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		record:   config.Record,
+		registry: registry,
+		codecs:   make(map[int]*codec),
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	id, payload, err := schemaregistry.Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := p.codec(id)
+	if err != nil {
+		return nil, err
+	}
+
+	native, rest, err := c.codec.NativeFromBinary(payload)
+	if err != nil {
+		return nil, fmt.Errorf("decoding message with schema %d failed: %v", id, err)
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("decoding message with schema %d failed: %d trailing bytes", id, len(rest))
+	}
+
+	record, err := c.schema.record(native)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := p.record.Metric(record)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, errors.New("parsing line is not supported by the avro format")
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.record.DefaultTags = tags
+}
+
+// codec returns the codec of a schema, fetching the schema from the registry
+// the first time.
+func (p *Parser) codec(id int) (*codec, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if c, ok := p.codecs[id]; ok {
+		return c, nil
+	}
+
+	s, err := p.registry.SchemaByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type != "AVRO" {
+		return nil, fmt.Errorf("schema %d is of type %s instead of AVRO", id, s.Type)
+	}
+
+	avroCodec, err := goavro.NewCodec(s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("creating codec of schema %d failed: %v", id, err)
+	}
+	walker, err := newSchema(s.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("creating codec of schema %d failed: %v", id, err)
+	}
+
+	c := &codec{codec: avroCodec, schema: walker}
+	p.codecs[id] = c
+	return c, nil
+}
@@ -0,0 +1,168 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+{
+  "type": "record",
+  "name": "Measurement",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "name", "type": "string"},
+    {"name": "host", "type": ["null", "string"]},
+    {"name": "time", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "level", "type": {"type": "enum", "name": "Level", "symbols": ["LOW", "HIGH"]}},
+    {"name": "value", "type": ["null", "double", "long"]},
+    {"name": "cpu", "type": ["null", {
+      "type": "record",
+      "name": "CPU",
+      "fields": [
+        {"name": "user", "type": "float"},
+        {"name": "idle", "type": ["null", "int"]}
+      ]
+    }]},
+    {"name": "previous", "type": ["null", "CPU"]},
+    {"name": "cores", "type": {"type": "array", "items": "int"}},
+    {"name": "labels", "type": {"type": "map", "values": ["null", "string"]}},
+    {"name": "raw", "type": "bytes"},
+    {"name": "ok", "type": "boolean"}
+  ]
+}`
+
+func registry(t *testing.T, schemas map[int]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for id, schema := range schemas {
+			if r.URL.Path == "/schemas/ids/"+strconv.Itoa(id) {
+				octets, err := json.Marshal(map[string]string{"schema": schema})
+				require.NoError(t, err)
+				w.Write(octets)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+}
+
+func encode(t *testing.T, id int, schema string, native interface{}) []byte {
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+
+	buf := make([]byte, 5)
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	buf, err = codec.BinaryFromNative(buf, native)
+	require.NoError(t, err)
+	return buf
+}
+
+func TestParse(t *testing.T) {
+	ts := registry(t, map[int]string{42: testSchema})
+	defer ts.Close()
+
+	parser, err := New(&Config{
+		SchemaRegistry: ts.URL,
+		Record: schemaregistry.RecordConfig{
+			MetricName:       "kafka_consumer",
+			MeasurementField: "name",
+			Tags:             []string{"host", "level"},
+			Timestamp:        "time",
+		},
+	})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"topic": "metrics"})
+
+	tm := time.Unix(1622745000, 123000000).UTC()
+	msg := encode(t, 42, testSchema, map[string]interface{}{
+		"name":     "cpu",
+		"host":     goavro.Union("string", "a"),
+		"time":     tm,
+		"level":    "HIGH",
+		"value":    goavro.Union("long", int64(7)),
+		"cpu":      goavro.Union("com.example.CPU", map[string]interface{}{"user": float32(4.5), "idle": goavro.Union("int", int32(90))}),
+		"previous": nil,
+		"cores":    []interface{}{int32(0), int32(1)},
+		"labels":   map[string]interface{}{"region": goavro.Union("string", "eu"), "zone": nil},
+		"raw":      []byte{1, 2},
+		"ok":       true,
+	})
+
+	metrics, err := parser.Parse(msg)
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "level": "HIGH", "topic": "metrics"},
+		map[string]interface{}{
+			"value":         int64(7),
+			"cpu_user":      4.5,
+			"cpu_idle":      int64(90),
+			"cores_0":       int64(0),
+			"cores_1":       int64(1),
+			"labels_region": "eu",
+			"ok":            true,
+		},
+		tm,
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, metrics)
+}
+
+func TestParseSchemaCache(t *testing.T) {
+	requests := 0
+	schema := `{"type": "record", "name": "m", "fields": [{"name": "value", "type": "double"}]}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		octets, err := json.Marshal(map[string]string{"schema": schema})
+		require.NoError(t, err)
+		w.Write(octets)
+	}))
+	defer ts.Close()
+
+	parser, err := New(&Config{SchemaRegistry: ts.URL, Record: schemaregistry.RecordConfig{MetricName: "test"}})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		metrics, err := parser.Parse(encode(t, 1, schema, map[string]interface{}{"value": float64(i)}))
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, map[string]interface{}{"value": float64(i)}, metrics[0].Fields())
+	}
+	require.Equal(t, 1, requests)
+}
+
+func TestParseErrors(t *testing.T) {
+	ts := registry(t, map[int]string{1: `{"type": "record", "name": "m", "fields": [{"name": "value", "type": "double"}]}`})
+	defer ts.Close()
+
+	parser, err := New(&Config{SchemaRegistry: ts.URL})
+	require.NoError(t, err)
+
+	// Not in the wire format
+	_, err = parser.Parse([]byte("value=1"))
+	require.Error(t, err)
+
+	// Unknown schema
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 2, 0})
+	require.Error(t, err)
+
+	// Truncated payload
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 1, 0})
+	require.Error(t, err)
+}
+
+func TestNewWithoutRegistry(t *testing.T) {
+	_, err := New(&Config{})
+	require.Error(t, err)
+}
@@ -0,0 +1,255 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// schema walks the decoded values of goavro along their schema, to unwrap
+// the values of unions and convert the values to the types of metrics.
+type schema struct {
+	root  interface{}
+	named map[string]interface{}
+}
+
+func newSchema(definition string) (*schema, error) {
+	var root interface{}
+	if err := json.Unmarshal([]byte(definition), &root); err != nil {
+		return nil, fmt.Errorf("decoding schema failed: %v", err)
+	}
+
+	s := &schema{root: root, named: make(map[string]interface{})}
+	s.collect(root, "")
+	return s, nil
+}
+
+// record returns the values of a decoded record.
+func (s *schema) record(native interface{}) (map[string]interface{}, error) {
+	record, ok := s.value(s.root, "", native).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("message is not a record but %T", native)
+	}
+	return record, nil
+}
+
+// collect registers the named types of the schema by full name.
+func (s *schema) collect(node interface{}, namespace string) {
+	switch n := node.(type) {
+	case []interface{}:
+		for _, branch := range n {
+			s.collect(branch, namespace)
+		}
+	case map[string]interface{}:
+		typ, _ := n["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			name, ns := fullName(n, namespace)
+			s.named[name] = n
+			for _, field := range fields(n) {
+				s.collect(field["type"], ns)
+			}
+		case "array":
+			s.collect(n["items"], namespace)
+		case "map":
+			s.collect(n["values"], namespace)
+		default:
+			// Nested type definitions, e.g. {"type": {"type": "array", ...}}
+			if _, ok := n["type"].(string); !ok {
+				s.collect(n["type"], namespace)
+			}
+		}
+	}
+}
+
+func (s *schema) value(node interface{}, namespace string, native interface{}) interface{} {
+	if native == nil {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case string:
+		if isPrimitive(n) {
+			return convert(native)
+		}
+		name := n
+		if !strings.Contains(name, ".") && namespace != "" {
+			name = namespace + "." + name
+		}
+		if def, ok := s.named[name]; ok {
+			return s.value(def, namespace, native)
+		}
+		if def, ok := s.named[n]; ok {
+			return s.value(def, namespace, native)
+		}
+		return generic(native)
+	case []interface{}:
+		// Unions are decoded as a map with the type name of the branch as key
+		wrapped, ok := native.(map[string]interface{})
+		if !ok || len(wrapped) != 1 {
+			return generic(native)
+		}
+		for key, value := range wrapped {
+			for _, branch := range n {
+				if s.branchName(branch, namespace) == key {
+					return s.value(branch, namespace, value)
+				}
+			}
+			return generic(value)
+		}
+	case map[string]interface{}:
+		switch n["type"] {
+		case "record", "error":
+			record, ok := native.(map[string]interface{})
+			if !ok {
+				return generic(native)
+			}
+			_, ns := fullName(n, namespace)
+			values := make(map[string]interface{}, len(record))
+			for _, field := range fields(n) {
+				name, _ := field["name"].(string)
+				values[name] = s.value(field["type"], ns, record[name])
+			}
+			return values
+		case "enum":
+			return convert(native)
+		case "fixed":
+			return nil
+		case "array":
+			items, ok := native.([]interface{})
+			if !ok {
+				return generic(native)
+			}
+			values := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				values = append(values, s.value(n["items"], namespace, item))
+			}
+			return values
+		case "map":
+			entries, ok := native.(map[string]interface{})
+			if !ok {
+				return generic(native)
+			}
+			values := make(map[string]interface{}, len(entries))
+			for key, entry := range entries {
+				values[key] = s.value(n["values"], namespace, entry)
+			}
+			return values
+		default:
+			// Primitive types with logical types, or nested definitions
+			return s.value(n["type"], namespace, native)
+		}
+	}
+	return generic(native)
+}
+
+// branchName returns the name goavro uses for a branch of a union.
+func (s *schema) branchName(branch interface{}, namespace string) string {
+	switch b := branch.(type) {
+	case string:
+		if isPrimitive(b) || strings.Contains(b, ".") {
+			return b
+		}
+		if namespace != "" {
+			if _, ok := s.named[namespace+"."+b]; ok {
+				return namespace + "." + b
+			}
+		}
+		return b
+	case map[string]interface{}:
+		typ, _ := b["type"].(string)
+		switch typ {
+		case "record", "error", "enum", "fixed":
+			name, _ := fullName(b, namespace)
+			return name
+		}
+		if logical, ok := b["logicalType"].(string); ok {
+			return typ + "." + logical
+		}
+		return typ
+	}
+	return ""
+}
+
+func fullName(node map[string]interface{}, namespace string) (string, string) {
+	name, _ := node["name"].(string)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name, name[:i]
+	}
+	if ns, ok := node["namespace"].(string); ok {
+		namespace = ns
+	}
+	if namespace == "" {
+		return name, ""
+	}
+	return namespace + "." + name, namespace
+}
+
+func fields(node map[string]interface{}) []map[string]interface{} {
+	list, _ := node["fields"].([]interface{})
+	result := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if field, ok := item.(map[string]interface{}); ok {
+			result = append(result, field)
+		}
+	}
+	return result
+}
+
+func isPrimitive(name string) bool {
+	switch name {
+	case "null", "boolean", "int", "long", "float", "double", "bytes", "string":
+		return true
+	}
+	return false
+}
+
+// generic converts values without known schema.
+func generic(native interface{}) interface{} {
+	switch v := native.(type) {
+	case map[string]interface{}:
+		values := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			values[key] = generic(item)
+		}
+		return values
+	case []interface{}:
+		values := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			values = append(values, generic(item))
+		}
+		return values
+	default:
+		return convert(v)
+	}
+}
+
+// convert converts the primitive values of goavro to the types of metrics.
+// Bytes are dropped.
+func convert(native interface{}) interface{} {
+	switch v := native.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		return v
+	case string:
+		return v
+	case time.Time:
+		return v
+	case time.Duration:
+		return int64(v)
+	case *big.Rat:
+		f, _ := v.Float64()
+		return f
+	default:
+		return nil
+	}
+}
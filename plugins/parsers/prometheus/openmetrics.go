@@ -0,0 +1,180 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/prometheus/prometheus/pkg/exemplar"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/textparse"
+)
+
+const openMetricsMediaType = "application/openmetrics-text"
+
+// isOpenMetrics tells if the buffer is in the OpenMetrics format, which
+// always ends with an EOF marker.
+func isOpenMetrics(mediatype string, buf []byte) bool {
+	return mediatype == openMetricsMediaType || bytes.HasSuffix(bytes.TrimSpace(buf), []byte("# EOF"))
+}
+
+// family is the metric family of the samples being parsed.
+type family struct {
+	name  string
+	mtype textparse.MetricType
+	unit  string
+}
+
+// sample is a telegraf metric made of the samples of a family with the same
+// labels, such as the count, sum and creation time of a histogram.
+type sample struct {
+	tags   map[string]string
+	fields map[string]interface{}
+	vtype  telegraf.ValueType
+	time   time.Time
+}
+
+// parseOpenMetrics parses the OpenMetrics text format.  The series of a
+// family with the same labels are fields of the same metric, and exemplars
+// are fields prefixed with the name of their series.
+func (p *Parser) parseOpenMetrics(buf []byte, now time.Time) ([]telegraf.Metric, error) {
+	parser := textparse.NewOpenMetricsParser(buf)
+
+	var current family
+	var order []string
+	samples := make(map[string]*sample)
+	for {
+		entry, err := parser.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading openmetrics format failed: %s", err)
+		}
+
+		switch entry {
+		case textparse.EntryType:
+			name, mtype := parser.Type()
+			current.set(string(name))
+			current.mtype = mtype
+		case textparse.EntryUnit:
+			name, unit := parser.Unit()
+			current.set(string(name))
+			current.unit = string(unit)
+		case textparse.EntryHelp:
+			name, _ := parser.Help()
+			current.set(string(name))
+		case textparse.EntrySeries:
+			_, ts, value := parser.Series()
+
+			var lset labels.Labels
+			parser.Metric(&lset)
+			name := lset.Get(labels.MetricName)
+
+			f := current
+			if !strings.HasPrefix(name, f.name) {
+				f = family{name: name, mtype: textparse.MetricTypeUnknown}
+			}
+			if math.IsNaN(value) && isScalar(f.mtype) {
+				continue
+			}
+
+			key := f.name + lset.WithoutLabels(labels.MetricName).String()
+			s, ok := samples[key]
+			if !ok {
+				t := now
+				if ts != nil {
+					t = time.Unix(0, *ts*int64(time.Millisecond))
+				}
+				s = &sample{
+					tags:   p.makeTags(f, lset),
+					fields: make(map[string]interface{}),
+					vtype:  valueType(f.mtype),
+					time:   t,
+				}
+				samples[key] = s
+				order = append(order, key)
+			}
+			s.fields[name] = value
+
+			var e exemplar.Exemplar
+			if parser.Exemplar(&e) {
+				s.fields[name+"_exemplar_value"] = e.Value
+				if e.HasTs {
+					s.fields[name+"_exemplar_timestamp"] = float64(e.Ts) / 1e3
+				}
+				for _, l := range e.Labels {
+					s.fields[name+"_exemplar_label_"+l.Name] = l.Value
+				}
+			}
+		}
+	}
+
+	metrics := make([]telegraf.Metric, 0, len(order))
+	for _, key := range order {
+		s := samples[key]
+		metrics = append(metrics, metric.New("prometheus", s.tags, s.fields, s.time, s.vtype))
+	}
+	return metrics, nil
+}
+
+// set starts a new family when the metadata is of another family.
+func (f *family) set(name string) {
+	if f.name != name {
+		*f = family{name: name, mtype: textparse.MetricTypeUnknown}
+	}
+}
+
+func (p *Parser) makeTags(f family, lset labels.Labels) map[string]string {
+	tags := make(map[string]string, len(p.DefaultTags)+len(lset))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for _, l := range lset {
+		switch l.Name {
+		case labels.MetricName:
+			continue
+		case "le", "quantile":
+			// Format the bounds and quantiles as the classic text format
+			if v, err := strconv.ParseFloat(l.Value, 64); err == nil {
+				tags[l.Name] = fmt.Sprint(v)
+				continue
+			}
+		}
+		tags[l.Name] = l.Value
+	}
+	if _, ok := tags["unit"]; !ok && f.unit != "" {
+		tags["unit"] = f.unit
+	}
+	return tags
+}
+
+func isScalar(mtype textparse.MetricType) bool {
+	switch mtype {
+	case textparse.MetricTypeCounter, textparse.MetricTypeGauge, textparse.MetricTypeUnknown:
+		return true
+	}
+	return false
+}
+
+func valueType(mtype textparse.MetricType) telegraf.ValueType {
+	switch mtype {
+	case textparse.MetricTypeCounter:
+		return telegraf.Counter
+	case textparse.MetricTypeGauge, textparse.MetricTypeGaugeHistogram,
+		textparse.MetricTypeInfo, textparse.MetricTypeStateset:
+		return telegraf.Gauge
+	case textparse.MetricTypeHistogram:
+		return telegraf.Histogram
+	case textparse.MetricTypeSummary:
+		return telegraf.Summary
+	default:
+		return telegraf.Untyped
+	}
+}
@@ -0,0 +1,160 @@
+package prometheus
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const validOpenMetrics = `# TYPE http_requests counter
+# HELP http_requests Number of requests.
+http_requests_total{code="200"} 1027 1622745000.000
+http_requests_created{code="200"} 1622700000.5 1622745000.000
+# TYPE request_duration_seconds histogram
+# UNIT request_duration_seconds seconds
+request_duration_seconds_bucket{le="0.1"} 8 # {trace_id="abc123"} 0.067 1622744999.5
+request_duration_seconds_bucket{le="1.0"} 11 # {trace_id="def456"} 0.5
+request_duration_seconds_bucket{le="+Inf"} 12
+request_duration_seconds_count 12
+request_duration_seconds_sum 5.5
+request_duration_seconds_created 1622700000
+# TYPE rpc_latency summary
+rpc_latency{quantile="0.50"} 0.05
+rpc_latency_count 4
+rpc_latency_sum 0.2
+# TYPE queue_size gaugehistogram
+queue_size_bucket{le="10"} 3
+queue_size_bucket{le="+Inf"} 4
+queue_size_gcount 4
+queue_size_gsum 21
+# TYPE build info
+build_info{version="1.2.3"} 1
+# TYPE feature stateset
+feature{feature="a"} 1
+feature{feature="b"} 0
+# TYPE temperature gauge
+temperature{room="kitchen"} 21.5
+temperature{room="garage"} NaN
+some_value 3
+# EOF
+`
+
+func TestParsingOpenMetrics(t *testing.T) {
+	parser := Parser{
+		DefaultTags: map[string]string{"source": "test"},
+		Header:      http.Header{"Content-Type": []string{"application/openmetrics-text; version=1.0.0; charset=utf-8"}},
+	}
+	metrics, err := parser.Parse([]byte(validOpenMetrics))
+	require.NoError(t, err)
+
+	ts := time.Unix(1622745000, 0)
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "code": "200"},
+			map[string]interface{}{"http_requests_total": float64(1027), "http_requests_created": 1622700000.5},
+			ts, telegraf.Counter),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "le": "0.1", "unit": "seconds"},
+			map[string]interface{}{
+				"request_duration_seconds_bucket":                         float64(8),
+				"request_duration_seconds_bucket_exemplar_value":          0.067,
+				"request_duration_seconds_bucket_exemplar_timestamp":      1622744999.5,
+				"request_duration_seconds_bucket_exemplar_label_trace_id": "abc123",
+			},
+			ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "le": "1", "unit": "seconds"},
+			map[string]interface{}{
+				"request_duration_seconds_bucket":                         float64(11),
+				"request_duration_seconds_bucket_exemplar_value":          0.5,
+				"request_duration_seconds_bucket_exemplar_label_trace_id": "def456",
+			},
+			ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "le": "+Inf", "unit": "seconds"},
+			map[string]interface{}{"request_duration_seconds_bucket": float64(12)},
+			ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "unit": "seconds"},
+			map[string]interface{}{
+				"request_duration_seconds_count":   float64(12),
+				"request_duration_seconds_sum":     5.5,
+				"request_duration_seconds_created": float64(1622700000),
+			},
+			ts, telegraf.Histogram),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "quantile": "0.5"},
+			map[string]interface{}{"rpc_latency": 0.05},
+			ts, telegraf.Summary),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"rpc_latency_count": float64(4), "rpc_latency_sum": 0.2},
+			ts, telegraf.Summary),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "le": "10"},
+			map[string]interface{}{"queue_size_bucket": float64(3)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "le": "+Inf"},
+			map[string]interface{}{"queue_size_bucket": float64(4)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"queue_size_gcount": float64(4), "queue_size_gsum": float64(21)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "version": "1.2.3"},
+			map[string]interface{}{"build_info": float64(1)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "feature": "a"},
+			map[string]interface{}{"feature": float64(1)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "feature": "b"},
+			map[string]interface{}{"feature": float64(0)},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test", "room": "kitchen"},
+			map[string]interface{}{"temperature": 21.5},
+			ts, telegraf.Gauge),
+		testutil.MustMetric("prometheus",
+			map[string]string{"source": "test"},
+			map[string]interface{}{"some_value": float64(3)},
+			ts, telegraf.Untyped),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+	require.Equal(t, ts, metrics[0].Time())
+}
+
+func TestParsingOpenMetricsWithoutHeader(t *testing.T) {
+	parser := Parser{}
+	metrics, err := parser.Parse([]byte("# TYPE jobs counter\njobs_total 3 # {id=\"1\"} 1\n# EOF\n"))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("prometheus",
+			map[string]string{},
+			map[string]interface{}{
+				"jobs_total":                   float64(3),
+				"jobs_total_exemplar_value":    float64(1),
+				"jobs_total_exemplar_label_id": "1",
+			},
+			time.Unix(0, 0), telegraf.Counter),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics, testutil.IgnoreTime())
+}
+
+func TestParsingOpenMetricsInvalid(t *testing.T) {
+	parser := Parser{
+		Header: http.Header{"Content-Type": []string{"application/openmetrics-text"}},
+	}
+
+	// Missing EOF marker
+	_, err := parser.Parse([]byte("# TYPE jobs counter\njobs_total 3\n"))
+	require.Error(t, err)
+}
@@ -51,6 +51,8 @@ func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
 			}
 			metricFamilies[mf.GetName()] = mf
 		}
+	} else if isOpenMetrics(mediatype, buf) {
+		return p.parseOpenMetrics(buf, time.Now())
 	} else {
 		metricFamilies, err = parser.TextToMetricFamilies(reader)
 		if err != nil {
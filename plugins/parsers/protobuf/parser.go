@@ -0,0 +1,222 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/jhump/protoreflect/desc"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type Config struct {
+	// SchemaRegistry is the URL of the schema registry.
+	SchemaRegistry string
+	Record         schemaregistry.RecordConfig
+}
+
+// Parser parses Protobuf messages of the Confluent wire format, fetching
+// their schema from a schema registry.
+type Parser struct {
+	record   schemaregistry.RecordConfig
+	registry *schemaregistry.Client
+
+	sync.Mutex
+	files map[int]protoreflect.FileDescriptor
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.SchemaRegistry == "" {
+		return nil, errors.New("schema registry URL must be set")
+	}
+
+	registry, err := schemaregistry.NewClient(config.SchemaRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Parser{
+		record:   config.Record,
+		registry: registry,
+		files:    make(map[int]protoreflect.FileDescriptor),
+	}, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	id, payload, err := schemaregistry.Decode(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, payload, err := messageIndexes(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := p.file(id)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := messageDescriptor(file, indexes)
+	if err != nil {
+		return nil, fmt.Errorf("schema %d: %v", id, err)
+	}
+
+	msg := dynamicpb.NewMessage(md)
+	if err := proto.Unmarshal(payload, msg); err != nil {
+		return nil, fmt.Errorf("decoding message %s failed: %v", md.FullName(), err)
+	}
+
+	record, ok := messageValue(msg).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("message %s is not a record", md.FullName())
+	}
+
+	m, err := p.record.Metric(record)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	return nil, errors.New("parsing line is not supported by the protobuf format")
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.record.DefaultTags = tags
+}
+
+// file returns the file descriptor of a schema, fetching the schema and its
+// references from the registry the first time.
+func (p *Parser) file(id int) (protoreflect.FileDescriptor, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	if file, ok := p.files[id]; ok {
+		return file, nil
+	}
+
+	s, err := p.registry.SchemaByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.Type != "PROTOBUF" {
+		return nil, fmt.Errorf("schema %d is of type %s instead of PROTOBUF", id, s.Type)
+	}
+
+	name := fmt.Sprintf("schema-%d.proto", id)
+	sources := map[string]string{name: s.Schema}
+	if err := p.resolveReferences(s.References, sources); err != nil {
+		return nil, fmt.Errorf("resolving references of schema %d failed: %v", id, err)
+	}
+
+	parser := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(sources)}
+	fds, err := parser.ParseFiles(name)
+	if err != nil {
+		return nil, fmt.Errorf("parsing schema %d failed: %v", id, err)
+	}
+
+	file, err := registerFile(&protoregistry.Files{}, fds[0])
+	if err != nil {
+		return nil, fmt.Errorf("loading schema %d failed: %v", id, err)
+	}
+
+	p.files[id] = file
+	return file, nil
+}
+
+func (p *Parser) resolveReferences(references []schemaregistry.Reference, sources map[string]string) error {
+	for _, ref := range references {
+		if _, ok := sources[ref.Name]; ok {
+			continue
+		}
+
+		s, err := p.registry.SchemaBySubject(ref.Subject, ref.Version)
+		if err != nil {
+			return err
+		}
+		sources[ref.Name] = s.Schema
+
+		if err := p.resolveReferences(s.References, sources); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerFile registers a file and its dependencies, and returns the file.
+func registerFile(files *protoregistry.Files, fd *desc.FileDescriptor) (protoreflect.FileDescriptor, error) {
+	if file, err := files.FindFileByPath(fd.GetName()); err == nil {
+		return file, nil
+	}
+
+	for _, dep := range fd.GetDependencies() {
+		if _, err := registerFile(files, dep); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := protodesc.NewFile(fd.AsFileDescriptorProto(), files)
+	if err != nil {
+		return nil, err
+	}
+	if err := files.RegisterFile(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// messageIndexes decodes the indexes of the message type in the schema,
+// which follow the schema ID in the wire format.  The indexes are the
+// zig-zag encoded count and indexes, with a single 0 for the first message.
+func messageIndexes(buf []byte) ([]int, []byte, error) {
+	count, n := binary.Varint(buf)
+	if n <= 0 {
+		return nil, nil, errors.New("invalid message indexes")
+	}
+	buf = buf[n:]
+
+	if count == 0 {
+		return []int{0}, buf, nil
+	}
+	if count < 0 || count > int64(len(buf)) {
+		return nil, nil, fmt.Errorf("invalid number of message indexes %d", count)
+	}
+
+	indexes := make([]int, 0, count)
+	for i := int64(0); i < count; i++ {
+		index, n := binary.Varint(buf)
+		if n <= 0 || index < 0 {
+			return nil, nil, errors.New("invalid message indexes")
+		}
+		indexes = append(indexes, int(index))
+		buf = buf[n:]
+	}
+	return indexes, buf, nil
+}
+
+// messageDescriptor returns the message type at the indexes, the first
+// index being the one of the top-level message and the next ones of the
+// nested messages.
+func messageDescriptor(file protoreflect.FileDescriptor, indexes []int) (protoreflect.MessageDescriptor, error) {
+	messages := file.Messages()
+	var md protoreflect.MessageDescriptor
+	for _, index := range indexes {
+		if index >= messages.Len() {
+			return nil, fmt.Errorf("message index %v out of range", indexes)
+		}
+		md = messages.Get(index)
+		messages = md.Messages()
+	}
+	return md, nil
+}
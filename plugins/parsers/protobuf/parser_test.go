@@ -0,0 +1,236 @@
+package protobuf
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const commonSchema = `
+syntax = "proto3";
+package example.common;
+
+message Labels {
+  map<string, string> values = 1;
+}
+`
+
+const testSchema = `
+syntax = "proto3";
+package example;
+
+import "google/protobuf/timestamp.proto";
+import "common.proto";
+
+message Ignored {
+  string text = 1;
+}
+
+message Measurement {
+  enum Level {
+    LOW = 0;
+    HIGH = 1;
+  }
+
+  message CPU {
+    float user = 1;
+    float idle = 2;
+  }
+
+  string name = 1;
+  string host = 2;
+  google.protobuf.Timestamp time = 3;
+  double value = 4;
+  int64 count = 5;
+  uint32 errors = 6;
+  bool ok = 7;
+  Level level = 8;
+  repeated int32 cores = 9;
+  example.common.Labels labels = 10;
+  bytes raw = 11;
+  CPU cpu = 12;
+  oneof extra {
+    string note = 13;
+    int32 code = 14;
+  }
+}
+`
+
+func registry(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case "/schemas/ids/7":
+			response = map[string]interface{}{
+				"schemaType": "PROTOBUF",
+				"schema":     testSchema,
+				"references": []map[string]interface{}{
+					{"name": "common.proto", "subject": "common-value", "version": 1},
+				},
+			}
+		case "/subjects/common-value/versions/1":
+			response = map[string]interface{}{
+				"subject":    "common-value",
+				"version":    1,
+				"id":         8,
+				"schemaType": "PROTOBUF",
+				"schema":     commonSchema,
+			}
+		case "/schemas/ids/9":
+			response = map[string]interface{}{"schema": `"string"`}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		octets, err := json.Marshal(response)
+		require.NoError(t, err)
+		w.Write(octets)
+	}))
+}
+
+// descriptors compiles the test schemas.
+func descriptors(t *testing.T) protoreflect.FileDescriptor {
+	parser := protoparse.Parser{Accessor: protoparse.FileContentsFromMap(map[string]string{
+		"test.proto":   testSchema,
+		"common.proto": commonSchema,
+	})}
+	fds, err := parser.ParseFiles("test.proto")
+	require.NoError(t, err)
+
+	file, err := registerFile(&protoregistry.Files{}, fds[0])
+	require.NoError(t, err)
+	return file
+}
+
+func encode(t *testing.T, id int, indexes []byte, msg proto.Message) []byte {
+	payload, err := proto.Marshal(msg)
+	require.NoError(t, err)
+
+	buf := make([]byte, 5, 5+len(indexes)+len(payload))
+	binary.BigEndian.PutUint32(buf[1:], uint32(id))
+	buf = append(buf, indexes...)
+	return append(buf, payload...)
+}
+
+func TestParse(t *testing.T) {
+	ts := registry(t)
+	defer ts.Close()
+
+	parser, err := New(&Config{
+		SchemaRegistry: ts.URL,
+		Record: schemaregistry.RecordConfig{
+			MetricName:       "kafka_consumer",
+			MeasurementField: "name",
+			Tags:             []string{"host", "level", "labels_values_region"},
+			Timestamp:        "time",
+		},
+	})
+	require.NoError(t, err)
+	parser.SetDefaultTags(map[string]string{"topic": "metrics"})
+
+	md := descriptors(t).Messages().ByName("Measurement")
+	msg := dynamicpb.NewMessage(md)
+	fields := md.Fields()
+	msg.Set(fields.ByName("name"), protoreflect.ValueOfString("cpu"))
+	msg.Set(fields.ByName("host"), protoreflect.ValueOfString("a"))
+	tm := msg.Mutable(fields.ByName("time")).Message()
+	tm.Set(tm.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(1622745000))
+	tm.Set(tm.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(5000))
+	msg.Set(fields.ByName("value"), protoreflect.ValueOfFloat64(4.5))
+	msg.Set(fields.ByName("errors"), protoreflect.ValueOfUint32(3))
+	msg.Set(fields.ByName("ok"), protoreflect.ValueOfBool(true))
+	msg.Set(fields.ByName("level"), protoreflect.ValueOfEnum(1))
+	cores := msg.Mutable(fields.ByName("cores")).List()
+	cores.Append(protoreflect.ValueOfInt32(0))
+	cores.Append(protoreflect.ValueOfInt32(1))
+	labels := msg.Mutable(fields.ByName("labels")).Message()
+	labels.Mutable(labels.Descriptor().Fields().ByName("values")).Map().Set(
+		protoreflect.ValueOfString("region").MapKey(), protoreflect.ValueOfString("eu"))
+	msg.Set(fields.ByName("raw"), protoreflect.ValueOfBytes([]byte{1, 2}))
+	msg.Set(fields.ByName("code"), protoreflect.ValueOfInt32(12))
+
+	// Measurement is the second message of the schema
+	metrics, err := parser.Parse(encode(t, 7, []byte{2, 2}, msg))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "level": "HIGH", "labels_values_region": "eu", "topic": "metrics"},
+		map[string]interface{}{
+			"value":   4.5,
+			"count":   int64(0),
+			"errors":  uint64(3),
+			"ok":      true,
+			"cores_0": int64(0),
+			"cores_1": int64(1),
+			"code":    int64(12),
+		},
+		time.Unix(1622745000, 5000),
+	)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{expected}, metrics)
+}
+
+func TestParseMessageIndexes(t *testing.T) {
+	ts := registry(t)
+	defer ts.Close()
+
+	parser, err := New(&Config{SchemaRegistry: ts.URL, Record: schemaregistry.RecordConfig{MetricName: "test"}})
+	require.NoError(t, err)
+
+	file := descriptors(t)
+
+	// A single 0 stands for the first message
+	ignored := dynamicpb.NewMessage(file.Messages().ByName("Ignored"))
+	ignored.Set(ignored.Descriptor().Fields().ByName("text"), protoreflect.ValueOfString("hello"))
+	metrics, err := parser.Parse(encode(t, 7, []byte{0}, ignored))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"text": "hello"}, metrics[0].Fields())
+
+	// Nested messages follow the index of their parent
+	cpu := dynamicpb.NewMessage(file.Messages().ByName("Measurement").Messages().ByName("CPU"))
+	cpu.Set(cpu.Descriptor().Fields().ByName("user"), protoreflect.ValueOfFloat32(0.5))
+	metrics, err = parser.Parse(encode(t, 7, []byte{4, 2, 0}, cpu))
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"user": 0.5, "idle": float64(0)}, metrics[0].Fields())
+
+	// Out of range
+	_, err = parser.Parse(encode(t, 7, []byte{2, 4}, cpu))
+	require.Error(t, err)
+}
+
+func TestParseErrors(t *testing.T) {
+	ts := registry(t)
+	defer ts.Close()
+
+	parser, err := New(&Config{SchemaRegistry: ts.URL})
+	require.NoError(t, err)
+
+	// Not in the wire format
+	_, err = parser.Parse([]byte("value=1"))
+	require.Error(t, err)
+
+	// Unknown schema
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 1, 0})
+	require.Error(t, err)
+
+	// Avro schema
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 9, 0})
+	require.Error(t, err)
+
+	// Missing message indexes
+	_, err = parser.Parse([]byte{0, 0, 0, 0, 7})
+	require.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package protobuf
+
+import (
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// messageValue converts a message to a map of its fields.  Well-known
+// timestamps, durations and wrappers are converted to their value.
+func messageValue(msg protoreflect.Message) interface{} {
+	md := msg.Descriptor()
+	name := string(md.FullName())
+
+	switch {
+	case name == "google.protobuf.Timestamp":
+		fields := md.Fields()
+		seconds := msg.Get(fields.ByName("seconds")).Int()
+		nanos := msg.Get(fields.ByName("nanos")).Int()
+		return time.Unix(seconds, nanos).UTC()
+	case name == "google.protobuf.Duration":
+		fields := md.Fields()
+		seconds := msg.Get(fields.ByName("seconds")).Int()
+		nanos := msg.Get(fields.ByName("nanos")).Int()
+		return int64(time.Duration(seconds)*time.Second + time.Duration(nanos))
+	case strings.HasPrefix(name, "google.protobuf.") && strings.HasSuffix(name, "Value"):
+		if fd := md.Fields().ByName("value"); fd != nil {
+			return fieldValue(fd, msg.Get(fd))
+		}
+	}
+
+	values := make(map[string]interface{}, md.Fields().Len())
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		// Skip the unset message, oneof and optional fields but keep the
+		// zero values of the other fields.
+		if fd.HasPresence() && !msg.Has(fd) {
+			continue
+		}
+		values[string(fd.Name())] = fieldValue(fd, msg.Get(fd))
+	}
+	return values
+}
+
+func fieldValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		values := make([]interface{}, 0, list.Len())
+		for i := 0; i < list.Len(); i++ {
+			values = append(values, scalarValue(fd, list.Get(i)))
+		}
+		return values
+	case fd.IsMap():
+		values := make(map[string]interface{}, v.Map().Len())
+		v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			values[key.String()] = scalarValue(fd.MapValue(), value)
+			return true
+		})
+		return values
+	default:
+		return scalarValue(fd, v)
+	}
+}
+
+// scalarValue converts a single value to the types of metrics.  Bytes are
+// dropped.
+func scalarValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.BoolKind:
+		return v.Bool()
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return int64(v.Enum())
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return v.Int()
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return v.Uint()
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return v.Float()
+	case protoreflect.StringKind:
+		return v.String()
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageValue(v.Message())
+	default:
+		return nil
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -18,6 +20,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
 	"github.com/influxdata/telegraf/plugins/parsers/prometheus"
 	"github.com/influxdata/telegraf/plugins/parsers/prometheusremotewrite"
+	"github.com/influxdata/telegraf/plugins/parsers/protobuf"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
 	"github.com/influxdata/telegraf/plugins/parsers/xpath"
@@ -168,6 +171,22 @@ type Config struct {
 
 	// JSONPath configuration
 	JSONV2Config []JSONV2Config `toml:"json_v2"`
+
+	// Avro configuration
+	AvroSchemaRegistry   string   `toml:"avro_schema_registry"`
+	AvroMeasurementField string   `toml:"avro_measurement_field"`
+	AvroTags             []string `toml:"avro_tags"`
+	AvroTimestamp        string   `toml:"avro_timestamp"`
+	AvroTimestampFormat  string   `toml:"avro_timestamp_format"`
+	AvroFieldSeparator   string   `toml:"avro_field_separator"`
+
+	// Protobuf configuration
+	ProtobufSchemaRegistry   string   `toml:"protobuf_schema_registry"`
+	ProtobufMeasurementField string   `toml:"protobuf_measurement_field"`
+	ProtobufTags             []string `toml:"protobuf_tags"`
+	ProtobufTimestamp        string   `toml:"protobuf_timestamp"`
+	ProtobufTimestampFormat  string   `toml:"protobuf_timestamp_format"`
+	ProtobufFieldSeparator   string   `toml:"protobuf_field_separator"`
 }
 
 type XPathConfig xpath.Config
@@ -276,6 +295,32 @@ func NewParser(config *Config) (Parser, error) {
 		}
 	case "json_v2":
 		parser, err = NewJSONPathParser(config.JSONV2Config)
+	case "avro":
+		parser, err = avro.New(&avro.Config{
+			SchemaRegistry: config.AvroSchemaRegistry,
+			Record: schemaregistry.RecordConfig{
+				MetricName:       config.MetricName,
+				MeasurementField: config.AvroMeasurementField,
+				Tags:             config.AvroTags,
+				Timestamp:        config.AvroTimestamp,
+				TimestampFormat:  config.AvroTimestampFormat,
+				FieldSeparator:   config.AvroFieldSeparator,
+				DefaultTags:      config.DefaultTags,
+			},
+		})
+	case "protobuf":
+		parser, err = protobuf.New(&protobuf.Config{
+			SchemaRegistry: config.ProtobufSchemaRegistry,
+			Record: schemaregistry.RecordConfig{
+				MetricName:       config.MetricName,
+				MeasurementField: config.ProtobufMeasurementField,
+				Tags:             config.ProtobufTags,
+				Timestamp:        config.ProtobufTimestamp,
+				TimestampFormat:  config.ProtobufTimestampFormat,
+				FieldSeparator:   config.ProtobufFieldSeparator,
+				DefaultTags:      config.DefaultTags,
+			},
+		})
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
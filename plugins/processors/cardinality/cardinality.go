@@ -0,0 +1,253 @@
+package cardinality
+
+import (
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  ## Maximum number of distinct series per measurement, a series being
+  ## identified by the measurement name and its tag set.
+  # limit = 10000
+
+  ## Sliding window over which the distinct series are counted.  A series
+  ## not seen for this long is no longer counted.
+  # window = "1h"
+
+  ## Action on new series once a measurement is over its limit:
+  ##   "drop"   - drop the metrics of the new series
+  ##   "strip"  - remove the tag with the most distinct values
+  ##   "bucket" - replace the value of the tag with the most distinct values
+  ##              by one of a fixed number of buckets, e.g. "bucket_3"
+  ## Metrics of the series already counted are always passed.
+  # action = "drop"
+
+  ## Number of buckets used by the "bucket" action.
+  # buckets = 16
+`
+
+const (
+	actionDrop   = "drop"
+	actionStrip  = "strip"
+	actionBucket = "bucket"
+)
+
+type Cardinality struct {
+	Limit   int             `toml:"limit"`
+	Window  config.Duration `toml:"window"`
+	Action  string          `toml:"action"`
+	Buckets int             `toml:"buckets"`
+	Log     telegraf.Logger `toml:"-"`
+
+	measurements map[string]*measurement
+	lastCleanup  time.Time
+	now          func() time.Time
+}
+
+// measurement holds the series of a measurement seen within the window, with
+// the distinct values of each of their tags.
+type measurement struct {
+	series  map[uint64]time.Time
+	values  map[string]map[string]time.Time
+	limited map[string]selfstat.Stat
+}
+
+func newMeasurement() *measurement {
+	return &measurement{
+		series:  make(map[uint64]time.Time),
+		values:  make(map[string]map[string]time.Time),
+		limited: make(map[string]selfstat.Stat),
+	}
+}
+
+func (c *Cardinality) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Cardinality) Description() string {
+	return "Limit the number of distinct series per measurement"
+}
+
+func (c *Cardinality) Init() error {
+	if c.Limit <= 0 {
+		return fmt.Errorf("limit must be positive")
+	}
+	if c.Window <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+
+	switch c.Action {
+	case "":
+		c.Action = actionDrop
+	case actionDrop, actionStrip:
+	case actionBucket:
+		if c.Buckets <= 0 {
+			return fmt.Errorf("buckets must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid action %q", c.Action)
+	}
+
+	c.measurements = make(map[string]*measurement)
+	if c.now == nil {
+		c.now = time.Now
+	}
+	c.lastCleanup = c.now()
+	return nil
+}
+
+func (c *Cardinality) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := c.now()
+	c.cleanup(now)
+
+	out := in[:0]
+	for _, metric := range in {
+		if c.apply(metric, now) {
+			out = append(out, metric)
+		} else {
+			metric.Drop()
+		}
+	}
+	return out
+}
+
+// apply counts the series of the metric, and applies the action once over
+// the limit.  It returns false if the metric must be dropped.
+func (c *Cardinality) apply(metric telegraf.Metric, now time.Time) bool {
+	m, ok := c.measurements[metric.Name()]
+	if !ok {
+		m = newMeasurement()
+		c.measurements[metric.Name()] = m
+	}
+
+	id := metric.HashID()
+	if _, ok := m.series[id]; ok || len(m.series) < c.Limit {
+		m.add(metric, id, now)
+		return true
+	}
+
+	tag := m.highestCardinalityTag(metric)
+	c.limited(metric.Name(), m, tag)
+	if tag == "" || c.Action == actionDrop {
+		return false
+	}
+
+	if c.Action == actionStrip {
+		metric.RemoveTag(tag)
+	} else {
+		value, _ := metric.GetTag(tag)
+		metric.AddTag(tag, bucket(value, c.Buckets))
+	}
+
+	// The rewritten series are counted but not limited again.  This removes
+	// the values of the tag driving the growth, but new values of the other
+	// tags still add series over the limit.
+	m.add(metric, metric.HashID(), now)
+	return true
+}
+
+// limited reports a new series over the limit, caused by the given tag.
+func (c *Cardinality) limited(name string, m *measurement, tag string) {
+	stat, ok := m.limited[tag]
+	if !ok {
+		if tag == "" {
+			c.Log.Warnf("Measurement %q is over the limit of %d series", name, c.Limit)
+		} else {
+			c.Log.Warnf("Measurement %q is over the limit of %d series, caused by tag %q with %d distinct values",
+				name, c.Limit, tag, len(m.values[tag]))
+		}
+		stat = selfstat.Register("cardinality", "limited_series", map[string]string{
+			"measurement": name,
+			"tag":         tag,
+		})
+		m.limited[tag] = stat
+	}
+	stat.Incr(1)
+}
+
+// cleanup forgets the series and tag values not seen within the window.  As
+// dedup does, it only runs once per tenth of the window to save CPU.
+func (c *Cardinality) cleanup(now time.Time) {
+	window := time.Duration(c.Window)
+	if now.Sub(c.lastCleanup) < window/10 {
+		return
+	}
+	c.lastCleanup = now
+
+	for name, m := range c.measurements {
+		for id, seen := range m.series {
+			if now.Sub(seen) >= window {
+				delete(m.series, id)
+			}
+		}
+		for key, values := range m.values {
+			for value, seen := range values {
+				if now.Sub(seen) >= window {
+					delete(values, value)
+				}
+			}
+			if len(values) == 0 {
+				delete(m.values, key)
+			}
+		}
+
+		// Warn again if the measurement goes over the limit again
+		if len(m.series) < c.Limit {
+			m.limited = make(map[string]selfstat.Stat)
+		}
+		if len(m.series) == 0 {
+			delete(c.measurements, name)
+		}
+	}
+}
+
+func (m *measurement) add(metric telegraf.Metric, id uint64, now time.Time) {
+	m.series[id] = now
+	for _, tag := range metric.TagList() {
+		values, ok := m.values[tag.Key]
+		if !ok {
+			values = make(map[string]time.Time)
+			m.values[tag.Key] = values
+		}
+		values[tag.Value] = now
+	}
+}
+
+// highestCardinalityTag returns the tag of the metric with the most distinct
+// values, or an empty string if the metric has no tags.
+func (m *measurement) highestCardinalityTag(metric telegraf.Metric) string {
+	var highest string
+	var count int
+	for _, tag := range metric.TagList() {
+		if n := len(m.values[tag.Key]); highest == "" || n > count {
+			highest = tag.Key
+			count = n
+		}
+	}
+	return highest
+}
+
+// bucket returns the bucket of a tag value.
+func bucket(value string, buckets int) string {
+	h := fnv.New64a()
+	// Writes to a hash never fail
+	//nolint:errcheck,revive
+	h.Write([]byte(value))
+	return fmt.Sprintf("bucket_%d", h.Sum64()%uint64(buckets))
+}
+
+func init() {
+	processors.Add("cardinality", func() telegraf.Processor {
+		return &Cardinality{
+			Limit:   10000,
+			Window:  config.Duration(time.Hour),
+			Buckets: 16,
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package cardinality
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric(name, tags, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+}
+
+func newCardinality(t *testing.T, action string, now *time.Time) *Cardinality {
+	plugin := &Cardinality{
+		Limit:   2,
+		Window:  config.Duration(time.Minute),
+		Action:  action,
+		Buckets: 4,
+		Log:     testutil.Logger{},
+		now:     func() time.Time { return *now },
+	}
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestCardinalityDrop(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	plugin := newCardinality(t, "drop", &now)
+
+	actual := plugin.Apply(
+		newMetric("http_drop", map[string]string{"host": "a", "request": "1"}),
+		newMetric("http_drop", map[string]string{"host": "a", "request": "2"}),
+		newMetric("http_drop", map[string]string{"host": "a", "request": "3"}),
+		newMetric("http_drop", map[string]string{"host": "a", "request": "1"}),
+		newMetric("other_drop", map[string]string{"host": "a", "request": "3"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric("http_drop", map[string]string{"host": "a", "request": "1"}),
+		newMetric("http_drop", map[string]string{"host": "a", "request": "2"}),
+		newMetric("http_drop", map[string]string{"host": "a", "request": "1"}),
+		newMetric("other_drop", map[string]string{"host": "a", "request": "3"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+
+	stat, ok := plugin.measurements["http_drop"].limited["request"]
+	require.True(t, ok)
+	require.Equal(t, int64(1), stat.Get())
+	require.Equal(t, map[string]string{"measurement": "http_drop", "tag": "request"}, stat.Tags())
+}
+
+func TestCardinalityStrip(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	plugin := newCardinality(t, "strip", &now)
+
+	actual := plugin.Apply(
+		newMetric("http_strip", map[string]string{"host": "a", "request": "1"}),
+		newMetric("http_strip", map[string]string{"host": "a", "request": "2"}),
+		newMetric("http_strip", map[string]string{"host": "a", "request": "3"}),
+		newMetric("http_strip", map[string]string{"host": "b", "request": "4"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric("http_strip", map[string]string{"host": "a", "request": "1"}),
+		newMetric("http_strip", map[string]string{"host": "a", "request": "2"}),
+		newMetric("http_strip", map[string]string{"host": "a"}),
+		newMetric("http_strip", map[string]string{"host": "b"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Equal(t, int64(2), plugin.measurements["http_strip"].limited["request"].Get())
+}
+
+func TestCardinalityBucket(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	plugin := newCardinality(t, "bucket", &now)
+
+	var input []telegraf.Metric
+	for _, request := range []string{"1", "2", "3", "4", "5", "6", "7", "8", "9", "10"} {
+		input = append(input, newMetric("http_bucket", map[string]string{"host": "a", "request": request}))
+	}
+	actual := plugin.Apply(input...)
+	require.Len(t, actual, 10)
+
+	requests := make(map[string]bool)
+	for _, m := range actual[2:] {
+		value, ok := m.GetTag("request")
+		require.True(t, ok)
+		require.Regexp(t, `^bucket_[0-3]$`, value)
+		requests[value] = true
+	}
+	require.LessOrEqual(t, len(requests), 4)
+}
+
+func TestCardinalityWindow(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	plugin := newCardinality(t, "drop", &now)
+
+	actual := plugin.Apply(
+		newMetric("http_window", map[string]string{"request": "1"}),
+		newMetric("http_window", map[string]string{"request": "2"}),
+		newMetric("http_window", map[string]string{"request": "3"}),
+	)
+	require.Len(t, actual, 2)
+
+	// The first series is still seen, while the second one expires
+	now = now.Add(30 * time.Second)
+	actual = plugin.Apply(newMetric("http_window", map[string]string{"request": "1"}))
+	require.Len(t, actual, 1)
+
+	now = now.Add(40 * time.Second)
+	actual = plugin.Apply(
+		newMetric("http_window", map[string]string{"request": "3"}),
+		newMetric("http_window", map[string]string{"request": "4"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric("http_window", map[string]string{"request": "3"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestCardinalityNoTags(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	plugin := newCardinality(t, "strip", &now)
+	plugin.Limit = 1
+
+	actual := plugin.Apply(
+		newMetric("http_notags", map[string]string{"host": "a"}),
+		newMetric("http_notags", map[string]string{}),
+	)
+	expected := []telegraf.Metric{
+		newMetric("http_notags", map[string]string{"host": "a"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestCardinalityInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Cardinality
+	}{
+		{
+			name:   "no limit",
+			plugin: &Cardinality{Window: config.Duration(time.Hour)},
+		},
+		{
+			name:   "no window",
+			plugin: &Cardinality{Limit: 10},
+		},
+		{
+			name:   "invalid action",
+			plugin: &Cardinality{Limit: 10, Window: config.Duration(time.Hour), Action: "truncate"},
+		},
+		{
+			name:   "no buckets",
+			plugin: &Cardinality{Limit: 10, Window: config.Duration(time.Hour), Action: "bucket"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
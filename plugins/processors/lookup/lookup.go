@@ -0,0 +1,257 @@
+package lookup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	common "github.com/influxdata/telegraf/plugins/common/template"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/selfstat"
+)
+
+const sampleConfig = `
+  ## Lookup file, in CSV or JSON format.
+  file = "/etc/telegraf/inventory.csv"
+
+  ## Format of the lookup file, "csv" or "json".  By default it is given by
+  ## the extension of the file.
+  ##
+  ## A CSV file starts with a header row naming its columns.  The key of each
+  ## row is taken from the key column, and its other non-empty columns are
+  ## added as tags.
+  ##
+  ## A JSON file holds an object mapping each key to an object of tags, for
+  ## example {"web01:eu": {"rack": "r12", "owner": "web"}}.
+  # format = "csv"
+
+  ## Column of the CSV file holding the keys.  By default the first column.
+  # key_column = "host"
+
+  ## Go template used to create the key of a metric, from its name, tags or
+  ## fields, for example '{{ .Tag "host" }}:{{ .Tag "region" }}' to combine
+  ## several tags.  In order to ease TOML escaping requirements, you may wish
+  ## to use single quotes around the template string.
+  key = '{{ .Tag "host" }}'
+
+  ## Interval at which the file is checked for changes, and reloaded when
+  ## modified.  Set to 0 to never reload the file.
+  # reload_interval = "30s"
+
+  ## What to do with the metrics not matching any row of the file:
+  ##   "pass"    - pass the metric unchanged
+  ##   "drop"    - drop the metric
+  ##   "default" - add the default tags to the metric
+  # on_miss = "pass"
+
+  ## Tags added to the metrics not matching any row, with on_miss = "default".
+  # [processors.lookup.default_tags]
+  #   rack = "unknown"
+`
+
+const (
+	onMissPass    = "pass"
+	onMissDrop    = "drop"
+	onMissDefault = "default"
+)
+
+const defaultReloadInterval = 30 * time.Second
+
+type Lookup struct {
+	File           string            `toml:"file"`
+	Format         string            `toml:"format"`
+	KeyColumn      string            `toml:"key_column"`
+	Key            string            `toml:"key"`
+	ReloadInterval config.Duration   `toml:"reload_interval"`
+	OnMiss         string            `toml:"on_miss"`
+	DefaultTags    map[string]string `toml:"default_tags"`
+	Log            telegraf.Logger   `toml:"-"`
+
+	tmpl *template.Template
+
+	lock    sync.RWMutex
+	table   table
+	modTime time.Time
+	size    int64
+
+	hits   selfstat.Stat
+	misses selfstat.Stat
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func (l *Lookup) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Lookup) Description() string {
+	return "Adds the tags of the matching row of a CSV or JSON lookup file"
+}
+
+func (l *Lookup) Init() error {
+	if l.File == "" {
+		return fmt.Errorf("file must be set")
+	}
+
+	format, err := fileFormat(l.Format, l.File)
+	if err != nil {
+		return err
+	}
+	l.Format = format
+	if l.KeyColumn != "" && l.Format != formatCSV {
+		return fmt.Errorf("key_column is only supported with the %q format", formatCSV)
+	}
+
+	switch l.OnMiss {
+	case "":
+		l.OnMiss = onMissPass
+	case onMissPass, onMissDrop:
+	case onMissDefault:
+		if len(l.DefaultTags) == 0 {
+			return fmt.Errorf("default_tags must be set with on_miss = %q", onMissDefault)
+		}
+	default:
+		return fmt.Errorf("invalid on_miss %q", l.OnMiss)
+	}
+
+	if l.Key == "" {
+		return fmt.Errorf("key must be set")
+	}
+	l.tmpl, err = template.New("key").Parse(l.Key)
+	if err != nil {
+		return fmt.Errorf("parsing key template: %w", err)
+	}
+
+	tags := map[string]string{"file": l.File}
+	l.hits = selfstat.Register("lookup", "hits", tags)
+	l.misses = selfstat.Register("lookup", "misses", tags)
+	return nil
+}
+
+func (l *Lookup) Start(_ telegraf.Accumulator) error {
+	info, err := os.Stat(l.File)
+	if err != nil {
+		return err
+	}
+	if err := l.load(info); err != nil {
+		return fmt.Errorf("loading %q: %w", l.File, err)
+	}
+
+	l.done = make(chan struct{})
+	if l.ReloadInterval > 0 {
+		l.wg.Add(1)
+		go l.watch(time.Duration(l.ReloadInterval))
+	}
+	return nil
+}
+
+func (l *Lookup) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
+	tags, found, err := l.lookup(metric)
+	if err != nil {
+		l.Log.Errorf("Creating key: %v", err)
+	}
+
+	if found {
+		l.hits.Incr(1)
+		for name, value := range tags {
+			metric.AddTag(name, value)
+		}
+		acc.AddMetric(metric)
+		return nil
+	}
+
+	l.misses.Incr(1)
+	switch l.OnMiss {
+	case onMissDrop:
+		metric.Drop()
+		return nil
+	case onMissDefault:
+		for name, value := range l.DefaultTags {
+			metric.AddTag(name, value)
+		}
+	}
+	acc.AddMetric(metric)
+	return nil
+}
+
+func (l *Lookup) Stop() error {
+	if l.done != nil {
+		close(l.done)
+		l.wg.Wait()
+	}
+	return nil
+}
+
+// lookup returns the tags of the row matching the metric.
+func (l *Lookup) lookup(metric telegraf.Metric) (map[string]string, bool, error) {
+	var b strings.Builder
+	if err := l.tmpl.Execute(&b, common.NewTemplateMetric(metric)); err != nil {
+		return nil, false, err
+	}
+
+	l.lock.RLock()
+	defer l.lock.RUnlock()
+	tags, found := l.table[b.String()]
+	return tags, found, nil
+}
+
+// watch reloads the file when its modification time or size changes.  The
+// current table is kept when the file cannot be read.
+func (l *Lookup) watch(interval time.Duration) {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.done:
+			return
+		case <-ticker.C:
+			info, err := os.Stat(l.File)
+			if err != nil {
+				l.Log.Errorf("Checking %q: %v", l.File, err)
+				continue
+			}
+			if info.ModTime().Equal(l.modTime) && info.Size() == l.size {
+				continue
+			}
+			if err := l.load(info); err != nil {
+				l.Log.Errorf("Reloading %q, keeping the previous content: %v", l.File, err)
+				continue
+			}
+			l.Log.Debugf("Reloaded %q", l.File)
+		}
+	}
+}
+
+// load reads the file, whose modification time and size are then remembered
+// even when it is invalid, so that it is only read again once modified.
+func (l *Lookup) load(info os.FileInfo) error {
+	l.modTime = info.ModTime()
+	l.size = info.Size()
+
+	t, err := loadTable(l.File, l.Format, l.KeyColumn)
+	if err != nil {
+		return err
+	}
+
+	l.lock.Lock()
+	l.table = t
+	l.lock.Unlock()
+	return nil
+}
+
+func init() {
+	processors.AddStreaming("lookup", func() telegraf.StreamingProcessor {
+		return &Lookup{
+			ReloadInterval: config.Duration(defaultReloadInterval),
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package lookup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const inventoryCSV = `host,region,rack,owner
+web01,eu,r12,web
+db01,eu,r3,
+`
+
+const inventoryJSON = `{
+  "web01:eu": {"rack": "r12", "owner": "web"},
+  "db01:eu": {"rack": "r3", "replicas": 2, "primary": true, "owner": null}
+}`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func newMetric(tags map[string]string) telegraf.Metric {
+	return testutil.MustMetric("cpu", tags, map[string]interface{}{"value": 42}, time.Unix(0, 0))
+}
+
+func process(t *testing.T, plugin *Lookup, metrics ...telegraf.Metric) []telegraf.Metric {
+	acc := &testutil.Accumulator{}
+	for _, m := range metrics {
+		require.NoError(t, plugin.Add(m, acc))
+	}
+	return acc.GetTelegrafMetrics()
+}
+
+func TestLookupCSV(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Lookup{
+		File:      writeFile(t, dir, "inventory.csv", inventoryCSV),
+		KeyColumn: "host",
+		Key:       `{{ .Tag "host" }}`,
+		Log:       testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+	defer plugin.Stop()
+
+	actual := process(t, plugin,
+		newMetric(map[string]string{"host": "web01"}),
+		newMetric(map[string]string{"host": "db01"}),
+		newMetric(map[string]string{"host": "app01"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+		newMetric(map[string]string{"host": "db01", "region": "eu", "rack": "r3"}),
+		newMetric(map[string]string{"host": "app01"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Equal(t, int64(2), plugin.hits.Get())
+	require.Equal(t, int64(1), plugin.misses.Get())
+}
+
+func TestLookupJSON(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	plugin := &Lookup{
+		File: writeFile(t, dir, "inventory.json", inventoryJSON),
+		Key:  `{{ .Tag "host" }}:{{ .Tag "region" }}`,
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+	defer plugin.Stop()
+
+	actual := process(t, plugin,
+		newMetric(map[string]string{"host": "web01", "region": "eu"}),
+		newMetric(map[string]string{"host": "db01", "region": "eu"}),
+		newMetric(map[string]string{"host": "web01", "region": "us"}),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+		newMetric(map[string]string{"host": "db01", "region": "eu", "rack": "r3", "replicas": "2", "primary": "true"}),
+		newMetric(map[string]string{"host": "web01", "region": "us"}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestLookupOnMiss(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "inventory.csv", inventoryCSV)
+
+	tests := []struct {
+		name        string
+		onMiss      string
+		defaultTags map[string]string
+		expected    []telegraf.Metric
+	}{
+		{
+			name:   "pass",
+			onMiss: "pass",
+			expected: []telegraf.Metric{
+				newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+				newMetric(map[string]string{"host": "app01"}),
+			},
+		},
+		{
+			name:   "drop",
+			onMiss: "drop",
+			expected: []telegraf.Metric{
+				newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+			},
+		},
+		{
+			name:        "default",
+			onMiss:      "default",
+			defaultTags: map[string]string{"rack": "unknown"},
+			expected: []telegraf.Metric{
+				newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+				newMetric(map[string]string{"host": "app01", "rack": "unknown"}),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Lookup{
+				File:        path,
+				Key:         `{{ .Tag "host" }}`,
+				OnMiss:      tt.onMiss,
+				DefaultTags: tt.defaultTags,
+				Log:         testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.NoError(t, plugin.Start(nil))
+			defer plugin.Stop()
+
+			actual := process(t, plugin,
+				newMetric(map[string]string{"host": "web01"}),
+				newMetric(map[string]string{"host": "app01"}),
+			)
+			testutil.RequireMetricsEqual(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestLookupReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "inventory.csv", inventoryCSV)
+
+	plugin := &Lookup{
+		File:           path,
+		Key:            `{{ .Tag "host" }}`,
+		ReloadInterval: config.Duration(10 * time.Millisecond),
+		Log:            testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.NoError(t, plugin.Start(nil))
+	defer plugin.Stop()
+
+	// An invalid file keeps the previous content
+	writeFile(t, dir, "inventory.csv", "host,rack\nweb01\n")
+	time.Sleep(50 * time.Millisecond)
+	actual := process(t, plugin, newMetric(map[string]string{"host": "web01"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "region": "eu", "rack": "r12", "owner": "web"}),
+	}, actual)
+
+	writeFile(t, dir, "inventory.csv", "host,rack\nweb01,r1\napp01,r2\n")
+	require.Eventually(t, func() bool {
+		actual := process(t, plugin, newMetric(map[string]string{"host": "app01"}))
+		return len(actual) == 1 && actual[0].HasTag("rack")
+	}, 5*time.Second, 10*time.Millisecond)
+
+	actual = process(t, plugin, newMetric(map[string]string{"host": "web01"}))
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		newMetric(map[string]string{"host": "web01", "rack": "r1"}),
+	}, actual)
+}
+
+func TestLookupInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Lookup
+	}{
+		{
+			name:   "missing file",
+			plugin: &Lookup{Key: `{{ .Tag "host" }}`},
+		},
+		{
+			name:   "missing key",
+			plugin: &Lookup{File: "inventory.csv"},
+		},
+		{
+			name:   "unknown format",
+			plugin: &Lookup{File: "inventory.txt", Key: `{{ .Tag "host" }}`},
+		},
+		{
+			name:   "key column with json",
+			plugin: &Lookup{File: "inventory.json", KeyColumn: "host", Key: `{{ .Tag "host" }}`},
+		},
+		{
+			name:   "invalid on_miss",
+			plugin: &Lookup{File: "inventory.csv", Key: `{{ .Tag "host" }}`, OnMiss: "ignore"},
+		},
+		{
+			name:   "default without tags",
+			plugin: &Lookup{File: "inventory.csv", Key: `{{ .Tag "host" }}`, OnMiss: "default"},
+		},
+		{
+			name:   "invalid template",
+			plugin: &Lookup{File: "inventory.csv", Key: `{{ .Tag "host" `},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
+
+func TestLookupInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lookup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		keyColumn string
+	}{
+		{
+			name: "empty csv",
+			file: "inventory.csv",
+		},
+		{
+			name:      "unknown key column",
+			file:      "inventory.csv",
+			content:   inventoryCSV,
+			keyColumn: "name",
+		},
+		{
+			name:    "nested json",
+			file:    "inventory.json",
+			content: `{"web01": {"rack": {"name": "r12"}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Lookup{
+				File:      writeFile(t, dir, tt.file, tt.content),
+				KeyColumn: tt.keyColumn,
+				Key:       `{{ .Tag "host" }}`,
+				Log:       testutil.Logger{},
+			}
+			require.NoError(t, plugin.Init())
+			require.Error(t, plugin.Start(nil))
+		})
+	}
+
+	plugin := &Lookup{
+		File: filepath.Join(dir, "missing.csv"),
+		Key:  `{{ .Tag "host" }}`,
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	require.Error(t, plugin.Start(nil))
+}
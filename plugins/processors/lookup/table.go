@@ -0,0 +1,128 @@
+package lookup
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// table maps the keys of the rows to the tags added to the metrics.
+type table map[string]map[string]string
+
+// fileFormat returns the configured format, or the one of the file extension.
+func fileFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	switch format {
+	case formatCSV, formatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid format %q, expected %q or %q", format, formatCSV, formatJSON)
+}
+
+func loadTable(path, format, keyColumn string) (table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch format {
+	case formatCSV:
+		return readCSV(f, keyColumn)
+	case formatJSON:
+		return readJSON(f)
+	}
+	return nil, fmt.Errorf("invalid format %q", format)
+}
+
+// readCSV reads a CSV file whose first row names the columns.  The key of
+// each row is taken from the key column, the first one by default, and the
+// other non-empty columns are the tags.
+func readCSV(r io.Reader, keyColumn string) (table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keyIndex := 0
+	if keyColumn != "" {
+		keyIndex = -1
+		for i, column := range header {
+			if column == keyColumn {
+				keyIndex = i
+				break
+			}
+		}
+		if keyIndex < 0 {
+			return nil, fmt.Errorf("key column %q not found in header", keyColumn)
+		}
+	}
+
+	t := make(table)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return t, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tags := make(map[string]string, len(record)-1)
+		for i, value := range record {
+			if i == keyIndex || value == "" {
+				continue
+			}
+			tags[header[i]] = value
+		}
+		t[record[keyIndex]] = tags
+	}
+}
+
+// readJSON reads a JSON object mapping each key to an object of tags.
+// Numbers and booleans are added as their JSON text.
+func readJSON(r io.Reader) (table, error) {
+	var rows map[string]map[string]interface{}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	t := make(table, len(rows))
+	for key, row := range rows {
+		tags := make(map[string]string, len(row))
+		for name, value := range row {
+			switch v := value.(type) {
+			case string:
+				tags[name] = v
+			case json.Number:
+				tags[name] = v.String()
+			case bool:
+				tags[name] = fmt.Sprintf("%t", v)
+			case nil:
+			default:
+				return nil, fmt.Errorf("invalid value for column %q of key %q", name, key)
+			}
+		}
+		t[key] = tags
+	}
+	return t, nil
+}
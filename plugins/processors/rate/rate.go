@@ -0,0 +1,308 @@
+package rate
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Counter fields to compute the rate or delta of.  Globs accepted.
+  fields = ["*"]
+
+  ## Computation applied to the counters:
+  ##   "rate"  - per-second rate, as a float
+  ##   "delta" - difference with the previous value, of the counter's type
+  # mode = "rate"
+
+  ## Suffix appended to the counter name to name the computed field.  By
+  ## default "_rate" or "_delta", depending on the mode.
+  # suffix = "_rate"
+
+  ## Keep the original counter fields.  When false, the counter fields are
+  ## removed, and metrics left without fields are dropped.
+  # keep_original = true
+
+  ## Size of the integer counters, to tell a wraparound from a reset when a
+  ## counter decreases:
+  ##   "none" - a decrease is always a reset
+  ##   "32"   - 32-bit counters, such as SNMP Counter32
+  ##   "64"   - 64-bit counters, such as SNMP Counter64
+  ##   "auto" - 32-bit if the previous value fits, 64-bit otherwise
+  ## A decrease is taken as a wraparound when the wrapped difference is less
+  ## than half the counter range.  No value is computed on a reset.
+  # wraparound = "auto"
+
+  ## Maximum time between two values of a counter.  A series not updated for
+  ## longer is forgotten, and no value is computed for its next metric.
+  # max_staleness = "10m"
+`
+
+const (
+	modeRate  = "rate"
+	modeDelta = "delta"
+)
+
+const (
+	wrapNone = "none"
+	wrap32   = "32"
+	wrap64   = "64"
+	wrapAuto = "auto"
+)
+
+type Rate struct {
+	Fields       []string        `toml:"fields"`
+	Mode         string          `toml:"mode"`
+	Suffix       string          `toml:"suffix"`
+	KeepOriginal bool            `toml:"keep_original"`
+	Wraparound   string          `toml:"wraparound"`
+	MaxStaleness config.Duration `toml:"max_staleness"`
+	Log          telegraf.Logger `toml:"-"`
+
+	fieldFilter filter.Filter
+	cache       map[uint64]*series
+	lastCleanup time.Time
+	now         func() time.Time
+}
+
+// series holds the last values of the counters of a series.
+type series struct {
+	counters map[string]counter
+	seen     time.Time
+}
+
+// counter is the last value of a counter.  Integer counters are kept as
+// unsigned integers, so that their difference is exact.
+type counter struct {
+	integer  bool
+	unsigned bool
+	u        uint64
+	f        float64
+	time     time.Time
+}
+
+func (r *Rate) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Rate) Description() string {
+	return "Compute the rate or delta of counter fields"
+}
+
+func (r *Rate) Init() error {
+	switch r.Mode {
+	case "":
+		r.Mode = modeRate
+	case modeRate, modeDelta:
+	default:
+		return fmt.Errorf("invalid mode %q", r.Mode)
+	}
+	if r.Suffix == "" {
+		r.Suffix = "_" + r.Mode
+	}
+
+	switch r.Wraparound {
+	case "":
+		r.Wraparound = wrapAuto
+	case wrapNone, wrap32, wrap64, wrapAuto:
+	default:
+		return fmt.Errorf("invalid wraparound %q", r.Wraparound)
+	}
+
+	if r.MaxStaleness <= 0 {
+		return fmt.Errorf("max_staleness must be positive")
+	}
+
+	var err error
+	r.fieldFilter, err = filter.Compile(r.Fields)
+	if err != nil {
+		return fmt.Errorf("compiling fields filter: %w", err)
+	}
+	if r.fieldFilter == nil {
+		return fmt.Errorf("fields must be set")
+	}
+
+	r.cache = make(map[uint64]*series)
+	if r.now == nil {
+		r.now = time.Now
+	}
+	r.lastCleanup = r.now()
+	return nil
+}
+
+func (r *Rate) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	now := r.now()
+	r.cleanup(now)
+
+	out := in[:0]
+	for _, metric := range in {
+		r.apply(metric, now)
+		if len(metric.FieldList()) == 0 {
+			metric.Drop()
+			continue
+		}
+		out = append(out, metric)
+	}
+	return out
+}
+
+func (r *Rate) apply(metric telegraf.Metric, now time.Time) {
+	id := metric.HashID()
+	s, ok := r.cache[id]
+	if !ok {
+		s = &series{counters: make(map[string]counter)}
+		r.cache[id] = s
+	}
+	s.seen = now
+
+	// Collect the results first, as the fields cannot be modified while
+	// iterating over them.
+	var counters []string
+	var results []interface{}
+	for _, field := range metric.FieldList() {
+		if !r.fieldFilter.Match(field.Key) {
+			continue
+		}
+		cur, ok := newCounter(field.Value, metric.Time())
+		if !ok {
+			continue
+		}
+		counters = append(counters, field.Key)
+		prev, ok := s.counters[field.Key]
+		if ok && !cur.time.After(prev.time) {
+			// Keep the newer value of out of order metrics
+			results = append(results, nil)
+			continue
+		}
+		s.counters[field.Key] = cur
+		if !ok || cur.time.Sub(prev.time) > time.Duration(r.MaxStaleness) {
+			results = append(results, nil)
+			continue
+		}
+		results = append(results, r.compute(field.Key, prev, cur))
+	}
+
+	for i, key := range counters {
+		if results[i] != nil {
+			metric.AddField(key+r.Suffix, results[i])
+		}
+		if !r.KeepOriginal {
+			metric.RemoveField(key)
+		}
+	}
+}
+
+// compute returns the rate or delta between two values of a counter, or nil
+// if the counter was reset.
+func (r *Rate) compute(key string, prev, cur counter) interface{} {
+	seconds := cur.time.Sub(prev.time).Seconds()
+
+	if !prev.integer || !cur.integer {
+		delta := cur.float() - prev.float()
+		if delta < 0 {
+			r.Log.Debugf("Counter %q reset", key)
+			return nil
+		}
+		if r.Mode == modeRate {
+			return delta / seconds
+		}
+		return delta
+	}
+
+	delta, ok := r.integerDelta(prev.u, cur.u)
+	if !ok {
+		r.Log.Debugf("Counter %q reset", key)
+		return nil
+	}
+	if r.Mode == modeRate {
+		return float64(delta) / seconds
+	}
+	if cur.unsigned {
+		return delta
+	}
+	if delta > math.MaxInt64 {
+		return nil
+	}
+	return int64(delta)
+}
+
+// integerDelta returns the increase of an integer counter, detecting the
+// wraparounds.  It returns false if the counter was reset.
+func (r *Rate) integerDelta(prev, cur uint64) (uint64, bool) {
+	if cur >= prev {
+		return cur - prev, true
+	}
+
+	bits := r.Wraparound
+	if bits == wrapAuto {
+		bits = wrap64
+		if prev <= math.MaxUint32 {
+			bits = wrap32
+		}
+	}
+
+	switch bits {
+	case wrap32:
+		if prev > math.MaxUint32 {
+			return 0, false
+		}
+		delta := uint64(math.MaxUint32) - prev + cur + 1
+		return delta, delta < 1<<31
+	case wrap64:
+		delta := cur - prev
+		return delta, delta < 1<<63
+	}
+	return 0, false
+}
+
+// cleanup forgets the series not updated within max_staleness.  It only
+// runs once per max_staleness to save CPU.
+func (r *Rate) cleanup(now time.Time) {
+	staleness := time.Duration(r.MaxStaleness)
+	if now.Sub(r.lastCleanup) < staleness {
+		return
+	}
+	r.lastCleanup = now
+
+	for id, s := range r.cache {
+		if now.Sub(s.seen) > staleness {
+			delete(r.cache, id)
+		}
+	}
+}
+
+func newCounter(value interface{}, t time.Time) (counter, bool) {
+	switch v := value.(type) {
+	case int64:
+		if v < 0 {
+			return counter{f: float64(v), time: t}, true
+		}
+		return counter{integer: true, u: uint64(v), time: t}, true
+	case uint64:
+		return counter{integer: true, unsigned: true, u: v, time: t}, true
+	case float64:
+		return counter{f: v, time: t}, true
+	}
+	return counter{}, false
+}
+
+func (c counter) float() float64 {
+	if c.integer {
+		return float64(c.u)
+	}
+	return c.f
+}
+
+func init() {
+	processors.Add("rate", func() telegraf.Processor {
+		return &Rate{
+			KeepOriginal: true,
+			MaxStaleness: config.Duration(10 * time.Minute),
+		}
+	})
+}
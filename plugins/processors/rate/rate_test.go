@@ -0,0 +1,246 @@
+package rate
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Unix(1600000000, 0)
+
+func newMetric(tags map[string]string, fields map[string]interface{}, seconds int) telegraf.Metric {
+	return testutil.MustMetric("net", tags, fields, start.Add(time.Duration(seconds)*time.Second))
+}
+
+func newRate(t *testing.T, r *Rate) *Rate {
+	r.Log = testutil.Logger{}
+	if r.Fields == nil {
+		r.Fields = []string{"*"}
+	}
+	if r.MaxStaleness == 0 {
+		r.MaxStaleness = config.Duration(10 * time.Minute)
+	}
+	r.now = func() time.Time { return start }
+	require.NoError(t, r.Init())
+	return r
+}
+
+func TestRate(t *testing.T) {
+	plugin := newRate(t, &Rate{KeepOriginal: true})
+
+	actual := plugin.Apply(
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": int64(1000), "up": true}, 0),
+		newMetric(map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": int64(50)}, 0),
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": int64(3000), "up": true}, 10),
+		newMetric(map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": int64(50)}, 10),
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": float64(3500), "up": true}, 20),
+	)
+	expected := []telegraf.Metric{
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": int64(1000), "up": true}, 0),
+		newMetric(map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": int64(50)}, 0),
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": int64(3000), "bytes_recv_rate": float64(200), "up": true}, 10),
+		newMetric(map[string]string{"interface": "eth1"}, map[string]interface{}{"bytes_recv": int64(50), "bytes_recv_rate": float64(0)}, 10),
+		newMetric(map[string]string{"interface": "eth0"}, map[string]interface{}{"bytes_recv": float64(3500), "bytes_recv_rate": float64(50), "up": true}, 20),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDelta(t *testing.T) {
+	plugin := newRate(t, &Rate{Mode: "delta", Fields: []string{"*_count"}})
+
+	actual := plugin.Apply(
+		newMetric(nil, map[string]interface{}{"in_count": int64(10), "out_count": uint64(10), "load": 1.5}, 0),
+		newMetric(nil, map[string]interface{}{"in_count": int64(15), "out_count": uint64(20), "load": 0.5}, 10),
+	)
+	expected := []telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"load": 1.5}, 0),
+		newMetric(nil, map[string]interface{}{"in_count_delta": int64(5), "out_count_delta": uint64(10), "load": 0.5}, 10),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestDropMetricsWithoutFields(t *testing.T) {
+	plugin := newRate(t, &Rate{Mode: "delta", Suffix: "_diff"})
+
+	actual := plugin.Apply(
+		newMetric(nil, map[string]interface{}{"packets": int64(10)}, 0),
+		newMetric(nil, map[string]interface{}{"packets": int64(15)}, 10),
+	)
+	expected := []telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"packets_diff": int64(5)}, 10),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestWraparound(t *testing.T) {
+	tests := []struct {
+		name       string
+		wraparound string
+		prev       uint64
+		cur        uint64
+		expected   interface{}
+	}{
+		{
+			name:       "32-bit wraparound",
+			wraparound: "32",
+			prev:       math.MaxUint32 - 9,
+			cur:        10,
+			expected:   uint64(20),
+		},
+		{
+			name:       "32-bit reset",
+			wraparound: "32",
+			prev:       1000,
+			cur:        10,
+		},
+		{
+			name:       "32-bit reset of a larger value",
+			wraparound: "32",
+			prev:       math.MaxUint32 + 1000,
+			cur:        10,
+		},
+		{
+			name:       "64-bit wraparound",
+			wraparound: "64",
+			prev:       math.MaxUint64 - 9,
+			cur:        10,
+			expected:   uint64(20),
+		},
+		{
+			name:       "64-bit reset",
+			wraparound: "64",
+			prev:       1000,
+			cur:        10,
+		},
+		{
+			name:       "auto 32-bit wraparound",
+			wraparound: "auto",
+			prev:       math.MaxUint32 - 9,
+			cur:        10,
+			expected:   uint64(20),
+		},
+		{
+			name:       "auto 64-bit wraparound",
+			wraparound: "auto",
+			prev:       math.MaxUint64 - 9,
+			cur:        10,
+			expected:   uint64(20),
+		},
+		{
+			name:       "no wraparound",
+			wraparound: "none",
+			prev:       math.MaxUint32 - 9,
+			cur:        10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := newRate(t, &Rate{Mode: "delta", Wraparound: tt.wraparound, KeepOriginal: true})
+
+			actual := plugin.Apply(
+				newMetric(nil, map[string]interface{}{"octets": tt.prev}, 0),
+				newMetric(nil, map[string]interface{}{"octets": tt.cur}, 10),
+			)
+			require.Len(t, actual, 2)
+			value, ok := actual[1].GetField("octets_delta")
+			if tt.expected == nil {
+				require.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, tt.expected, value)
+		})
+	}
+}
+
+func TestReset(t *testing.T) {
+	plugin := newRate(t, &Rate{KeepOriginal: true})
+
+	actual := plugin.Apply(
+		newMetric(nil, map[string]interface{}{"requests": 1000.0}, 0),
+		newMetric(nil, map[string]interface{}{"requests": 10.0}, 10),
+		newMetric(nil, map[string]interface{}{"requests": 30.0}, 20),
+	)
+	expected := []telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"requests": 1000.0}, 0),
+		newMetric(nil, map[string]interface{}{"requests": 10.0}, 10),
+		newMetric(nil, map[string]interface{}{"requests": 30.0, "requests_rate": 2.0}, 20),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestOutOfOrder(t *testing.T) {
+	plugin := newRate(t, &Rate{KeepOriginal: true})
+
+	actual := plugin.Apply(
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 10),
+		newMetric(nil, map[string]interface{}{"requests": int64(50)}, 0),
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 10),
+		newMetric(nil, map[string]interface{}{"requests": int64(300)}, 20),
+	)
+	expected := []telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 10),
+		newMetric(nil, map[string]interface{}{"requests": int64(50)}, 0),
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 10),
+		newMetric(nil, map[string]interface{}{"requests": int64(300), "requests_rate": 20.0}, 20),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestMaxStaleness(t *testing.T) {
+	plugin := newRate(t, &Rate{KeepOriginal: true, MaxStaleness: config.Duration(time.Minute)})
+
+	// Values too far apart are not compared
+	actual := plugin.Apply(
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 0),
+		newMetric(nil, map[string]interface{}{"requests": int64(200)}, 120),
+		newMetric(nil, map[string]interface{}{"requests": int64(300)}, 130),
+	)
+	expected := []telegraf.Metric{
+		newMetric(nil, map[string]interface{}{"requests": int64(100)}, 0),
+		newMetric(nil, map[string]interface{}{"requests": int64(200)}, 120),
+		newMetric(nil, map[string]interface{}{"requests": int64(300), "requests_rate": 10.0}, 130),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+	require.Len(t, plugin.cache, 1)
+
+	// Series not updated are evicted
+	plugin.now = func() time.Time { return start.Add(2 * time.Minute) }
+	actual = plugin.Apply(newMetric(map[string]string{"host": "b"}, map[string]interface{}{"requests": int64(100)}, 0))
+	require.Len(t, actual, 1)
+	require.Len(t, plugin.cache, 1)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		plugin *Rate
+	}{
+		{
+			name:   "no fields",
+			plugin: &Rate{MaxStaleness: config.Duration(time.Minute)},
+		},
+		{
+			name:   "invalid mode",
+			plugin: &Rate{Fields: []string{"*"}, Mode: "ratio", MaxStaleness: config.Duration(time.Minute)},
+		},
+		{
+			name:   "invalid wraparound",
+			plugin: &Rate{Fields: []string{"*"}, Wraparound: "16", MaxStaleness: config.Duration(time.Minute)},
+		},
+		{
+			name:   "no staleness",
+			plugin: &Rate{Fields: []string{"*"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Error(t, tt.plugin.Init())
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package scale
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Each scaling applies to the numeric fields it selects, the first
+  ## matching scaling being applied to a field.  The results are floats.
+  [[processors.scale.scaling]]
+    ## Fields to scale.  Globs accepted.
+    fields = ["temp_*"]
+
+    ## Suffix appended to the field name to name the result.  By default the
+    ## field is overwritten.
+    # suffix = "_fahrenheit"
+
+    ## Scaling applied, one of:
+    ##
+    ## Conversion between named units, e.g. "byte", "KiB", "MB", "ms", "s",
+    ## "celsius", "fahrenheit", "kelvin", "Hz", "percent" or "ratio".
+    from_unit = "celsius"
+    to_unit = "fahrenheit"
+    ##
+    ## Linear mapping of an input range to an output range.
+    # input_minimum = 0.0
+    # input_maximum = 1.0
+    # output_minimum = 0.0
+    # output_maximum = 100.0
+    ##
+    ## Multiplication by a factor, followed by the addition of an offset.
+    # factor = 1.0
+    # offset = 0.0
+`
+
+type Scaling struct {
+	Fields        []string `toml:"fields"`
+	Suffix        string   `toml:"suffix"`
+	FromUnit      string   `toml:"from_unit"`
+	ToUnit        string   `toml:"to_unit"`
+	InputMinimum  *float64 `toml:"input_minimum"`
+	InputMaximum  *float64 `toml:"input_maximum"`
+	OutputMinimum *float64 `toml:"output_minimum"`
+	OutputMaximum *float64 `toml:"output_maximum"`
+	Factor        *float64 `toml:"factor"`
+	Offset        *float64 `toml:"offset"`
+
+	fieldFilter filter.Filter
+	convert     func(float64) float64
+}
+
+type Scale struct {
+	Scalings []Scaling       `toml:"scaling"`
+	Log      telegraf.Logger `toml:"-"`
+}
+
+func (s *Scale) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Scale) Description() string {
+	return "Scale numeric fields linearly, or convert them between units"
+}
+
+func (s *Scale) Init() error {
+	if len(s.Scalings) == 0 {
+		return fmt.Errorf("no scaling defined")
+	}
+	for i := range s.Scalings {
+		if err := s.Scalings[i].init(); err != nil {
+			return fmt.Errorf("scaling %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (s *Scale) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, metric := range in {
+		// Collect the results first, as the fields cannot be modified while
+		// iterating over them.
+		var keys []string
+		var values []float64
+		for _, field := range metric.FieldList() {
+			scaling := s.scaling(field.Key)
+			if scaling == nil {
+				continue
+			}
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+			keys = append(keys, field.Key+scaling.Suffix)
+			values = append(values, scaling.convert(value))
+		}
+
+		for i, key := range keys {
+			metric.AddField(key, values[i])
+		}
+	}
+	return in
+}
+
+// scaling returns the first scaling matching a field.
+func (s *Scale) scaling(key string) *Scaling {
+	for i := range s.Scalings {
+		if s.Scalings[i].fieldFilter.Match(key) {
+			return &s.Scalings[i]
+		}
+	}
+	return nil
+}
+
+// init checks that a single kind of scaling is configured, and creates its
+// conversion.
+func (s *Scaling) init() error {
+	var err error
+	s.fieldFilter, err = filter.Compile(s.Fields)
+	if err != nil {
+		return fmt.Errorf("compiling fields filter: %w", err)
+	}
+	if s.fieldFilter == nil {
+		return fmt.Errorf("fields must be set")
+	}
+
+	named := s.FromUnit != "" || s.ToUnit != ""
+	ranges := s.InputMinimum != nil || s.InputMaximum != nil || s.OutputMinimum != nil || s.OutputMaximum != nil
+	linear := s.Factor != nil || s.Offset != nil
+
+	switch {
+	case named && !ranges && !linear:
+		if s.FromUnit == "" || s.ToUnit == "" {
+			return fmt.Errorf("both from_unit and to_unit must be set")
+		}
+		s.convert, err = unitConversion(s.FromUnit, s.ToUnit)
+		return err
+	case ranges && !named && !linear:
+		if s.InputMinimum == nil || s.InputMaximum == nil || s.OutputMinimum == nil || s.OutputMaximum == nil {
+			return fmt.Errorf("input and output minimum and maximum must all be set")
+		}
+		if *s.InputMinimum == *s.InputMaximum {
+			return fmt.Errorf("input minimum and maximum must differ")
+		}
+		factor := (*s.OutputMaximum - *s.OutputMinimum) / (*s.InputMaximum - *s.InputMinimum)
+		s.convert = linearConversion(factor, *s.OutputMinimum-*s.InputMinimum*factor)
+		return nil
+	case linear && !named && !ranges:
+		factor, offset := 1.0, 0.0
+		if s.Factor != nil {
+			factor = *s.Factor
+		}
+		if s.Offset != nil {
+			offset = *s.Offset
+		}
+		s.convert = linearConversion(factor, offset)
+		return nil
+	}
+	return fmt.Errorf("either units, ranges or factor and offset must be set")
+}
+
+func linearConversion(factor, offset float64) func(float64) float64 {
+	return func(value float64) float64 {
+		return value*factor + offset
+	}
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func init() {
+	processors.Add("scale", func() telegraf.Processor {
+		return &Scale{}
+	})
+}
@@ -0,0 +1,155 @@
+package scale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric("sensors", map[string]string{}, fields, time.Unix(0, 0))
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestUnitConversion(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		value    interface{}
+		expected float64
+	}{
+		{from: "celsius", to: "fahrenheit", value: 100.0, expected: 212},
+		{from: "F", to: "C", value: int64(32), expected: 0},
+		{from: "celsius", to: "kelvin", value: -273.15, expected: 0},
+		{from: "fahrenheit", to: "kelvin", value: 212.0, expected: 373.15},
+		{from: "byte", to: "KiB", value: uint64(2048), expected: 2},
+		{from: "MiB", to: "MB", value: int64(1), expected: 1.048576},
+		{from: "Mbit", to: "byte", value: 1.0, expected: 125000},
+		{from: "ms", to: "s", value: int64(1500), expected: 1.5},
+		{from: "hour", to: "minute", value: 1.5, expected: 90},
+		{from: "GHz", to: "MHz", value: 2.4, expected: 2400},
+		{from: "percent", to: "ratio", value: 42.0, expected: 0.42},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+" to "+tt.to, func(t *testing.T) {
+			plugin := &Scale{
+				Scalings: []Scaling{{Fields: []string{"value"}, FromUnit: tt.from, ToUnit: tt.to}},
+			}
+			require.NoError(t, plugin.Init())
+
+			actual := plugin.Apply(newMetric(map[string]interface{}{"value": tt.value}))
+			require.Len(t, actual, 1)
+			value, ok := actual[0].GetField("value")
+			require.True(t, ok)
+			require.InDelta(t, tt.expected, value, 1e-9)
+		})
+	}
+}
+
+func TestExactUnitConversion(t *testing.T) {
+	convert, err := unitConversion("celsius", "fahrenheit")
+	require.NoError(t, err)
+	require.Equal(t, 104.0, convert(40))
+
+	convert, err = unitConversion("ms", "s")
+	require.NoError(t, err)
+	require.Equal(t, 0.3, convert(300))
+}
+
+func TestScale(t *testing.T) {
+	plugin := &Scale{
+		Scalings: []Scaling{
+			{
+				Fields:        []string{"level_*"},
+				InputMinimum:  float(0),
+				InputMaximum:  float(4095),
+				OutputMinimum: float(0),
+				OutputMaximum: float(100),
+				Suffix:        "_percent",
+			},
+			{
+				Fields: []string{"temp_*", "level_*"},
+				Factor: float(0.1),
+				Offset: float(-5),
+			},
+		},
+	}
+	require.NoError(t, plugin.Init())
+
+	actual := plugin.Apply(newMetric(map[string]interface{}{
+		"level_tank": int64(4095),
+		"temp_cpu":   uint64(500),
+		"status":     "ok",
+		"other":      int64(3),
+	}))
+	expected := []telegraf.Metric{
+		newMetric(map[string]interface{}{
+			"level_tank":         int64(4095),
+			"level_tank_percent": 100.0,
+			"temp_cpu":           45.0,
+			"status":             "ok",
+			"other":              int64(3),
+		}),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		scaling Scaling
+	}{
+		{
+			name:    "no fields",
+			scaling: Scaling{FromUnit: "s", ToUnit: "ms"},
+		},
+		{
+			name:    "no scaling",
+			scaling: Scaling{Fields: []string{"*"}},
+		},
+		{
+			name:    "missing unit",
+			scaling: Scaling{Fields: []string{"*"}, FromUnit: "s"},
+		},
+		{
+			name:    "unknown unit",
+			scaling: Scaling{Fields: []string{"*"}, FromUnit: "s", ToUnit: "fortnight"},
+		},
+		{
+			name:    "different dimensions",
+			scaling: Scaling{Fields: []string{"*"}, FromUnit: "s", ToUnit: "byte"},
+		},
+		{
+			name:    "missing range",
+			scaling: Scaling{Fields: []string{"*"}, InputMinimum: float(0), InputMaximum: float(1)},
+		},
+		{
+			name: "empty range",
+			scaling: Scaling{
+				Fields:        []string{"*"},
+				InputMinimum:  float(1),
+				InputMaximum:  float(1),
+				OutputMinimum: float(0),
+				OutputMaximum: float(100),
+			},
+		},
+		{
+			name:    "several scalings",
+			scaling: Scaling{Fields: []string{"*"}, FromUnit: "s", ToUnit: "ms", Factor: float(2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Scale{Scalings: []Scaling{tt.scaling}}
+			require.Error(t, plugin.Init())
+		})
+	}
+
+	require.Error(t, (&Scale{}).Init())
+}
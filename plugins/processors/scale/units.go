@@ -0,0 +1,107 @@
+package scale
+
+import (
+	"fmt"
+)
+
+// unit converts a value to the base unit of its dimension, as
+// (value + offset) * mul / div.  The multiplier and divisor are kept apart to
+// avoid rounding errors on exact ratios such as 5/9 or 1/1000.
+type unit struct {
+	dimension string
+	offset    float64
+	mul       float64
+	div       float64
+}
+
+const (
+	dimensionData        = "data"
+	dimensionTime        = "time"
+	dimensionTemperature = "temperature"
+	dimensionFrequency   = "frequency"
+	dimensionRatio       = "ratio"
+)
+
+// units maps the names and symbols of the known units.  The base units are
+// the byte, the second, the degree Celsius, the hertz and the ratio.
+var units = map[string]unit{
+	"bit":         {dimension: dimensionData, mul: 1, div: 8},
+	"kilobit":     {dimension: dimensionData, mul: 1e3, div: 8},
+	"kbit":        {dimension: dimensionData, mul: 1e3, div: 8},
+	"megabit":     {dimension: dimensionData, mul: 1e6, div: 8},
+	"Mbit":        {dimension: dimensionData, mul: 1e6, div: 8},
+	"gigabit":     {dimension: dimensionData, mul: 1e9, div: 8},
+	"Gbit":        {dimension: dimensionData, mul: 1e9, div: 8},
+	"byte":        {dimension: dimensionData, mul: 1, div: 1},
+	"B":           {dimension: dimensionData, mul: 1, div: 1},
+	"kilobyte":    {dimension: dimensionData, mul: 1e3, div: 1},
+	"kB":          {dimension: dimensionData, mul: 1e3, div: 1},
+	"KB":          {dimension: dimensionData, mul: 1e3, div: 1},
+	"megabyte":    {dimension: dimensionData, mul: 1e6, div: 1},
+	"MB":          {dimension: dimensionData, mul: 1e6, div: 1},
+	"gigabyte":    {dimension: dimensionData, mul: 1e9, div: 1},
+	"GB":          {dimension: dimensionData, mul: 1e9, div: 1},
+	"terabyte":    {dimension: dimensionData, mul: 1e12, div: 1},
+	"TB":          {dimension: dimensionData, mul: 1e12, div: 1},
+	"kibibyte":    {dimension: dimensionData, mul: 1 << 10, div: 1},
+	"KiB":         {dimension: dimensionData, mul: 1 << 10, div: 1},
+	"mebibyte":    {dimension: dimensionData, mul: 1 << 20, div: 1},
+	"MiB":         {dimension: dimensionData, mul: 1 << 20, div: 1},
+	"gibibyte":    {dimension: dimensionData, mul: 1 << 30, div: 1},
+	"GiB":         {dimension: dimensionData, mul: 1 << 30, div: 1},
+	"tebibyte":    {dimension: dimensionData, mul: 1 << 40, div: 1},
+	"TiB":         {dimension: dimensionData, mul: 1 << 40, div: 1},
+	"nanosecond":  {dimension: dimensionTime, mul: 1, div: 1e9},
+	"ns":          {dimension: dimensionTime, mul: 1, div: 1e9},
+	"microsecond": {dimension: dimensionTime, mul: 1, div: 1e6},
+	"us":          {dimension: dimensionTime, mul: 1, div: 1e6},
+	"millisecond": {dimension: dimensionTime, mul: 1, div: 1e3},
+	"ms":          {dimension: dimensionTime, mul: 1, div: 1e3},
+	"second":      {dimension: dimensionTime, mul: 1, div: 1},
+	"s":           {dimension: dimensionTime, mul: 1, div: 1},
+	"minute":      {dimension: dimensionTime, mul: 60, div: 1},
+	"min":         {dimension: dimensionTime, mul: 60, div: 1},
+	"hour":        {dimension: dimensionTime, mul: 3600, div: 1},
+	"h":           {dimension: dimensionTime, mul: 3600, div: 1},
+	"day":         {dimension: dimensionTime, mul: 86400, div: 1},
+	"d":           {dimension: dimensionTime, mul: 86400, div: 1},
+	"kelvin":      {dimension: dimensionTemperature, offset: -273.15, mul: 1, div: 1},
+	"K":           {dimension: dimensionTemperature, offset: -273.15, mul: 1, div: 1},
+	"celsius":     {dimension: dimensionTemperature, mul: 1, div: 1},
+	"C":           {dimension: dimensionTemperature, mul: 1, div: 1},
+	"fahrenheit":  {dimension: dimensionTemperature, offset: -32, mul: 5, div: 9},
+	"F":           {dimension: dimensionTemperature, offset: -32, mul: 5, div: 9},
+	"hertz":       {dimension: dimensionFrequency, mul: 1, div: 1},
+	"Hz":          {dimension: dimensionFrequency, mul: 1, div: 1},
+	"kilohertz":   {dimension: dimensionFrequency, mul: 1e3, div: 1},
+	"kHz":         {dimension: dimensionFrequency, mul: 1e3, div: 1},
+	"megahertz":   {dimension: dimensionFrequency, mul: 1e6, div: 1},
+	"MHz":         {dimension: dimensionFrequency, mul: 1e6, div: 1},
+	"gigahertz":   {dimension: dimensionFrequency, mul: 1e9, div: 1},
+	"GHz":         {dimension: dimensionFrequency, mul: 1e9, div: 1},
+	"ratio":       {dimension: dimensionRatio, mul: 1, div: 1},
+	"percent":     {dimension: dimensionRatio, mul: 1, div: 1e2},
+	"%":           {dimension: dimensionRatio, mul: 1, div: 1e2},
+}
+
+// unitConversion returns the function converting a value between two units
+// of the same dimension.
+func unitConversion(from, to string) (func(float64) float64, error) {
+	fromUnit, ok := units[from]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", from)
+	}
+	toUnit, ok := units[to]
+	if !ok {
+		return nil, fmt.Errorf("unknown unit %q", to)
+	}
+	if fromUnit.dimension != toUnit.dimension {
+		return nil, fmt.Errorf("cannot convert %s unit %q to %s unit %q",
+			fromUnit.dimension, from, toUnit.dimension, to)
+	}
+
+	return func(value float64) float64 {
+		base := (value + fromUnit.offset) * fromUnit.mul / fromUnit.div
+		return base*toUnit.div/toUnit.mul - toUnit.offset
+	}, nil
+}
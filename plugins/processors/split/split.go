@@ -0,0 +1,140 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Drop the original metric, once split.  Metrics not matching any
+  ## template are always kept.
+  # drop_original = false
+
+  ## Each template creates a new metric, with the fields of the original
+  ## metric it selects.  No metric is created when no field is selected.
+  [[processors.split.template]]
+    ## Name of the new metric.
+    name = "mysql_innodb"
+
+    ## Tags of the original metric to copy to the new metric.  Globs
+    ## accepted.  By default all the tags are copied.
+    # tags = ["server"]
+
+    ## Fields of the original metric to copy to the new metric.  Globs
+    ## accepted.
+    fields = ["innodb_*"]
+`
+
+type Template struct {
+	Name   string   `toml:"name"`
+	Tags   []string `toml:"tags"`
+	Fields []string `toml:"fields"`
+
+	tagFilter   filter.Filter
+	fieldFilter filter.Filter
+}
+
+type Split struct {
+	DropOriginal bool            `toml:"drop_original"`
+	Templates    []Template      `toml:"template"`
+	Log          telegraf.Logger `toml:"-"`
+}
+
+func (s *Split) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Split) Description() string {
+	return "Split metrics into several metrics by groups of fields"
+}
+
+func (s *Split) Init() error {
+	if len(s.Templates) == 0 {
+		return fmt.Errorf("no template defined")
+	}
+
+	for i := range s.Templates {
+		t := &s.Templates[i]
+		if t.Name == "" {
+			return fmt.Errorf("template %d: name must be set", i+1)
+		}
+
+		var err error
+		t.tagFilter, err = filter.Compile(t.Tags)
+		if err != nil {
+			return fmt.Errorf("template %q: compiling tags filter: %w", t.Name, err)
+		}
+		t.fieldFilter, err = filter.Compile(t.Fields)
+		if err != nil {
+			return fmt.Errorf("template %q: compiling fields filter: %w", t.Name, err)
+		}
+		if t.fieldFilter == nil {
+			return fmt.Errorf("template %q: fields must be set", t.Name)
+		}
+	}
+	return nil
+}
+
+// Apply splits the metrics, keeping their order: each metric is followed by
+// the metrics split from it, in the order of the templates.
+func (s *Split) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	results := make([]telegraf.Metric, 0, len(in)*(len(s.Templates)+1))
+	for _, metric := range in {
+		var splits []telegraf.Metric
+		for i := range s.Templates {
+			if m := s.Templates[i].apply(metric); m != nil {
+				splits = append(splits, m)
+			}
+		}
+
+		if len(splits) == 0 || !s.DropOriginal {
+			results = append(results, metric)
+		} else {
+			metric.Accept()
+		}
+		results = append(results, splits...)
+	}
+	return results
+}
+
+// apply returns the metric created by the template, or nil if no field of
+// the metric is selected.
+func (t *Template) apply(metric telegraf.Metric) telegraf.Metric {
+	var removedFields []string
+	for _, field := range metric.FieldList() {
+		if !t.fieldFilter.Match(field.Key) {
+			removedFields = append(removedFields, field.Key)
+		}
+	}
+	if len(removedFields) == len(metric.FieldList()) {
+		return nil
+	}
+
+	var removedTags []string
+	if t.tagFilter != nil {
+		for _, tag := range metric.TagList() {
+			if !t.tagFilter.Match(tag.Key) {
+				removedTags = append(removedTags, tag.Key)
+			}
+		}
+	}
+
+	m := metric.Copy()
+	m.SetName(t.Name)
+	for _, key := range removedFields {
+		m.RemoveField(key)
+	}
+	for _, key := range removedTags {
+		m.RemoveTag(key)
+	}
+	return m
+}
+
+func init() {
+	processors.Add("split", func() telegraf.Processor {
+		return &Split{}
+	})
+}
@@ -0,0 +1,146 @@
+package split
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetric(name string, tags map[string]string, fields map[string]interface{}) telegraf.Metric {
+	return testutil.MustMetric(name, tags, fields, time.Unix(1600000000, 0))
+}
+
+func newSplit(t *testing.T, dropOriginal bool) *Split {
+	plugin := &Split{
+		DropOriginal: dropOriginal,
+		Templates: []Template{
+			{
+				Name:   "mysql_innodb",
+				Tags:   []string{"server"},
+				Fields: []string{"innodb_*"},
+			},
+			{
+				Name:   "mysql_queries",
+				Fields: []string{"queries", "slow_queries"},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, plugin.Init())
+	return plugin
+}
+
+func TestSplit(t *testing.T) {
+	plugin := newSplit(t, false)
+
+	input := newMetric("mysql",
+		map[string]string{"server": "db01", "region": "eu"},
+		map[string]interface{}{"innodb_reads": int64(10), "innodb_writes": int64(5), "queries": int64(42), "uptime": int64(3600)},
+	)
+	actual := plugin.Apply(input)
+	expected := []telegraf.Metric{
+		newMetric("mysql",
+			map[string]string{"server": "db01", "region": "eu"},
+			map[string]interface{}{"innodb_reads": int64(10), "innodb_writes": int64(5), "queries": int64(42), "uptime": int64(3600)},
+		),
+		newMetric("mysql_innodb",
+			map[string]string{"server": "db01"},
+			map[string]interface{}{"innodb_reads": int64(10), "innodb_writes": int64(5)},
+		),
+		newMetric("mysql_queries",
+			map[string]string{"server": "db01", "region": "eu"},
+			map[string]interface{}{"queries": int64(42)},
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSplitDropOriginal(t *testing.T) {
+	plugin := newSplit(t, true)
+
+	actual := plugin.Apply(
+		newMetric("mysql",
+			map[string]string{"server": "db01"},
+			map[string]interface{}{"innodb_reads": int64(10), "queries": int64(42)},
+		),
+		newMetric("mysql",
+			map[string]string{"server": "db02"},
+			map[string]interface{}{"uptime": int64(3600)},
+		),
+		newMetric("mysql",
+			map[string]string{"server": "db03"},
+			map[string]interface{}{"slow_queries": int64(1)},
+		),
+	)
+	expected := []telegraf.Metric{
+		newMetric("mysql_innodb",
+			map[string]string{"server": "db01"},
+			map[string]interface{}{"innodb_reads": int64(10)},
+		),
+		newMetric("mysql_queries",
+			map[string]string{"server": "db01"},
+			map[string]interface{}{"queries": int64(42)},
+		),
+		newMetric("mysql",
+			map[string]string{"server": "db02"},
+			map[string]interface{}{"uptime": int64(3600)},
+		),
+		newMetric("mysql_queries",
+			map[string]string{"server": "db03"},
+			map[string]interface{}{"slow_queries": int64(1)},
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, actual)
+}
+
+func TestSplitTracking(t *testing.T) {
+	plugin := newSplit(t, true)
+
+	var delivered bool
+	notify := func(di telegraf.DeliveryInfo) {
+		delivered = di.Delivered()
+	}
+	input, _ := metric.WithTracking(
+		newMetric("mysql",
+			map[string]string{"server": "db01"},
+			map[string]interface{}{"innodb_reads": int64(10), "queries": int64(42)},
+		),
+		notify,
+	)
+
+	actual := plugin.Apply(input)
+	require.Len(t, actual, 2)
+	require.False(t, delivered)
+	for _, m := range actual {
+		m.Accept()
+	}
+	require.True(t, delivered)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		template Template
+	}{
+		{
+			name:     "no name",
+			template: Template{Fields: []string{"*"}},
+		},
+		{
+			name:     "no fields",
+			template: Template{Name: "mysql_innodb"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &Split{Templates: []Template{tt.template}}
+			require.Error(t, plugin.Init())
+		})
+	}
+
+	require.Error(t, (&Split{}).Init())
+}
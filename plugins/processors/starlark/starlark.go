@@ -3,15 +3,11 @@ package starlark
 import (
 	"errors"
 	"fmt"
-	"strings"
 
 	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
 	"github.com/influxdata/telegraf/plugins/processors"
-	"go.starlark.net/lib/math"
-	"go.starlark.net/lib/time"
-	"go.starlark.net/resolve"
 	"go.starlark.net/starlark"
-	"go.starlark.net/starlarkjson"
 )
 
 const (
@@ -40,97 +36,29 @@ def apply(metric):
 )
 
 type Starlark struct {
-	Source    string                 `toml:"source"`
-	Script    string                 `toml:"script"`
-	Constants map[string]interface{} `toml:"constants"`
+	common.StarlarkCommon
 
-	Log telegraf.Logger `toml:"-"`
-
-	thread           *starlark.Thread
-	applyFunc        *starlark.Function
-	args             starlark.Tuple
-	results          []telegraf.Metric
-	starlarkLoadFunc func(module string, logger telegraf.Logger) (starlark.StringDict, error)
+	results []telegraf.Metric
 }
 
 func (s *Starlark) Init() error {
-	if s.Source == "" && s.Script == "" {
-		return errors.New("one of source or script must be set")
-	}
-	if s.Source != "" && s.Script != "" {
-		return errors.New("both source or script cannot be set")
-	}
-
-	s.thread = &starlark.Thread{
-		Print: func(_ *starlark.Thread, msg string) { s.Log.Debug(msg) },
-		Load: func(thread *starlark.Thread, module string) (starlark.StringDict, error) {
-			return s.starlarkLoadFunc(module, s.Log)
-		},
-	}
-
-	builtins := starlark.StringDict{}
-	builtins["Metric"] = starlark.NewBuiltin("Metric", newMetric)
-	builtins["deepcopy"] = starlark.NewBuiltin("deepcopy", deepcopy)
-	builtins["catch"] = starlark.NewBuiltin("catch", catch)
-	s.addConstants(&builtins)
-
-	program, err := s.sourceProgram(builtins)
+	err := s.StarlarkCommon.Init()
 	if err != nil {
 		return err
 	}
 
-	// Execute source
-	globals, err := program.Init(s.thread, builtins)
+	// The source should define an apply function.
+	err = s.AddFunction("apply", &common.Metric{})
 	if err != nil {
 		return err
 	}
 
-	// Make available a shared state to the apply function
-	globals["state"] = starlark.NewDict(0)
-
-	// Freeze the global state.  This prevents modifications to the processor
-	// state and prevents scripts from containing errors storing tracking
-	// metrics.  Tasks that require global state will not be possible due to
-	// this, so maybe we should relax this in the future.
-	globals.Freeze()
-
-	// The source should define an apply function.
-	apply := globals["apply"]
-
-	if apply == nil {
-		return errors.New("apply is not defined")
-	}
-
-	var ok bool
-	if s.applyFunc, ok = apply.(*starlark.Function); !ok {
-		return errors.New("apply is not a function")
-	}
-
-	if s.applyFunc.NumParams() != 1 {
-		return errors.New("apply function must take one parameter")
-	}
-
-	// Reusing the same metric wrapper to skip an allocation.  This will cause
-	// any saved references to point to the new metric, but due to freezing the
-	// globals none should exist.
-	s.args = make(starlark.Tuple, 1)
-	s.args[0] = &Metric{}
-
 	// Preallocate a slice for return values.
 	s.results = make([]telegraf.Metric, 0, 10)
 
 	return nil
 }
 
-func (s *Starlark) sourceProgram(builtins starlark.StringDict) (*starlark.Program, error) {
-	if s.Source != "" {
-		_, program, err := starlark.SourceProgram("processor.starlark", s.Source, builtins.Has)
-		return program, err
-	}
-	_, program, err := starlark.SourceProgram(s.Script, nil, builtins.Has)
-	return program, err
-}
-
 func (s *Starlark) SampleConfig() string {
 	return sampleConfig
 }
@@ -144,15 +72,18 @@ func (s *Starlark) Start(_ telegraf.Accumulator) error {
 }
 
 func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
-	s.args[0].(*Metric).Wrap(metric)
+	parameters, found := s.GetParameters("apply")
+	if !found {
+		return errors.New("the parameters of the apply function could not be found")
+	}
+	// Reusing the same metric wrapper to skip an allocation.  This will cause
+	// any saved references to point to the new metric, but due to freezing the
+	// globals none should exist.
+	parameters[0].(*common.Metric).Wrap(metric)
 
-	rv, err := starlark.Call(s.thread, s.applyFunc, s.args, nil)
+	rv, err := s.Call("apply")
 	if err != nil {
-		if err, ok := err.(*starlark.EvalError); ok {
-			for _, line := range strings.Split(err.Backtrace(), "\n") {
-				s.Log.Error(line)
-			}
-		}
+		s.LogError(err)
 		metric.Reject()
 		return err
 	}
@@ -164,7 +95,7 @@ func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
 		var v starlark.Value
 		for iter.Next(&v) {
 			switch v := v.(type) {
-			case *Metric:
+			case *common.Metric:
 				m := v.Unwrap()
 				if containsMetric(s.results, m) {
 					s.Log.Errorf("Duplicate metric reference detected")
@@ -188,7 +119,7 @@ func (s *Starlark) Add(metric telegraf.Metric, acc telegraf.Accumulator) error {
 			s.results[i] = nil
 		}
 		s.results = s.results[:0]
-	case *Metric:
+	case *common.Metric:
 		m := rv.Unwrap()
 
 		// If the script returned a different metric, mark this metric as
@@ -209,17 +140,6 @@ func (s *Starlark) Stop() error {
 	return nil
 }
 
-// Add all the constants defined in the plugin as constants of the script
-func (s *Starlark) addConstants(builtins *starlark.StringDict) {
-	for key, val := range s.Constants {
-		sVal, err := asStarlarkValue(val)
-		if err != nil {
-			s.Log.Errorf("Unsupported type: %T", val)
-		}
-		(*builtins)[key] = sVal
-	}
-}
-
 func containsMetric(metrics []telegraf.Metric, metric telegraf.Metric) bool {
 	for _, m := range metrics {
 		if m == metric {
@@ -229,43 +149,12 @@ func containsMetric(metrics []telegraf.Metric, metric telegraf.Metric) bool {
 	return false
 }
 
-func init() {
-	// https://github.com/bazelbuild/starlark/issues/20
-	resolve.AllowNestedDef = true
-	resolve.AllowLambda = true
-	resolve.AllowFloat = true
-	resolve.AllowSet = true
-	resolve.AllowGlobalReassign = true
-	resolve.AllowRecursion = true
-}
-
 func init() {
 	processors.AddStreaming("starlark", func() telegraf.StreamingProcessor {
 		return &Starlark{
-			starlarkLoadFunc: loadFunc,
+			StarlarkCommon: common.StarlarkCommon{
+				StarlarkLoadFunc: common.LoadFunc,
+			},
 		}
 	})
 }
-
-func loadFunc(module string, logger telegraf.Logger) (starlark.StringDict, error) {
-	switch module {
-	case "json.star":
-		return starlark.StringDict{
-			"json": starlarkjson.Module,
-		}, nil
-	case "logging.star":
-		return starlark.StringDict{
-			"log": LogModule(logger),
-		}, nil
-	case "math.star":
-		return starlark.StringDict{
-			"math": math.Module,
-		}, nil
-	case "time.star":
-		return starlark.StringDict{
-			"time": time.Module,
-		}, nil
-	default:
-		return nil, errors.New("module " + module + " is not available")
-	}
-}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	common "github.com/influxdata/telegraf/plugins/common/starlark"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -29,67 +30,81 @@ func TestInitError(t *testing.T) {
 		{
 			name: "source must define apply",
 			plugin: &Starlark{
-				Source:           "",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Source:           "",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "apply must be a function",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 apply = 42
 `,
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "apply function must take one arg",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 def apply():
 	pass
 `,
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "package scope must have valid syntax",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 for
 `,
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "no source no script",
 			plugin: &Starlark{
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "source and script",
 			plugin: &Starlark{
-				Source: `
+				StarlarkCommon: common.StarlarkCommon{
+					Source: `
 def apply():
 	pass
 `,
-				Script:           "testdata/ratio.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+					Script:           "testdata/ratio.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 		{
 			name: "script file not found",
 			plugin: &Starlark{
-				Script:           "testdata/file_not_found.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/file_not_found.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 		},
 	}
@@ -229,9 +244,11 @@ def apply(metric):
 	for _, tt := range applyTests {
 		t.Run(tt.name, func(t *testing.T) {
 			plugin := &Starlark{
-				Source:           tt.source,
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Source:           tt.source,
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			}
 			err := plugin.Init()
 			require.NoError(t, err)
@@ -2487,10 +2504,12 @@ def apply(metric):
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			plugin := &Starlark{
-				Source:           tt.source,
-				Log:              testutil.Logger{},
-				Constants:        tt.constants,
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Source:           tt.source,
+					Log:              testutil.Logger{},
+					Constants:        tt.constants,
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			}
 			err := plugin.Init()
 			require.NoError(t, err)
@@ -2630,9 +2649,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "rename",
 			plugin: &Starlark{
-				Script:           "testdata/rename.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/rename.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("cpu",
@@ -2658,9 +2679,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "drop fields by type",
 			plugin: &Starlark{
-				Script:           "testdata/drop_string_fields.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/drop_string_fields.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("device",
@@ -2690,9 +2713,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "drop fields with unexpected type",
 			plugin: &Starlark{
-				Script:           "testdata/drop_fields_with_unexpected_type.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/drop_fields_with_unexpected_type.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("device",
@@ -2725,9 +2750,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "scale",
 			plugin: &Starlark{
-				Script:           "testdata/scale.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/scale.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("cpu",
@@ -2747,9 +2774,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "ratio",
 			plugin: &Starlark{
-				Script:           "testdata/ratio.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/ratio.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("mem",
@@ -2776,9 +2805,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "logging",
 			plugin: &Starlark{
-				Script:           "testdata/logging.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/logging.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("log",
@@ -2802,9 +2833,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "multiple_metrics",
 			plugin: &Starlark{
-				Script:           "testdata/multiple_metrics.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/multiple_metrics.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("mm",
@@ -2835,9 +2868,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "multiple_metrics_with_json",
 			plugin: &Starlark{
-				Script:           "testdata/multiple_metrics_with_json.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/multiple_metrics_with_json.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("json",
@@ -2868,9 +2903,11 @@ func TestScript(t *testing.T) {
 		{
 			name: "fail",
 			plugin: &Starlark{
-				Script:           "testdata/fail.star",
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Script:           "testdata/fail.star",
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			},
 			input: []telegraf.Metric{
 				testutil.MustMetric("fail",
@@ -3158,9 +3195,11 @@ def apply(metric):
 	for _, tt := range tests {
 		b.Run(tt.name, func(b *testing.B) {
 			plugin := &Starlark{
-				Source:           tt.source,
-				Log:              testutil.Logger{},
-				starlarkLoadFunc: testLoadFunc,
+				StarlarkCommon: common.StarlarkCommon{
+					Source:           tt.source,
+					Log:              testutil.Logger{},
+					StarlarkLoadFunc: testLoadFunc,
+				},
 			}
 
 			err := plugin.Init()
@@ -3204,9 +3243,11 @@ func TestAllScriptTestData(t *testing.T) {
 					outputMetrics = parseMetricsFrom(t, lines, "Example Output:")
 				}
 				plugin := &Starlark{
-					Script:           fn,
-					Log:              testutil.Logger{},
-					starlarkLoadFunc: testLoadFunc,
+					StarlarkCommon: common.StarlarkCommon{
+						Script:           fn,
+						Log:              testutil.Logger{},
+						StarlarkLoadFunc: testLoadFunc,
+					},
 				}
 				require.NoError(t, plugin.Init())
 
@@ -3281,7 +3322,7 @@ func parseErrorMessage(t *testing.T, lines []string, header string) string {
 }
 
 func testLoadFunc(module string, logger telegraf.Logger) (starlark.StringDict, error) {
-	result, err := loadFunc(module, logger)
+	result, err := common.LoadFunc(module, logger)
 	if err != nil {
 		return nil, err
 	}
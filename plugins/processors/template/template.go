@@ -5,6 +5,7 @@ import (
 	"text/template"
 
 	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/template"
 	"github.com/influxdata/telegraf/plugins/processors"
 )
 
@@ -37,10 +38,10 @@ func (r *TemplateProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
 	// for each metric in "in" array
 	for _, metric := range in {
 		var b strings.Builder
-		newM := TemplateMetric{metric}
+		newM := common.NewTemplateMetric(metric)
 
 		// supply TemplateMetric and Template from configuration to Template.Execute
-		err := r.tmpl.Execute(&b, &newM)
+		err := r.tmpl.Execute(&b, newM)
 		if err != nil {
 			r.Log.Errorf("failed to execute template: %v", err)
 			continue
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+// Command reads secrets from the output of a command called with the key as
+// last argument.
+type Command struct {
+	Command []string        `toml:"command"`
+	Timeout config.Duration `toml:"timeout"`
+	Log     telegraf.Logger `toml:"-"`
+}
+
+var sampleConfig = `
+  ## Command to run, the key of the secret is appended as last argument.  The
+  ## secret is read from the standard output of the command with the trailing
+  ## newline removed.
+  command = ["pass", "show"]
+
+  ## Timeout for the command to complete.
+  # timeout = "5s"
+`
+
+func (c *Command) SampleConfig() string {
+	return sampleConfig
+}
+
+func (c *Command) Description() string {
+	return "Read secrets from the output of a command"
+}
+
+func (c *Command) Init() error {
+	if len(c.Command) == 0 {
+		return errors.New("command must be set")
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = config.Duration(5 * time.Second)
+	}
+	return nil
+}
+
+func (c *Command) Get(key string) (string, error) {
+	args := append(append([]string{}, c.Command[1:]...), key)
+	cmd := exec.Command(c.Command[0], args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := internal.RunTimeout(cmd, time.Duration(c.Timeout)); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return "", fmt.Errorf("running %q for key %q failed: %v: %s", c.Command[0], key, err, msg)
+		}
+		return "", fmt.Errorf("running %q for key %q failed: %v", c.Command[0], key, err)
+	}
+
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("command", func() telegraf.SecretStore {
+		return &Command{}
+	})
+}
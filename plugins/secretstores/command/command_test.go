@@ -0,0 +1,44 @@
+package command
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommand_Get(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on unsupported OS")
+	}
+
+	store := &Command{Command: []string{"echo", "-n", "secret"}}
+	require.NoError(t, store.Init())
+
+	value, err := store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "secret token", value)
+
+	store = &Command{Command: []string{"echo"}}
+	require.NoError(t, store.Init())
+
+	value, err = store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "token", value)
+}
+
+func TestCommand_Error(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on unsupported OS")
+	}
+
+	store := &Command{Command: []string{"sh", "-c", "echo not found >&2; exit 1", "sh"}}
+	require.NoError(t, store.Init())
+
+	_, err := store.Get("token")
+	require.EqualError(t, err, `running "sh" for key "token" failed: exit status 1: not found`)
+}
+
+func TestCommand_MissingCommand(t *testing.T) {
+	require.EqualError(t, (&Command{}).Init(), "command must be set")
+}
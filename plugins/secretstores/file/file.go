@@ -0,0 +1,206 @@
+package file
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters used to derive the encryption key from the password
+const (
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	keyLength    = 32
+	saltLength   = 16
+	fileVersion1 = 1
+)
+
+// encryptedFile is the on-disk format of the store.  The data is the
+// AES-GCM encrypted JSON object of all secrets.
+type encryptedFile struct {
+	Version int    `json:"version"`
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+type File struct {
+	Path     string          `toml:"path"`
+	Password string          `toml:"password"`
+	Log      telegraf.Logger `toml:"-"`
+
+	secrets map[string]string
+	sync.Mutex
+}
+
+var sampleConfig = `
+  ## Path of the encrypted secret file, it is created when storing the first
+  ## secret using 'telegraf secret set <id> <key>'.
+  path = "/etc/telegraf/secrets.enc"
+
+  ## Password used to encrypt the secrets.  Use an environment variable to
+  ## keep it out of the config file.
+  password = "${TELEGRAF_SECRETS_PASSWORD}"
+`
+
+func (f *File) SampleConfig() string {
+	return sampleConfig
+}
+
+func (f *File) Description() string {
+	return "Read secrets from an encrypted file"
+}
+
+func (f *File) Init() error {
+	if f.Path == "" {
+		return errors.New("path must be set")
+	}
+	if f.Password == "" {
+		return errors.New("password must be set")
+	}
+
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	f.secrets = secrets
+	return nil
+}
+
+func (f *File) Get(key string) (string, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	value, ok := f.secrets[key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found", key)
+	}
+	return value, nil
+}
+
+// Set stores the secret and writes the re-encrypted file.
+func (f *File) Set(key, value string) error {
+	f.Lock()
+	defer f.Unlock()
+
+	secrets := make(map[string]string, len(f.secrets)+1)
+	for k, v := range f.secrets {
+		secrets[k] = v
+	}
+	secrets[key] = value
+
+	if err := f.write(secrets); err != nil {
+		return err
+	}
+	f.secrets = secrets
+	return nil
+}
+
+// read decrypts the secrets file, a missing file is an empty store.
+func (f *File) read() (map[string]string, error) {
+	buf, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ef encryptedFile
+	if err := json.Unmarshal(buf, &ef); err != nil {
+		return nil, fmt.Errorf("parsing %q failed: %v", f.Path, err)
+	}
+	if ef.Version != fileVersion1 {
+		return nil, fmt.Errorf("unsupported version %d of %q", ef.Version, f.Path)
+	}
+
+	aead, err := f.cipher(ef.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, ef.Nonce, ef.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypting %q failed, wrong password?", f.Path)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("decoding secrets of %q failed", f.Path)
+	}
+	return secrets, nil
+}
+
+// write encrypts the secrets with a new salt and nonce and atomically
+// replaces the secrets file.
+func (f *File) write(secrets map[string]string) error {
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	ef := encryptedFile{
+		Version: fileVersion1,
+		Salt:    make([]byte, saltLength),
+	}
+	if _, err := rand.Read(ef.Salt); err != nil {
+		return err
+	}
+	aead, err := f.cipher(ef.Salt)
+	if err != nil {
+		return err
+	}
+	ef.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ef.Nonce); err != nil {
+		return err
+	}
+	ef.Data = aead.Seal(nil, ef.Nonce, plain, nil)
+
+	buf, err := json.Marshal(ef)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.Path), filepath.Base(f.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+func (f *File) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(f.Password), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func init() {
+	secretstores.Add("file", func() telegraf.SecretStore {
+		return &File{}
+	})
+}
@@ -0,0 +1,50 @@
+package file
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFile_SetGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store := &File{Path: path, Password: "secret-password"}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("token", "1234"))
+	require.NoError(t, store.Set("user", "admin"))
+
+	// Secrets are read back by a new instance
+	store = &File{Path: path, Password: "secret-password"}
+	require.NoError(t, store.Init())
+
+	value, err := store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "1234", value)
+
+	value, err = store.Get("user")
+	require.NoError(t, err)
+	require.Equal(t, "admin", value)
+
+	_, err = store.Get("missing")
+	require.EqualError(t, err, `secret "missing" not found`)
+}
+
+func TestFile_WrongPassword(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	store := &File{Path: path, Password: "secret-password"}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("token", "1234"))
+
+	store = &File{Path: path, Password: "wrong"}
+	err := store.Init()
+	require.Error(t, err)
+	require.NotContains(t, err.Error(), "1234")
+}
+
+func TestFile_MissingOptions(t *testing.T) {
+	require.EqualError(t, (&File{Password: "secret"}).Init(), "path must be set")
+	require.EqualError(t, (&File{Path: "secrets.enc"}).Init(), "password must be set")
+}
@@ -0,0 +1,134 @@
+package keyring
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+// Keyring is a file based stand-in for the OS keyring.  Secrets are grouped
+// by service in a JSON file only accessible by the owner.
+type Keyring struct {
+	Path    string          `toml:"path"`
+	Service string          `toml:"service"`
+	Log     telegraf.Logger `toml:"-"`
+
+	sync.Mutex
+}
+
+var sampleConfig = `
+  ## Path of the keyring file, it must only be accessible by its owner.
+  path = "/etc/telegraf/keyring.json"
+
+  ## Service the secrets are stored under in the keyring.
+  # service = "telegraf"
+`
+
+func (k *Keyring) SampleConfig() string {
+	return sampleConfig
+}
+
+func (k *Keyring) Description() string {
+	return "Read secrets from a keyring file"
+}
+
+func (k *Keyring) Init() error {
+	if k.Path == "" {
+		return errors.New("path must be set")
+	}
+	if k.Service == "" {
+		k.Service = "telegraf"
+	}
+
+	_, err := k.read()
+	return err
+}
+
+func (k *Keyring) Get(key string) (string, error) {
+	k.Lock()
+	defer k.Unlock()
+
+	keyring, err := k.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := keyring[k.Service][key]
+	if !ok {
+		return "", fmt.Errorf("secret %q not found for service %q", key, k.Service)
+	}
+	return value, nil
+}
+
+// Set stores the secret for the service and rewrites the keyring file.
+func (k *Keyring) Set(key, value string) error {
+	k.Lock()
+	defer k.Unlock()
+
+	keyring, err := k.read()
+	if err != nil {
+		return err
+	}
+	if keyring[k.Service] == nil {
+		keyring[k.Service] = make(map[string]string)
+	}
+	keyring[k.Service][key] = value
+
+	buf, err := json.MarshalIndent(keyring, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(k.Path), filepath.Base(k.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.Path)
+}
+
+// read loads the keyring file, a missing file is an empty keyring.
+func (k *Keyring) read() (map[string]map[string]string, error) {
+	keyring := make(map[string]map[string]string)
+
+	info, err := os.Stat(k.Path)
+	if os.IsNotExist(err) {
+		return keyring, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("keyring %q must not be accessible by group or others, permissions are %v",
+			k.Path, info.Mode().Perm())
+	}
+
+	buf, err := ioutil.ReadFile(k.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &keyring); err != nil {
+		return nil, fmt.Errorf("parsing keyring %q failed: %v", k.Path, err)
+	}
+	return keyring, nil
+}
+
+func init() {
+	secretstores.Add("keyring", func() telegraf.SecretStore {
+		return &Keyring{}
+	})
+}
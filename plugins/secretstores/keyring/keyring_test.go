@@ -0,0 +1,61 @@
+package keyring
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyring_SetGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+
+	store := &Keyring{Path: path}
+	require.NoError(t, store.Init())
+	require.NoError(t, store.Set("token", "1234"))
+
+	other := &Keyring{Path: path, Service: "other"}
+	require.NoError(t, other.Init())
+	require.NoError(t, other.Set("token", "5678"))
+
+	value, err := store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "1234", value)
+
+	value, err = other.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "5678", value)
+
+	_, err = store.Get("missing")
+	require.EqualError(t, err, `secret "missing" not found for service "telegraf"`)
+}
+
+func TestKeyring_Refresh(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"telegraf": {"token": "1234"}}`), 0600))
+
+	store := &Keyring{Path: path}
+	require.NoError(t, store.Init())
+	value, err := store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "1234", value)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"telegraf": {"token": "5678"}}`), 0600))
+	value, err = store.Get("token")
+	require.NoError(t, err)
+	require.Equal(t, "5678", value)
+}
+
+func TestKeyring_Permissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping test on unsupported OS")
+	}
+
+	path := filepath.Join(t.TempDir(), "keyring.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{}`), 0644))
+
+	store := &Keyring{Path: path}
+	require.Error(t, store.Init())
+}
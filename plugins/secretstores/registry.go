@@ -0,0 +1,17 @@
+package secretstores
+
+import "github.com/influxdata/telegraf"
+
+type Creator func() telegraf.SecretStore
+
+var SecretStores = map[string]Creator{}
+
+func Add(name string, creator Creator) {
+	SecretStores[name] = creator
+}
+
+// Setter is implemented by secret stores that allow to store secrets.
+type Setter interface {
+	// Set stores the secret under the given key.
+	Set(key, value string) error
+}
@@ -0,0 +1,209 @@
+package avro
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+)
+
+const defaultNamespace = "telegraf"
+
+type Config struct {
+	// SchemaRegistry is the URL of the schema registry.
+	SchemaRegistry string
+
+	// Namespace of the derived record schemas.
+	Namespace string
+
+	// Schemas are the record schemas of measurements, used instead of
+	// deriving them.
+	Schemas map[string]string
+
+	// SkipRegistration encodes the records without registering their
+	// schemas, using a schema ID of zero; for estimating the size of the
+	// messages without side effects.
+	SkipRegistration bool
+}
+
+// Serializer writes metrics as Avro records in the Confluent wire format,
+// registering the record schema of each measurement with a schema registry.
+//
+// The schema of a measurement without a given schema is derived from its
+// metrics, and extended and registered again when a metric has a new field
+// or a field of another type.
+type Serializer struct {
+	registry  *schemaregistry.Client
+	namespace string
+	schemas   map[string]*schema
+	derived   map[string]*schema
+
+	skipRegistration bool
+}
+
+func NewSerializer(config *Config) (*Serializer, error) {
+	if config.SchemaRegistry == "" {
+		return nil, errors.New("schema registry URL must be set")
+	}
+
+	registry, err := schemaregistry.NewClient(config.SchemaRegistry)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := config.Namespace
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+
+	schemas := make(map[string]*schema, len(config.Schemas))
+	for measurement, text := range config.Schemas {
+		s, err := parseSchema(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schema of measurement %q: %v", measurement, err)
+		}
+		schemas[measurement] = s
+	}
+
+	return &Serializer{
+		registry:  registry,
+		namespace: namespace,
+		schemas:   schemas,
+		derived:   make(map[string]*schema),
+
+		skipRegistration: config.SkipRegistration,
+	}, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	sch, err := s.schema(metric)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := sch.native(metric)
+	if err != nil {
+		return nil, fmt.Errorf("metric %q does not match schema %s: %v", metric.Name(), sch.fullname, err)
+	}
+
+	payload, err := sch.codec.BinaryFromNative(nil, record)
+	if err != nil {
+		return nil, fmt.Errorf("encoding metric %q failed: %v", metric.Name(), err)
+	}
+	return schemaregistry.Encode(sch.id, payload), nil
+}
+
+// SerializeBatch concatenates the messages of the metrics.  The messages
+// are not delimited, so outputs should send each of them on its own.
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, metric := range metrics {
+		msg, err := s.Serialize(metric)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, msg...)
+	}
+	return buf, nil
+}
+
+// schema returns the registered schema of the metric's measurement.
+func (s *Serializer) schema(metric telegraf.Metric) (*schema, error) {
+	sch, ok := s.schemas[metric.Name()]
+	if !ok {
+		var err error
+		if sch, err = s.derive(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	if !sch.registered {
+		if err := s.register(sch); err != nil {
+			return nil, err
+		}
+	}
+	return sch, nil
+}
+
+// derive returns the derived schema of the metric's measurement, extending
+// it with the new fields and field types of the metric.  The schema in use
+// is only replaced once the extended one is registered.
+func (s *Serializer) derive(metric telegraf.Metric) (*schema, error) {
+	current := s.derived[metric.Name()]
+
+	var fields []*field
+	if current != nil {
+		fields = append(fields, current.fields...)
+	} else {
+		fields = []*field{
+			{name: "timestamp", source: sourceTime, types: []string{timestampType}},
+			{name: "tags", source: sourceTags, types: []string{tagsType}},
+		}
+	}
+
+	index := make(map[string]int, len(fields))
+	for i, f := range fields {
+		index[f.name] = i
+	}
+
+	metricFields := append(metric.FieldList()[:0:0], metric.FieldList()...)
+	sort.Slice(metricFields, func(i, j int) bool { return metricFields[i].Key < metricFields[j].Key })
+
+	changed := current == nil
+	for _, mf := range metricFields {
+		t := valueType(mf.Value)
+		if t == "" {
+			return nil, fmt.Errorf("field %q of metric %q has unsupported type %T", mf.Key, metric.Name(), mf.Value)
+		}
+		name := sanitize(mf.Key)
+
+		i, ok := index[name]
+		if !ok {
+			index[name] = len(fields)
+			fields = append(fields, &field{name: name, key: mf.Key, source: sourceField, types: []string{"null", t}})
+			changed = true
+			continue
+		}
+
+		f := fields[i]
+		if f.source != sourceField || f.key != mf.Key {
+			return nil, fmt.Errorf("field %q of metric %q conflicts with record field %q", mf.Key, metric.Name(), name)
+		}
+		if !contains(f.types, t) {
+			fields[i] = &field{name: f.name, key: f.key, source: f.source, types: append(f.types[:len(f.types):len(f.types)], t)}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return current, nil
+	}
+
+	sch, err := deriveSchema(s.namespace, sanitize(metric.Name()), fields)
+	if err != nil {
+		return nil, fmt.Errorf("deriving schema of metric %q failed: %v", metric.Name(), err)
+	}
+	if err := s.register(sch); err != nil {
+		return nil, err
+	}
+	s.derived[metric.Name()] = sch
+	return sch, nil
+}
+
+// register registers a schema under its full name as subject.
+func (s *Serializer) register(sch *schema) error {
+	if s.skipRegistration {
+		sch.registered = true
+		return nil
+	}
+
+	id, err := s.registry.Register(sch.fullname, &schemaregistry.Schema{Schema: sch.text})
+	if err != nil {
+		return fmt.Errorf("registering schema %s failed: %v", sch.fullname, err)
+	}
+	sch.id = id
+	sch.registered = true
+	return nil
+}
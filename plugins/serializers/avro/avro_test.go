@@ -0,0 +1,267 @@
+package avro
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/schemaregistry"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// registry is a schema registry stand-in giving an ID to each new schema.
+type registry struct {
+	*httptest.Server
+	subjects map[string][]string
+	schemas  []string
+}
+
+func newRegistry(t *testing.T) *registry {
+	r := &registry{subjects: make(map[string][]string)}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		require.Equal(t, http.MethodPost, req.Method)
+		subject := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/subjects/"), "/versions")
+
+		var schema schemaregistry.Schema
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&schema))
+
+		id := 0
+		for i, s := range r.schemas {
+			if s == schema.Schema {
+				id = i + 1
+			}
+		}
+		if id == 0 {
+			r.schemas = append(r.schemas, schema.Schema)
+			id = len(r.schemas)
+		}
+		r.subjects[subject] = append(r.subjects[subject], schema.Schema)
+
+		octets, err := json.Marshal(map[string]int{"id": id})
+		require.NoError(t, err)
+		w.Write(octets)
+	}))
+	return r
+}
+
+// decode decodes a message with the schema registered under its ID.
+func (r *registry) decode(t *testing.T, buf []byte) (int, map[string]interface{}) {
+	id, payload, err := schemaregistry.Decode(buf)
+	require.NoError(t, err)
+	require.True(t, id > 0 && id <= len(r.schemas))
+
+	codec, err := goavro.NewCodec(r.schemas[id-1])
+	require.NoError(t, err)
+	native, rest, err := codec.NativeFromBinary(payload)
+	require.NoError(t, err)
+	require.Empty(t, rest)
+	return id, native.(map[string]interface{})
+}
+
+func TestSerializeDerivedSchema(t *testing.T) {
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: r.URL})
+	require.NoError(t, err)
+
+	tm := time.Unix(1622745000, 123456000).UTC()
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"usage-idle": 90.5, "cores": int64(4), "ok": true},
+		tm,
+	)
+
+	buf, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Len(t, r.subjects["telegraf.cpu"], 1)
+
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(r.schemas[0]), &schema))
+	require.Equal(t, "cpu", schema["name"])
+	require.Equal(t, "telegraf", schema["namespace"])
+	require.Len(t, schema["fields"], 5)
+
+	id, record := r.decode(t, buf)
+	require.Equal(t, 1, id)
+	require.Equal(t, map[string]interface{}{
+		"timestamp":  tm,
+		"tags":       map[string]interface{}{"host": "a"},
+		"cores":      goavro.Union("long", int64(4)),
+		"ok":         goavro.Union("boolean", true),
+		"usage_idle": goavro.Union("double", 90.5),
+	}, record)
+
+	// The same fields reuse the registered schema
+	_, err = s.Serialize(testutil.MustMetric("cpu", nil, map[string]interface{}{"cores": int64(2)}, tm))
+	require.NoError(t, err)
+	require.Len(t, r.subjects["telegraf.cpu"], 1)
+}
+
+func TestSerializeFieldSetChange(t *testing.T) {
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: r.URL, Namespace: "com.example"})
+	require.NoError(t, err)
+
+	tm := time.Unix(1622745000, 0).UTC()
+	_, err = s.Serialize(testutil.MustMetric("mem", nil, map[string]interface{}{"used": int64(1)}, tm))
+	require.NoError(t, err)
+
+	// A new field extends the schema
+	buf, err := s.Serialize(testutil.MustMetric("mem", nil, map[string]interface{}{"free": int64(2)}, tm))
+	require.NoError(t, err)
+	require.Len(t, r.subjects["com.example.mem"], 2)
+
+	id, record := r.decode(t, buf)
+	require.Equal(t, 2, id)
+	require.Nil(t, record["used"])
+	require.Equal(t, goavro.Union("long", int64(2)), record["free"])
+
+	// A new type of a field adds a branch to its union
+	buf, err = s.Serialize(testutil.MustMetric("mem", nil, map[string]interface{}{"used": 1.5}, tm))
+	require.NoError(t, err)
+	require.Len(t, r.subjects["com.example.mem"], 3)
+
+	id, record = r.decode(t, buf)
+	require.Equal(t, 3, id)
+	require.Equal(t, goavro.Union("double", 1.5), record["used"])
+
+	// The previous types still use the extended schema
+	buf, err = s.Serialize(testutil.MustMetric("mem", nil, map[string]interface{}{"used": int64(3)}, tm))
+	require.NoError(t, err)
+	require.Len(t, r.subjects["com.example.mem"], 3)
+
+	id, record = r.decode(t, buf)
+	require.Equal(t, 3, id)
+	require.Equal(t, goavro.Union("long", int64(3)), record["used"])
+}
+
+func TestSerializeGivenSchema(t *testing.T) {
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{
+		SchemaRegistry: r.URL,
+		Schemas: map[string]string{
+			"cpu": `{
+			  "type": "record",
+			  "name": "CPU",
+			  "namespace": "com.example",
+			  "fields": [
+			    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+			    {"name": "measurement", "type": "string"},
+			    {"name": "host", "type": "string"},
+			    {"name": "usage", "type": "float"},
+			    {"name": "cores", "type": ["null", "int"], "default": null}
+			  ]
+			}`,
+		},
+	})
+	require.NoError(t, err)
+
+	tm := time.Unix(1622745000, 123000000).UTC()
+	buf, err := s.Serialize(testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "cpu": "cpu0"},
+		map[string]interface{}{"usage": 4.5, "extra": "ignored"},
+		tm,
+	))
+	require.NoError(t, err)
+	require.Len(t, r.subjects["com.example.CPU"], 1)
+
+	_, record := r.decode(t, buf)
+	require.Equal(t, map[string]interface{}{
+		"timestamp":   tm,
+		"measurement": "cpu",
+		"host":        "a",
+		"usage":       float32(4.5),
+		"cores":       nil,
+	}, record)
+
+	// Missing required field
+	_, err = s.Serialize(testutil.MustMetric("cpu", nil, map[string]interface{}{"usage": 4.5}, tm))
+	require.Error(t, err)
+}
+
+func TestSerializeErrors(t *testing.T) {
+	_, err := NewSerializer(&Config{})
+	require.Error(t, err)
+
+	_, err = NewSerializer(&Config{SchemaRegistry: "http://localhost:8081", Schemas: map[string]string{"cpu": `"string"`}})
+	require.Error(t, err)
+
+	_, err = NewSerializer(&Config{
+		SchemaRegistry: "http://localhost:8081",
+		Schemas:        map[string]string{"cpu": `{"type": "record", "name": "cpu", "fields": [{"name": "raw", "type": "bytes"}]}`},
+	})
+	require.Error(t, err)
+
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: r.URL})
+	require.NoError(t, err)
+
+	// Fields sanitized to the same name
+	_, err = s.Serialize(testutil.MustMetric("cpu", nil, map[string]interface{}{"a.b": 1.0, "a_b": 2.0}, time.Unix(0, 0)))
+	require.Error(t, err)
+
+	// Unsigned integers out of range of longs
+	_, err = s.Serialize(testutil.MustMetric("disk", nil, map[string]interface{}{"free": uint64(1 << 63)}, time.Unix(0, 0)))
+	require.Error(t, err)
+}
+
+func TestSerializeBatch(t *testing.T) {
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: r.URL})
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	single, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	batch, err := s.SerializeBatch([]telegraf.Metric{m, m})
+	require.NoError(t, err)
+	require.Equal(t, append(single, single...), batch)
+}
+
+func TestSerializeSkipRegistration(t *testing.T) {
+	r := newRegistry(t)
+	defer r.Close()
+
+	s, err := NewSerializer(&Config{SchemaRegistry: r.URL, SkipRegistration: true})
+	require.NoError(t, err)
+
+	m := testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0))
+	unregistered, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Empty(t, r.schemas)
+
+	id, _, err := schemaregistry.Decode(unregistered)
+	require.NoError(t, err)
+	require.Equal(t, 0, id)
+
+	// The message is as long as the one of a registered schema
+	s, err = NewSerializer(&Config{SchemaRegistry: r.URL})
+	require.NoError(t, err)
+	registered, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Len(t, unregistered, len(registered))
+}
+
+func TestSanitize(t *testing.T) {
+	require.Equal(t, "usage_idle", sanitize("usage-idle"))
+	require.Equal(t, "_95th", sanitize("95th"))
+	require.Equal(t, "_", sanitize(""))
+}
@@ -0,0 +1,383 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/linkedin/goavro/v2"
+)
+
+// source is where the value of a record field is taken from.
+type source int
+
+const (
+	// sourceValue takes the tag or else the field of the same key.
+	sourceValue source = iota
+	// sourceField takes the field of the key, for the derived schemas.
+	sourceField
+	sourceTime
+	sourceName
+	sourceTags
+)
+
+const (
+	timestampType = "long.timestamp-micros"
+	tagsType      = "map"
+)
+
+// field is a field of an Avro record.  Its types are the names of the union
+// branches, or a single type.
+type field struct {
+	name   string
+	key    string
+	source source
+	types  []string
+}
+
+// schema is a record schema along with its codec and registered ID.
+type schema struct {
+	fullname   string
+	text       string
+	fields     []*field
+	codec      *goavro.Codec
+	id         int
+	registered bool
+}
+
+type recordJSON struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace,omitempty"`
+	Fields    []fieldJSON `json:"fields"`
+}
+
+type fieldJSON struct {
+	Name    string          `json:"name"`
+	Type    json.RawMessage `json:"type"`
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+// newSchema builds the codec of a schema text.
+func newSchema(fullname, text string, fields []*field) (*schema, error) {
+	codec, err := goavro.NewCodec(text)
+	if err != nil {
+		return nil, err
+	}
+	return &schema{fullname: fullname, text: text, fields: fields, codec: codec}, nil
+}
+
+// deriveSchema builds the record schema of a measurement from its fields.
+// The metric fields are nullable so that a record can omit some of them.
+func deriveSchema(namespace, name string, fields []*field) (*schema, error) {
+	record := recordJSON{
+		Type:      "record",
+		Name:      name,
+		Namespace: namespace,
+		Fields:    make([]fieldJSON, 0, len(fields)),
+	}
+	for _, f := range fields {
+		types := make([]json.RawMessage, 0, len(f.types))
+		for _, t := range f.types {
+			types = append(types, typeJSON(t))
+		}
+
+		fj := fieldJSON{Name: f.name}
+		if len(types) == 1 {
+			fj.Type = types[0]
+		} else {
+			octets, err := json.Marshal(types)
+			if err != nil {
+				return nil, err
+			}
+			fj.Type = octets
+		}
+		if f.types[0] == "null" {
+			fj.Default = json.RawMessage("null")
+		}
+		record.Fields = append(record.Fields, fj)
+	}
+
+	octets, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	return newSchema(namespace+"."+name, string(octets), fields)
+}
+
+func typeJSON(t string) json.RawMessage {
+	switch t {
+	case timestampType:
+		return json.RawMessage(`{"type":"long","logicalType":"timestamp-micros"}`)
+	case tagsType:
+		return json.RawMessage(`{"type":"map","values":"string"}`)
+	default:
+		return json.RawMessage(`"` + t + `"`)
+	}
+}
+
+// parseSchema reads a record schema given by the user.  The fields named
+// "timestamp", "measurement" and "tags" take the time, the name and the tags
+// of the metric, the other fields take the tag or field of the same name.
+func parseSchema(text string) (*schema, error) {
+	var record recordJSON
+	if err := json.Unmarshal([]byte(text), &record); err != nil {
+		return nil, err
+	}
+	if record.Type != "record" {
+		return nil, fmt.Errorf("schema must be a record instead of %q", record.Type)
+	}
+
+	fullname := record.Name
+	if record.Namespace != "" && !strings.Contains(fullname, ".") {
+		fullname = record.Namespace + "." + fullname
+	}
+
+	fields := make([]*field, 0, len(record.Fields))
+	for _, fj := range record.Fields {
+		types, err := typeNames(fj.Type)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", fj.Name, err)
+		}
+
+		f := &field{name: fj.Name, key: fj.Name, types: types}
+		switch {
+		case fj.Name == "timestamp":
+			f.source = sourceTime
+		case fj.Name == "measurement":
+			f.source = sourceName
+		case fj.Name == "tags" && contains(types, tagsType):
+			f.source = sourceTags
+		}
+		fields = append(fields, f)
+	}
+
+	return newSchema(fullname, text, fields)
+}
+
+// typeNames returns the names of the union branches of a type, as named by
+// goavro, or the name of a single type.
+func typeNames(raw json.RawMessage) ([]string, error) {
+	var union []json.RawMessage
+	if err := json.Unmarshal(raw, &union); err == nil {
+		names := make([]string, 0, len(union))
+		for _, branch := range union {
+			name, err := typeName(branch)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+		return names, nil
+	}
+
+	name, err := typeName(raw)
+	if err != nil {
+		return nil, err
+	}
+	return []string{name}, nil
+}
+
+func typeName(raw json.RawMessage) (string, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		switch name {
+		case "null", "boolean", "int", "long", "float", "double", "string":
+			return name, nil
+		}
+		return "", fmt.Errorf("unsupported type %q", name)
+	}
+
+	var complex struct {
+		Type        string          `json:"type"`
+		LogicalType string          `json:"logicalType"`
+		Values      json.RawMessage `json:"values"`
+	}
+	if err := json.Unmarshal(raw, &complex); err != nil {
+		return "", err
+	}
+	switch {
+	case complex.Type == "long" && (complex.LogicalType == "timestamp-millis" || complex.LogicalType == "timestamp-micros"):
+		return "long." + complex.LogicalType, nil
+	case complex.Type == "map" && string(complex.Values) == `"string"`:
+		return tagsType, nil
+	}
+	return "", fmt.Errorf("unsupported type %s", raw)
+}
+
+// native returns the record of a metric, as expected by the codec.
+func (s *schema) native(m telegraf.Metric) (map[string]interface{}, error) {
+	record := make(map[string]interface{}, len(s.fields))
+	for _, f := range s.fields {
+		var v interface{}
+		switch f.source {
+		case sourceTime:
+			v = m.Time()
+		case sourceName:
+			v = m.Name()
+		case sourceTags:
+			v = m.Tags()
+		case sourceField:
+			v, _ = m.GetField(f.key)
+		default:
+			if tag, ok := m.GetTag(f.key); ok {
+				v = tag
+			} else if value, ok := m.GetField(f.key); ok {
+				v = value
+			}
+		}
+
+		datum, err := f.datum(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", f.name, err)
+		}
+		record[f.name] = datum
+	}
+	return record, nil
+}
+
+// datum converts a value to the type of the field, preferring the union
+// branch of the same type over the ones the value can be converted to.
+func (f *field) datum(v interface{}) (interface{}, error) {
+	if v == nil {
+		if contains(f.types, "null") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("missing value")
+	}
+
+	if t := valueType(v); t != "" && contains(f.types, t) {
+		return f.wrap(t, convert(t, v))
+	}
+	for _, t := range f.types {
+		if datum := convert(t, v); datum != nil {
+			return f.wrap(t, datum)
+		}
+	}
+	return nil, fmt.Errorf("cannot convert %T to %s", v, strings.Join(f.types, " or "))
+}
+
+func (f *field) wrap(t string, datum interface{}) (interface{}, error) {
+	if datum == nil {
+		return nil, fmt.Errorf("value out of range of %s", t)
+	}
+	if len(f.types) == 1 {
+		return datum, nil
+	}
+	return goavro.Union(t, datum), nil
+}
+
+// valueType returns the Avro type of a field value.
+func valueType(v interface{}) string {
+	switch v.(type) {
+	case int64, uint64:
+		return "long"
+	case float64:
+		return "double"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	}
+	return ""
+}
+
+// convert converts a value to an Avro type, returning nil if it cannot.
+func convert(t string, v interface{}) interface{} {
+	switch t {
+	case "boolean":
+		if b, ok := v.(bool); ok {
+			return b
+		}
+	case "int":
+		switch v := v.(type) {
+		case int64:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int32(v)
+			}
+		case uint64:
+			if v <= math.MaxInt32 {
+				return int32(v)
+			}
+		}
+	case "long":
+		switch v := v.(type) {
+		case int64:
+			return v
+		case uint64:
+			if v <= math.MaxInt64 {
+				return int64(v)
+			}
+		case time.Time:
+			return v.UnixNano()
+		}
+	case "float":
+		switch v := v.(type) {
+		case float64:
+			return float32(v)
+		case int64:
+			return float32(v)
+		case uint64:
+			return float32(v)
+		}
+	case "double":
+		switch v := v.(type) {
+		case float64:
+			return v
+		case int64:
+			return float64(v)
+		case uint64:
+			return float64(v)
+		}
+	case "string":
+		if s, ok := v.(string); ok {
+			return s
+		}
+	case "long.timestamp-millis", "long.timestamp-micros":
+		if tm, ok := v.(time.Time); ok {
+			return tm
+		}
+	case tagsType:
+		if tags, ok := v.(map[string]string); ok {
+			values := make(map[string]interface{}, len(tags))
+			for k, v := range tags {
+				values[k] = v
+			}
+			return values
+		}
+	}
+	return nil
+}
+
+// sanitize replaces the characters that are not allowed in Avro names.
+func sanitize(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
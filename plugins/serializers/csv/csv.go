@@ -0,0 +1,279 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// WideFormat writes a row per metric with a column per tag and field.
+	WideFormat = "wide"
+	// LongFormat writes a row per field with the name, tag set, field key
+	// and field value columns.
+	LongFormat = "long"
+)
+
+var longColumns = []string{"timestamp", "name", "tags", "field", "value"}
+
+type Config struct {
+	// Format is either WideFormat or LongFormat, defaults to WideFormat.
+	Format string
+	// Columns sets the columns and their order, defaults to all columns.
+	Columns []string
+	// Header enables writing a header row.
+	Header bool
+	// TimestampFormat is "unix", "unix_ms", "unix_us", "unix_ns" or a Go
+	// time layout, defaults to "unix".
+	TimestampFormat string
+}
+
+// Serializer writes metrics as CSV rows.  It keeps track of the headers
+// already written, so it is not safe for concurrent use.
+type Serializer struct {
+	format          string
+	columns         []string
+	header          bool
+	timestampFormat string
+
+	// skipHeader is set when resuming output that holds the headers already.
+	skipHeader bool
+
+	// Columns of the measurements, by measurement name in wide format
+	// without configured columns, and with an empty name otherwise.
+	measurements map[string][]string
+}
+
+func NewSerializer(config Config) (*Serializer, error) {
+	s := &Serializer{
+		format:          config.Format,
+		columns:         config.Columns,
+		header:          config.Header,
+		timestampFormat: config.TimestampFormat,
+		measurements:    make(map[string][]string),
+	}
+
+	if s.format == "" {
+		s.format = WideFormat
+	}
+	if s.timestampFormat == "" {
+		s.timestampFormat = "unix"
+	}
+
+	switch s.format {
+	case WideFormat:
+	case LongFormat:
+		if len(s.columns) == 0 {
+			s.columns = longColumns
+		}
+		for _, column := range s.columns {
+			if !contains(longColumns, column) {
+				return nil, fmt.Errorf("invalid column %q for the long format", column)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid format %q", s.format)
+	}
+
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	for _, metric := range metrics {
+		var err error
+		if s.format == LongFormat {
+			err = s.writeLong(w, metric)
+		} else {
+			err = s.writeWide(w, metric)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Copy returns a serializer with the same configuration and no headers
+// written.
+func (s *Serializer) Copy() *Serializer {
+	c := *s
+	c.measurements = make(map[string][]string)
+	c.skipHeader = false
+	return &c
+}
+
+// Reset forgets the headers already written, so that they are written again,
+// for example at the start of a new file.
+func (s *Serializer) Reset() {
+	s.measurements = make(map[string][]string)
+	s.skipHeader = false
+}
+
+// Resume does not write the headers until the next Reset, for example when
+// appending to a file that already starts with them.
+func (s *Serializer) Resume() {
+	s.skipHeader = true
+}
+
+func (s *Serializer) writeWide(w *csv.Writer, metric telegraf.Metric) error {
+	key := ""
+	if len(s.columns) == 0 {
+		key = metric.Name()
+	}
+
+	columns, ok := s.measurements[key]
+	if !ok {
+		columns = s.columns
+		if len(columns) == 0 {
+			columns = wideColumns(metric)
+		}
+		s.measurements[key] = columns
+
+		if s.header && !s.skipHeader {
+			if err := w.Write(columns); err != nil {
+				return err
+			}
+		}
+	}
+
+	record := make([]string, 0, len(columns))
+	for _, column := range columns {
+		switch column {
+		case "timestamp":
+			record = append(record, s.formatTimestamp(metric.Time()))
+		case "name":
+			record = append(record, metric.Name())
+		default:
+			if value, ok := metric.GetTag(column); ok {
+				record = append(record, value)
+			} else if value, ok := metric.GetField(column); ok {
+				record = append(record, formatValue(value))
+			} else {
+				record = append(record, "")
+			}
+		}
+	}
+	return w.Write(record)
+}
+
+func (s *Serializer) writeLong(w *csv.Writer, metric telegraf.Metric) error {
+	if _, ok := s.measurements[""]; !ok {
+		s.measurements[""] = s.columns
+
+		if s.header && !s.skipHeader {
+			if err := w.Write(s.columns); err != nil {
+				return err
+			}
+		}
+	}
+
+	timestamp := s.formatTimestamp(metric.Time())
+	tags := formatTags(metric.TagList())
+	fields := make([]*telegraf.Field, len(metric.FieldList()))
+	copy(fields, metric.FieldList())
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Key < fields[j].Key })
+	for _, field := range fields {
+		record := make([]string, 0, len(s.columns))
+		for _, column := range s.columns {
+			switch column {
+			case "timestamp":
+				record = append(record, timestamp)
+			case "name":
+				record = append(record, metric.Name())
+			case "tags":
+				record = append(record, tags)
+			case "field":
+				record = append(record, field.Key)
+			case "value":
+				record = append(record, formatValue(field.Value))
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Serializer) formatTimestamp(t time.Time) string {
+	switch s.timestampFormat {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	default:
+		return t.UTC().Format(s.timestampFormat)
+	}
+}
+
+// wideColumns returns the default columns of a measurement: the timestamp,
+// the name, and the sorted tag and field keys of its first metric.
+func wideColumns(metric telegraf.Metric) []string {
+	columns := []string{"timestamp", "name"}
+	for _, tag := range metric.TagList() {
+		columns = append(columns, tag.Key)
+	}
+
+	fields := make([]string, 0, len(metric.FieldList()))
+	for _, field := range metric.FieldList() {
+		fields = append(fields, field.Key)
+	}
+	sort.Strings(fields)
+	return append(columns, fields...)
+}
+
+func formatTags(tags []*telegraf.Tag) string {
+	pairs := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		pairs = append(pairs, tag.Key+"="+tag.Value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package csv
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_user": 4.5, "usage_system": 1.0},
+			time.Unix(1622745000, 0),
+		),
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "b", "cpu": "cpu0"},
+			map[string]interface{}{"usage_user": 2.25},
+			time.Unix(1622745010, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"used": int64(1024), "active": true, "state": "ok, fine"},
+			time.Unix(1622745000, 0),
+		),
+	}
+}
+
+func TestSerializeWide(t *testing.T) {
+	s, err := NewSerializer(Config{Header: true})
+	require.NoError(t, err)
+
+	actual, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	expected := "timestamp,name,cpu,host,usage_system,usage_user\n" +
+		"1622745000,cpu,cpu0,a,1,4.5\n" +
+		"1622745010,cpu,cpu0,b,,2.25\n" +
+		"timestamp,name,host,active,state,used\n" +
+		"1622745000,mem,a,true,\"ok, fine\",1024\n"
+	require.Equal(t, expected, string(actual))
+}
+
+func TestSerializeWideColumns(t *testing.T) {
+	s, err := NewSerializer(Config{
+		Columns:         []string{"name", "host", "usage_user", "used", "timestamp"},
+		Header:          true,
+		TimestampFormat: "2006-01-02T15:04:05Z07:00",
+	})
+	require.NoError(t, err)
+
+	actual, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	expected := "name,host,usage_user,used,timestamp\n" +
+		"cpu,a,4.5,,2021-06-03T18:30:00Z\n" +
+		"cpu,b,2.25,,2021-06-03T18:30:10Z\n" +
+		"mem,a,,1024,2021-06-03T18:30:00Z\n"
+	require.Equal(t, expected, string(actual))
+}
+
+func TestSerializeLong(t *testing.T) {
+	s, err := NewSerializer(Config{
+		Format:          LongFormat,
+		Header:          true,
+		TimestampFormat: "unix_ms",
+	})
+	require.NoError(t, err)
+
+	actual, err := s.SerializeBatch(testMetrics()[1:])
+	require.NoError(t, err)
+
+	expected := "timestamp,name,tags,field,value\n" +
+		"1622745010000,cpu,\"cpu=cpu0,host=b\",usage_user,2.25\n" +
+		"1622745000000,mem,host=a,active,true\n" +
+		"1622745000000,mem,host=a,state,\"ok, fine\"\n" +
+		"1622745000000,mem,host=a,used,1024\n"
+	require.Equal(t, expected, string(actual))
+}
+
+func TestSerializeLongColumns(t *testing.T) {
+	s, err := NewSerializer(Config{
+		Format:  LongFormat,
+		Columns: []string{"field", "value"},
+	})
+	require.NoError(t, err)
+
+	actual, err := s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, "usage_user,2.25\n", string(actual))
+}
+
+func TestSerializeLongInvalidColumn(t *testing.T) {
+	_, err := NewSerializer(Config{
+		Format:  LongFormat,
+		Columns: []string{"host"},
+	})
+	require.Error(t, err)
+}
+
+func TestSerializeInvalidFormat(t *testing.T) {
+	_, err := NewSerializer(Config{Format: "pivot"})
+	require.Error(t, err)
+}
+
+func TestHeaderOnce(t *testing.T) {
+	s, err := NewSerializer(Config{Header: true})
+	require.NoError(t, err)
+
+	m := testMetrics()[1]
+	first, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,name,cpu,host,usage_user\n1622745010,cpu,cpu0,b,2.25\n", string(first))
+
+	second, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "1622745010,cpu,cpu0,b,2.25\n", string(second))
+
+	// A copy starts without any header written
+	c := s.Copy()
+	copied, err := c.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(copied))
+
+	s.Reset()
+	reset, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(reset))
+
+	// A resumed serializer writes no header until reset
+	r := s.Copy()
+	r.Resume()
+	resumed, err := r.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, string(second), string(resumed))
+
+	r.Reset()
+	reset, err = r.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, string(first), string(reset))
+}
+
+func TestTimestampFormats(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		time.Unix(1622745000, 123456789),
+	)
+
+	tests := []struct {
+		format   string
+		expected string
+	}{
+		{format: "unix", expected: "1622745000,1\n"},
+		{format: "unix_ms", expected: "1622745000123,1\n"},
+		{format: "unix_us", expected: "1622745000123456,1\n"},
+		{format: "unix_ns", expected: "1622745000123456789,1\n"},
+		{format: "2006-01-02 15:04:05.000", expected: "2021-06-03 18:30:00.123,1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			s, err := NewSerializer(Config{
+				Columns:         []string{"timestamp", "value"},
+				TimestampFormat: tt.format,
+			})
+			require.NoError(t, err)
+
+			actual, err := s.Serialize(m)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
@@ -2,6 +2,7 @@ package prometheus
 
 import (
 	"hash/fnv"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -10,10 +11,15 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/influxdata/telegraf"
 	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const helpString = "Telegraf collected metric"
 
+// exemplarInfix separates the name of a sample from the exemplar parts in
+// the field keys of exemplars, as parsed from the OpenMetrics format.
+const exemplarInfix = "_exemplar_"
+
 type TimeFunc func() time.Time
 
 type MetricFamily struct {
@@ -36,12 +42,20 @@ type LabelPair struct {
 }
 
 type Scaler struct {
-	Value float64
+	Value    float64
+	Exemplar *Exemplar
 }
 
 type Bucket struct {
-	Bound float64
-	Count uint64
+	Bound    float64
+	Count    uint64
+	Exemplar *Exemplar
+}
+
+type Exemplar struct {
+	Labels []LabelPair
+	Value  float64
+	Time   time.Time
 }
 
 type Quantile struct {
@@ -58,7 +72,7 @@ type Histogram struct {
 func (h *Histogram) merge(b Bucket) {
 	for i := range h.Buckets {
 		if h.Buckets[i].Bound == b.Bound {
-			h.Buckets[i].Count = b.Count
+			h.Buckets[i] = b
 			return
 		}
 	}
@@ -151,7 +165,7 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 	addedFieldLabel := false
 	for _, field := range metric.FieldList() {
 		value, ok := field.Value.(string)
-		if !ok {
+		if !ok || isExemplarField(metric, field.Key) {
 			continue
 		}
 
@@ -179,9 +193,100 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 	return labels
 }
 
+// isExemplarField tells if a field is part of the exemplar of another field
+// of the metric.
+func isExemplarField(metric telegraf.Metric, key string) bool {
+	_, ok := exemplarSample(metric, key)
+	return ok
+}
+
+// exemplarSample returns the key of the field of the exemplar field.
+func exemplarSample(metric telegraf.Metric, key string) (string, bool) {
+	for i := strings.Index(key, exemplarInfix); i > 0; {
+		if metric.HasField(key[:i]) {
+			return key[:i], true
+		}
+		next := strings.Index(key[i+1:], exemplarInfix)
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return "", false
+}
+
+// isCreatedField tells if a field is the creation time of a counter,
+// histogram or summary of the metric, which has no Prometheus type.
+func isCreatedField(metric telegraf.Metric, key string) bool {
+	if !strings.HasSuffix(key, "_created") {
+		return false
+	}
+	name := strings.TrimSuffix(key, "_created")
+	for _, suffix := range []string{"_total", "_count", "_sum"} {
+		if metric.HasField(name + suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// exemplars returns the exemplars of the fields of the metric.
+func exemplars(metric telegraf.Metric) map[string]*Exemplar {
+	var result map[string]*Exemplar
+	for _, field := range metric.FieldList() {
+		sample, ok := exemplarSample(metric, field.Key)
+		if !ok {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]*Exemplar)
+		}
+		e, ok := result[sample]
+		if !ok {
+			e = &Exemplar{}
+			result[sample] = e
+		}
+
+		switch part := strings.TrimPrefix(field.Key, sample+exemplarInfix); {
+		case part == "value":
+			if value, ok := SampleValue(field.Value); ok {
+				e.Value = value
+			}
+		case part == "timestamp":
+			if value, ok := SampleValue(field.Value); ok {
+				sec, frac := math.Modf(value)
+				e.Time = time.Unix(int64(sec), int64(frac*1e9))
+			}
+		case strings.HasPrefix(part, "label_"):
+			value, ok := field.Value.(string)
+			if !ok {
+				continue
+			}
+			name, ok := SanitizeLabelName(strings.TrimPrefix(part, "label_"))
+			if !ok {
+				continue
+			}
+			e.Labels = append(e.Labels, LabelPair{Name: name, Value: value})
+		}
+	}
+
+	for _, e := range result {
+		sort.Slice(e.Labels, func(i, j int) bool {
+			return e.Labels[i].Name < e.Labels[j].Name
+		})
+	}
+	return result
+}
+
 func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	labels := c.createLabels(metric)
+	exemplars := exemplars(metric)
 	for _, field := range metric.FieldList() {
+		if isExemplarField(metric, field.Key) || isCreatedField(metric, field.Key) {
+			continue
+		}
+
 		metricName := MetricName(metric.Name(), field.Key, metric.Type())
 		metricName, ok := SanitizeMetricName(metricName)
 		if !ok {
@@ -229,7 +334,7 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 				Labels:  labels,
 				Time:    metric.Time(),
 				AddTime: now,
-				Scaler:  &Scaler{Value: value},
+				Scaler:  &Scaler{Value: value, Exemplar: exemplars[field.Key]},
 			}
 
 			entry.Metrics[metricKey] = m
@@ -259,8 +364,9 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 				}
 
 				m.Histogram.merge(Bucket{
-					Bound: bound,
-					Count: count,
+					Bound:    bound,
+					Count:    count,
+					Exemplar: exemplars[field.Key],
 				})
 			case strings.HasSuffix(field.Key, "_sum"):
 				sum, ok := SampleSum(field.Value)
@@ -432,7 +538,10 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			case telegraf.Gauge:
 				m.Gauge = &dto.Gauge{Value: proto.Float64(metric.Scaler.Value)}
 			case telegraf.Counter:
-				m.Counter = &dto.Counter{Value: proto.Float64(metric.Scaler.Value)}
+				m.Counter = &dto.Counter{
+					Value:    proto.Float64(metric.Scaler.Value),
+					Exemplar: exemplarProto(metric.Scaler.Exemplar),
+				}
 			case telegraf.Untyped:
 				m.Untyped = &dto.Untyped{Value: proto.Float64(metric.Scaler.Value)}
 			case telegraf.Histogram:
@@ -441,6 +550,7 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 					buckets = append(buckets, &dto.Bucket{
 						UpperBound:      proto.Float64(bucket.Bound),
 						CumulativeCount: proto.Uint64(bucket.Count),
+						Exemplar:        exemplarProto(bucket.Exemplar),
 					})
 				}
 
@@ -477,3 +587,26 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 
 	return result
 }
+
+func exemplarProto(e *Exemplar) *dto.Exemplar {
+	if e == nil {
+		return nil
+	}
+
+	labels := make([]*dto.LabelPair, 0, len(e.Labels))
+	for _, label := range e.Labels {
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(label.Name),
+			Value: proto.String(label.Value),
+		})
+	}
+
+	exemplar := &dto.Exemplar{
+		Label: labels,
+		Value: proto.Float64(e.Value),
+	}
+	if !e.Time.IsZero() {
+		exemplar.Timestamp = timestamppb.New(e.Time)
+	}
+	return exemplar
+}
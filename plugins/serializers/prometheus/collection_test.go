@@ -10,6 +10,7 @@ import (
 	"github.com/influxdata/telegraf/testutil"
 	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type Input struct {
@@ -425,3 +426,88 @@ func TestCollectionExpire(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionExemplars(t *testing.T) {
+	c := NewCollection(FormatConfig{StringHandling: StringAsLabel, MetricSortOrder: SortMetrics})
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{},
+		map[string]interface{}{
+			"jobs_total":                         3.0,
+			"jobs_total_exemplar_value":          1.0,
+			"jobs_total_exemplar_timestamp":      1622745000.5,
+			"jobs_total_exemplar_label_trace_id": "abc",
+			"jobs_created":                       1622700000.0,
+		},
+		time.Unix(0, 0),
+		telegraf.Counter,
+	), time.Unix(0, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{"le": "0.5"},
+		map[string]interface{}{
+			"latency_bucket":                         2.0,
+			"latency_bucket_exemplar_value":          0.25,
+			"latency_bucket_exemplar_label_trace_id": "def",
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	), time.Unix(0, 0))
+	c.Add(testutil.MustMetric(
+		"prometheus",
+		map[string]string{},
+		map[string]interface{}{
+			"latency_count":   2.0,
+			"latency_sum":     0.4,
+			"latency_created": 1622700000.0,
+		},
+		time.Unix(0, 0),
+		telegraf.Histogram,
+	), time.Unix(0, 0))
+
+	expected := []*dto.MetricFamily{
+		{
+			Name: proto.String("jobs_total"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_COUNTER.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{},
+					Counter: &dto.Counter{
+						Value: proto.Float64(3.0),
+						Exemplar: &dto.Exemplar{
+							Label:     []*dto.LabelPair{{Name: proto.String("trace_id"), Value: proto.String("abc")}},
+							Value:     proto.Float64(1.0),
+							Timestamp: timestamppb.New(time.Unix(1622745000, 5e8)),
+						},
+					},
+				},
+			},
+		},
+		{
+			Name: proto.String("latency"),
+			Help: proto.String(helpString),
+			Type: dto.MetricType_HISTOGRAM.Enum(),
+			Metric: []*dto.Metric{
+				{
+					Label: []*dto.LabelPair{},
+					Histogram: &dto.Histogram{
+						SampleCount: proto.Uint64(2),
+						SampleSum:   proto.Float64(0.4),
+						Bucket: []*dto.Bucket{
+							{
+								UpperBound:      proto.Float64(0.5),
+								CumulativeCount: proto.Uint64(2),
+								Exemplar: &dto.Exemplar{
+									Label: []*dto.LabelPair{{Name: proto.String("trace_id"), Value: proto.String("def")}},
+									Value: proto.Float64(0.25),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, c.GetProto())
+}
@@ -54,7 +54,24 @@ func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
 }
 
 func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	promTS, err := s.TimeSeries(metrics)
+	if err != nil {
+		return nil, err
+	}
+
 	var buf bytes.Buffer
+	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: promTS})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal protobuf: %v", err)
+	}
+	encoded := snappy.Encode(nil, data)
+	buf.Write(encoded)
+	return buf.Bytes(), nil
+}
+
+// TimeSeries converts the metrics to the time series of a write request,
+// with the latest sample of each series.
+func (s *Serializer) TimeSeries(metrics []telegraf.Metric) ([]prompb.TimeSeries, error) {
 	var entries = make(map[MetricKey]prompb.TimeSeries)
 	for _, metric := range metrics {
 		commonLabels := s.createLabels(metric)
@@ -236,13 +253,7 @@ func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
 			return false
 		})
 	}
-	data, err := proto.Marshal(&prompb.WriteRequest{Timeseries: promTS})
-	if err != nil {
-		return nil, fmt.Errorf("unable to marshal protobuf: %v", err)
-	}
-	encoded := snappy.Encode(nil, data)
-	buf.Write(encoded)
-	return buf.Bytes(), nil
+	return promTS, nil
 }
 
 func hasLabel(name string, labels []prompb.Label) bool {
@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/avro"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
@@ -14,6 +16,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheusremotewrite"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
+	"github.com/influxdata/telegraf/plugins/serializers/template"
 	"github.com/influxdata/telegraf/plugins/serializers/wavefront"
 )
 
@@ -44,18 +47,59 @@ type Serializer interface {
 	SerializeBatch(metrics []telegraf.Metric) ([]byte, error)
 }
 
+// StatefulSerializer is a Serializer whose output depends on the metrics
+// already serialized, such as the csv serializer which writes its headers only
+// once.  Outputs writing to files use a copy per file, resume it when appending
+// to a non-empty file and reset it when starting a new file.
+type StatefulSerializer interface {
+	Serializer
+
+	// Copy returns a serializer with the same configuration and a cleared
+	// state.
+	Copy() StatefulSerializer
+
+	// Reset clears the state.
+	Reset()
+
+	// Resume sets the state for appending to output that already holds
+	// serialized metrics.
+	Resume()
+}
+
 // Config is a struct that covers the data types needed for all serializer types,
 // and can be used to instantiate _any_ of the serializers.
 type Config struct {
 	// Dataformat can be one of the serializer types listed in NewSerializer.
 	DataFormat string `toml:"data_format"`
 
+	// URL of the schema registry; avro format only
+	AvroSchemaRegistry string `toml:"avro_schema_registry"`
+
+	// Namespace of the derived record schemas; avro format only
+	AvroNamespace string `toml:"avro_namespace"`
+
+	// Record schemas by measurement, instead of deriving them; avro format
+	// only
+	AvroSchemas map[string]string `toml:"avro_schemas"`
+
 	// Carbon2 metric format.
 	Carbon2Format string `toml:"carbon2_format"`
 
 	// Character used for metric name sanitization in Carbon2.
 	Carbon2SanitizeReplaceChar string `toml:"carbon2_sanitize_replace_char"`
 
+	// CSV format, "wide" or "long".
+	CSVFormat string `toml:"csv_format"`
+
+	// Columns of the CSV rows, in order.
+	CSVColumns []string `toml:"csv_columns"`
+
+	// Write the CSV header at the start of each file.
+	CSVHeader bool `toml:"csv_header"`
+
+	// Format of the CSV timestamp column.
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
+
 	// Support tags in graphite protocol
 	GraphiteTagSupport bool `toml:"graphite_tag_support"`
 
@@ -85,6 +129,9 @@ type Config struct {
 	// Templates same Template, but multiple
 	Templates []string `toml:"templates"`
 
+	// Template for rendering a batch of metrics; template format only
+	BatchTemplate string `toml:"batch_template"`
+
 	// Timestamp units to use for JSON formatted output
 	TimestampUnits time.Duration `toml:"timestamp_units"`
 
@@ -138,12 +185,38 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewPrometheusRemoteWriteSerializer(config)
 	case "msgpack":
 		serializer, err = NewMsgpackSerializer()
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
+	case "template":
+		serializer, err = NewTemplateSerializer(config.Template, config.BatchTemplate)
+	case "avro":
+		serializer, err = NewAvroSerializer(config)
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
 	return serializer, err
 }
 
+// NewSizeSerializer returns a serializer for estimating the size of the
+// metrics serialized with the given config, keeping its state apart from the
+// serializer the output writes with.  Avro schemas are not registered.  The
+// size of metrics of outputs without a config is estimated using line
+// protocol.
+func NewSizeSerializer(config *Config) (Serializer, error) {
+	if config == nil {
+		return influx.NewSerializer(), nil
+	}
+	if config.DataFormat == "avro" {
+		return avro.NewSerializer(&avro.Config{
+			SchemaRegistry:   config.AvroSchemaRegistry,
+			Namespace:        config.AvroNamespace,
+			Schemas:          config.AvroSchemas,
+			SkipRegistration: true,
+		})
+	}
+	return NewSerializer(config)
+}
+
 func NewPrometheusRemoteWriteSerializer(config *Config) (Serializer, error) {
 	sortMetrics := prometheusremotewrite.NoSortMetrics
 	if config.PrometheusExportTimestamp {
@@ -255,6 +328,40 @@ func NewGraphiteSerializer(prefix, template string, tagSupport bool, tagSanitize
 	}, nil
 }
 
+// csvSerializer makes the csv serializer a StatefulSerializer.
+type csvSerializer struct {
+	*csv.Serializer
+}
+
+func (s *csvSerializer) Copy() StatefulSerializer {
+	return &csvSerializer{s.Serializer.Copy()}
+}
+
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	s, err := csv.NewSerializer(csv.Config{
+		Format:          config.CSVFormat,
+		Columns:         config.CSVColumns,
+		Header:          config.CSVHeader,
+		TimestampFormat: config.CSVTimestampFormat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &csvSerializer{s}, nil
+}
+
+func NewTemplateSerializer(metricTemplate, batchTemplate string) (Serializer, error) {
+	return template.NewSerializer(metricTemplate, batchTemplate)
+}
+
 func NewMsgpackSerializer() (Serializer, error) {
 	return msgpack.NewSerializer(), nil
 }
+
+func NewAvroSerializer(config *Config) (Serializer, error) {
+	return avro.NewSerializer(&avro.Config{
+		SchemaRegistry: config.AvroSchemaRegistry,
+		Namespace:      config.AvroNamespace,
+		Schemas:        config.AvroSchemas,
+	})
+}
@@ -0,0 +1,116 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	common "github.com/influxdata/telegraf/plugins/common/template"
+)
+
+// funcs are the helpers available to the templates in addition to the
+// builtin functions of text/template.
+var funcs = template.FuncMap{
+	"unix":       func(t time.Time) int64 { return t.Unix() },
+	"unixMilli":  func(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) },
+	"unixMicro":  func(t time.Time) int64 { return t.UnixNano() / int64(time.Microsecond) },
+	"unixNano":   func(t time.Time) int64 { return t.UnixNano() },
+	"timeFormat": func(layout string, t time.Time) string { return t.Format(layout) },
+	"json":       toJSON,
+	"quote":      func(v interface{}) string { return strconv.Quote(fmt.Sprint(v)) },
+	"escape":     escape,
+	"replace":    func(old, new string, v interface{}) string { return strings.ReplaceAll(fmt.Sprint(v), old, new) },
+	"lower":      func(v interface{}) string { return strings.ToLower(fmt.Sprint(v)) },
+	"upper":      func(v interface{}) string { return strings.ToUpper(fmt.Sprint(v)) },
+}
+
+type Serializer struct {
+	tmpl      *template.Template
+	batchTmpl *template.Template
+}
+
+// NewSerializer creates a serializer rendering each metric with the metric
+// template, and each batch of metrics with the batch template.  When only
+// one of the templates is set, it is used for both.
+func NewSerializer(metricTemplate, batchTemplate string) (*Serializer, error) {
+	if metricTemplate == "" && batchTemplate == "" {
+		return nil, errors.New("template or batch_template must be set")
+	}
+
+	s := &Serializer{}
+	if metricTemplate != "" {
+		t, err := template.New("template").Funcs(funcs).Parse(metricTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing template failed: %v", err)
+		}
+		s.tmpl = t
+	}
+	if batchTemplate != "" {
+		t, err := template.New("batch_template").Funcs(funcs).Parse(batchTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("parsing batch_template failed: %v", err)
+		}
+		s.batchTmpl = t
+	}
+	return s, nil
+}
+
+func (s *Serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	if s.tmpl == nil {
+		return s.SerializeBatch([]telegraf.Metric{metric})
+	}
+
+	var b bytes.Buffer
+	if err := s.tmpl.Execute(&b, common.NewTemplateMetric(metric)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (s *Serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var b bytes.Buffer
+	if s.batchTmpl == nil {
+		for _, metric := range metrics {
+			if err := s.tmpl.Execute(&b, common.NewTemplateMetric(metric)); err != nil {
+				return nil, err
+			}
+		}
+		return b.Bytes(), nil
+	}
+
+	batch := make([]*common.TemplateMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		batch = append(batch, common.NewTemplateMetric(metric))
+	}
+	if err := s.batchTmpl.Execute(&b, batch); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	octets, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(octets), nil
+}
+
+// escape prefixes each of the given characters in the value with a backslash,
+// as well as the backslash itself.
+func escape(chars string, v interface{}) string {
+	var b strings.Builder
+	for _, r := range fmt.Sprint(v) {
+		if r == '\\' || strings.ContainsRune(chars, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package template
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "web 1", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "usage_user": 4.5},
+			time.Unix(1622745000, 123000000),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "web 1"},
+			map[string]interface{}{"used": int64(1024)},
+			time.Unix(1622745010, 0),
+		),
+	}
+}
+
+func TestSerialize(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "accessors",
+			template: `{{ .Name }} {{ .Tag "host" }} {{ .Field "usage_idle" }} {{ .Time | unix }}` + "\n",
+			expected: "cpu web 1 91.5 1622745000\n",
+		},
+		{
+			name:     "tags and fields",
+			template: `{{ range $k, $v := .Tags }}{{ $k }}={{ $v }};{{ end }}{{ range $k, $v := .Fields }}{{ $k }}:{{ $v }};{{ end }}`,
+			expected: "cpu=cpu0;host=web 1;usage_idle:91.5;usage_user:4.5;",
+		},
+		{
+			name:     "time",
+			template: `{{ .Time | unixMilli }} {{ .Time.UTC | timeFormat "2006-01-02T15:04:05Z07:00" }}`,
+			expected: "1622745000123 2021-06-03T18:30:00Z",
+		},
+		{
+			name:     "escaping",
+			template: `{{ .Tag "host" | escape " ," }} {{ .Tag "host" | quote }} {{ .Fields | json }} {{ .Tag "host" | replace " " "_" | upper }}`,
+			expected: `web\ 1 "web 1" {"usage_idle":91.5,"usage_user":4.5} WEB_1`,
+		},
+		{
+			name:     "missing",
+			template: `{{ .Tag "missing" }}|{{ .Field "missing" }}`,
+			expected: "|<no value>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(tt.template, "")
+			require.NoError(t, err)
+
+			actual, err := s.Serialize(testMetrics()[0])
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
+
+func TestSerializeBatchMetricTemplate(t *testing.T) {
+	s, err := NewSerializer(`{{ .Name }} {{ .Tag "host" }}`+"\n", "")
+	require.NoError(t, err)
+
+	actual, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, "cpu web 1\nmem web 1\n", string(actual))
+}
+
+func TestSerializeBatchTemplate(t *testing.T) {
+	batch := `[{{ range $i, $m := . }}{{ if $i }},{{ end }}{{ $m.Name | quote }}{{ end }}]`
+	s, err := NewSerializer("", batch)
+	require.NoError(t, err)
+
+	actual, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, `["cpu","mem"]`, string(actual))
+
+	// Without metric template, single metrics are rendered as a batch
+	actual, err = s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+	require.Equal(t, `["mem"]`, string(actual))
+}
+
+func TestSerializeBothTemplates(t *testing.T) {
+	s, err := NewSerializer(`{{ .Name }}`, `{{ len . }}`)
+	require.NoError(t, err)
+
+	actual, err := s.Serialize(testMetrics()[0])
+	require.NoError(t, err)
+	require.Equal(t, "cpu", string(actual))
+
+	actual, err = s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+	require.Equal(t, "2", string(actual))
+}
+
+func TestInvalidTemplates(t *testing.T) {
+	_, err := NewSerializer("", "")
+	require.Error(t, err)
+
+	_, err = NewSerializer("{{ .Name", "")
+	require.Error(t, err)
+
+	_, err = NewSerializer("", "{{ unknown . }}")
+	require.Error(t, err)
+}
+
+func TestExecutionError(t *testing.T) {
+	s, err := NewSerializer(`{{ .Missing }}`, "")
+	require.NoError(t, err)
+
+	_, err = s.Serialize(testMetrics()[0])
+	require.Error(t, err)
+}
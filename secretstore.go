@@ -0,0 +1,14 @@
+package telegraf
+
+// SecretStore is a secret store plugin interface for resolving secret
+// references such as @{store:key} in the configuration of other plugins.
+type SecretStore interface {
+	PluginDescriber
+
+	// Init performs one time setup of the secret store and returns an error
+	// if the configuration is invalid.
+	Init() error
+
+	// Get returns the secret stored under the given key.
+	Get(key string) (string, error)
+}